@@ -6,6 +6,8 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"html_image_creator/pkg/config"
 	mcpHandler "html_image_creator/pkg/handler"
@@ -19,30 +21,187 @@ import (
 func main() {
 	// Define terminal mode flags
 	var (
-		createPost   string
-		updatePost   string
-		htmlContent  string
-		width        int
-		height       int
-		listPosts    bool
-		getPost      string
-		exportPost   string
-		exportOutput string
-		addMedia     string
-		mediaPath    string
+		createPost    string
+		updatePost    string
+		htmlContent   string
+		width         int
+		height        int
+		listPosts     bool
+		filterTag     string
+		filterColl    string
+		listQuery     string
+		listSort      string
+		listOrder     string
+		listLimit     int
+		listCursor    string
+		listSince     string
+		listUntil     string
+		getPost       string
+		exportPost    string
+		exportOutput  string
+		exportFormat  string
+		exportScale   float64
+		exportQuality int
+		addMedia      string
+		mediaPath     string
+		mediaURL      string
+		localize      string
+		deletePost    string
+		permanent     bool
+		listTrash     bool
+		restorePost   string
+		emptyTrash    bool
+		olderThan     float64
+		resizePost    string
+		resizeMode    string
+		clonePost     string
+		postName      string
+		setMetadata   string
+		description   string
+		caption       string
+		altText       string
+		customFields  stringList
+		tagPost       string
+		addTags       stringList
+		removeTags    stringList
+		createColl    string
+		listColls     bool
+		moveColl      string
+		deleteColl    string
+		rescale       bool
+		exportBundle  string
+		bundleFormat  string
+		withVersions  bool
+		importBundle  string
+		onConflict    string
+		newID         bool
+		migrate       bool
+		dryRun        bool
+		fsck          bool
+		repair        string
+		listWS        bool
+		createWS      string
+		rootDir       string
+		gc            bool
+		maxAgeDays    int
+		keepVersions  int
+		postLog       string
+		checkoutPost  string
+		revision      string
+		usage         bool
+		defineTmpl    string
+		tmplVars      string
+		renderTmpl    string
+		tmplValues    string
+		batchTmpl     string
+		batchData     string
+		batchPattern  string
+		mediaColumns  stringList
+		createKit     string
+		updateKit     string
+		kitJSON       string
+		listKits      bool
+		applyKit      string
+		brandKit      string
+		regComponent  string
+		componentCSS  string
+		componentArgs string
+		listComps     bool
+		previewComp   string
+		editPost      string
+		editOps       string
 	)
 
 	flag.StringVar(&createPost, "create", "", "Create a new image post with the specified name")
 	flag.StringVar(&updatePost, "update", "", "Update post with the specified ID")
+	flag.StringVar(&editPost, "edit", "", "Apply the --ops edits to the post with the specified ID")
+	flag.StringVar(&editOps, "ops", "", `With --edit, JSON array of operations, e.g. '[{"op":"set_text","selector":"h1","value":"Hello"}]'`)
 	flag.StringVar(&htmlContent, "html", "", "HTML content for create/update operations")
-	flag.IntVar(&width, "width", 0, "Canvas width in pixels (required for create)")
-	flag.IntVar(&height, "height", 0, "Canvas height in pixels (required for create)")
+	flag.IntVar(&width, "width", 0, "Canvas width in pixels (required for create and resize, optional for clone, filter for list)")
+	flag.IntVar(&height, "height", 0, "Canvas height in pixels (required for create and resize, optional for clone, filter for list)")
 	flag.BoolVar(&listPosts, "list", false, "List all image posts")
+	flag.StringVar(&filterTag, "tag", "", "With --list, only list posts with this tag")
+	flag.StringVar(&filterColl, "collection", "", "With --list, only list posts in this collection; with --move-to-collection, the target collection")
+	flag.StringVar(&listQuery, "query", "", "With --list, search words in post names and HTML text")
+	flag.StringVar(&listSort, "sort", "", "With --list, sort by created, updated (default) or name")
+	flag.StringVar(&listOrder, "order", "", "With --list, sort order: asc or desc (default)")
+	flag.IntVar(&listLimit, "limit", 0, "With --list, maximum number of posts to return; with --log, maximum number of revisions; with --usage, maximum number of posts")
+	flag.StringVar(&listCursor, "cursor", "", "With --list, next_cursor from a previous page")
+	flag.StringVar(&listSince, "since", "", "With --list, only posts created at or after this date (YYYY-MM-DD or RFC 3339)")
+	flag.StringVar(&listUntil, "until", "", "With --list, only posts created before this date (YYYY-MM-DD or RFC 3339)")
 	flag.StringVar(&getPost, "get", "", "Get image post by ID")
 	flag.StringVar(&exportPost, "export", "", "Export image post by ID")
 	flag.StringVar(&exportOutput, "output", "", "Output path for export")
+	flag.StringVar(&exportFormat, "format", "", "With --export, image format: png, jpeg or webp (defaults from --output extension)")
+	flag.Float64Var(&exportScale, "scale", 0, "With --export, device scale factor (default 2)")
+	flag.IntVar(&exportQuality, "quality", 0, "With --export, jpeg/webp quality 1-100 (default 90)")
 	flag.StringVar(&addMedia, "add-media", "", "Add media to post (specify post ID)")
 	flag.StringVar(&mediaPath, "media-path", "", "Path to media file")
+	flag.StringVar(&mediaURL, "media-url", "", "HTTP(S) URL of media file to download")
+	flag.StringVar(&localize, "localize", "", "Download remote images referenced by post (specify post ID)")
+	flag.StringVar(&deletePost, "delete", "", "Move post with the specified ID to the trash")
+	flag.BoolVar(&permanent, "permanent", false, "With --delete, delete the post permanently instead of trashing it")
+	flag.BoolVar(&listTrash, "list-trash", false, "List posts in the trash")
+	flag.StringVar(&restorePost, "restore", "", "Restore post with the specified ID from the trash")
+	flag.BoolVar(&emptyTrash, "empty-trash", false, "Permanently delete trashed posts")
+	flag.Float64Var(&olderThan, "older-than-days", 0, "With --empty-trash, only purge posts deleted more than this many days ago")
+	flag.StringVar(&resizePost, "resize", "", "Resize post with the specified ID (requires --width and --height)")
+	flag.StringVar(&resizeMode, "mode", "keep", "With --resize, how to adapt the layout: keep, scale or rewrite")
+	flag.StringVar(&clonePost, "clone", "", "Clone post with the specified ID (requires --name)")
+	flag.StringVar(&postName, "name", "", "New name for --clone, --set-metadata or --render-template")
+	flag.StringVar(&setMetadata, "set-metadata", "", "Edit metadata of post with the specified ID (use --name, --description, --caption, --alt-text, --field)")
+	flag.StringVar(&description, "description", "", "With --set-metadata, post description; with --create-collection, --create-workspace, --create-brand-kit or --register-component, their description")
+	flag.StringVar(&caption, "caption", "", "With --set-metadata, caption text")
+	flag.StringVar(&altText, "alt-text", "", "With --set-metadata, alt text")
+	flag.Var(&customFields, "field", "With --set-metadata, custom field as key=value (repeatable; key= removes it)")
+	flag.StringVar(&tagPost, "tag-post", "", "Add or remove tags on post with the specified ID (use --add-tag, --remove-tag)")
+	flag.Var(&addTags, "add-tag", "With --tag-post, tag to add (repeatable)")
+	flag.Var(&removeTags, "remove-tag", "With --tag-post, tag to remove (repeatable)")
+	flag.StringVar(&createColl, "create-collection", "", "Create a collection with the specified name (optional --description)")
+	flag.BoolVar(&listColls, "list-collections", false, "List collections")
+	flag.StringVar(&moveColl, "move-to-collection", "", "Comma separated post IDs to move into --collection (empty to remove)")
+	flag.StringVar(&deleteColl, "delete-collection", "", "Delete the collection with the specified ID")
+	flag.BoolVar(&rescale, "rescale", false, "With --clone and new --width/--height, proportionally scale px values in the CSS")
+	flag.StringVar(&exportBundle, "export-bundle", "", "Export post with the specified ID as a bundle (requires --output)")
+	flag.StringVar(&bundleFormat, "bundle-format", "", "Bundle format: zip or tar.gz (defaults from --output extension)")
+	flag.BoolVar(&withVersions, "include-versions", false, "With --export-bundle, include previous HTML versions")
+	flag.StringVar(&importBundle, "import-bundle", "", "Import a post bundle from the specified path")
+	flag.StringVar(&onConflict, "on-conflict", "", "With --import-bundle, handle an existing post ID: error, rename or overwrite")
+	flag.BoolVar(&newID, "new-id", false, "With --import-bundle, always import under a newly generated ID")
+	flag.BoolVar(&migrate, "migrate", false, "Upgrade all posts to the current metadata schema version")
+	flag.BoolVar(&dryRun, "dry-run", false, "With --migrate, --gc or --batch, only report pending changes")
+	flag.BoolVar(&fsck, "fsck", false, "Check the storage root for broken posts and stray files")
+	flag.StringVar(&repair, "repair", "", "With --fsck, repair issues: regenerate, quarantine or delete")
+	flag.BoolVar(&gc, "gc", false, "Apply retention policies, deleting old posts and surplus versions")
+	flag.IntVar(&maxAgeDays, "max-age-days", 0, "With --gc, delete posts not updated in this many days (default HTML_IMAGE_CREATOR_RETENTION_DAYS)")
+	flag.IntVar(&keepVersions, "keep-versions", 0, "With --gc, keep only this many versions per post (default HTML_IMAGE_CREATOR_KEEP_VERSIONS)")
+	flag.StringVar(&postLog, "log", "", "Show the git history of post with the specified ID")
+	flag.StringVar(&checkoutPost, "checkout", "", "Restore post with the specified ID to a past revision (requires --revision)")
+	flag.StringVar(&revision, "revision", "", "With --checkout, commit hash or revision such as HEAD~1")
+	flag.BoolVar(&usage, "usage", false, "Report disk usage per post (optional --limit)")
+	flag.StringVar(&defineTmpl, "define-template", "", "Declare the template variables of post with the specified ID (requires --variables)")
+	flag.StringVar(&tmplVars, "variables", "", "With --define-template, JSON array of variables, e.g. '[{\"name\":\"title\",\"required\":true}]'")
+	flag.StringVar(&renderTmpl, "render-template", "", "Render template with the specified ID into a new post (--name) and/or an image (--output)")
+	flag.StringVar(&tmplValues, "values", "", "With --render-template, JSON object of variable values; with --preview-component, of parameter values")
+	flag.StringVar(&batchTmpl, "batch", "", "Render one image per row of --data through the template with the specified ID into the --output directory")
+	flag.StringVar(&batchData, "data", "", "With --batch, CSV or JSON data file")
+	flag.StringVar(&batchPattern, "pattern", "", "With --batch, output file name pattern, e.g. '{{.name}}-card' (default row-0001 and so on)")
+	flag.Var(&mediaColumns, "media-column", "With --batch, column holding media file paths (repeatable)")
+	flag.StringVar(&createKit, "create-brand-kit", "", "Create a brand kit with the specified name (tokens and logos from --kit)")
+	flag.StringVar(&updateKit, "update-brand-kit", "", "Edit the brand kit with the specified ID (changes from --kit) and update its posts")
+	flag.StringVar(&kitJSON, "kit", "", `With --create-brand-kit or --update-brand-kit, JSON object with palette, typography, spacing and logos, e.g. '{"palette":{"primary":"#0b5fff"},"logos":{"primary":"logo.svg"}}'`)
+	flag.BoolVar(&listKits, "list-brand-kits", false, "List brand kits")
+	flag.StringVar(&applyKit, "apply-brand-kit", "", "Apply --brand-kit to the post with the specified ID (empty --brand-kit removes it)")
+	flag.StringVar(&brandKit, "brand-kit", "", "With --apply-brand-kit, the brand kit ID")
+	flag.StringVar(&regComponent, "register-component", "", "Register a component with the specified name (requires --html; optional --css, --params, --description)")
+	flag.StringVar(&componentCSS, "css", "", "With --register-component, CSS added to posts using the component")
+	flag.StringVar(&componentArgs, "params", "", `With --register-component, JSON array of parameters, e.g. '[{"name":"text","required":true}]'`)
+	flag.BoolVar(&listComps, "list-components", false, "List registered components")
+	flag.StringVar(&previewComp, "preview-component", "", "Render the component with the specified name (parameters from --values; optional --output, --width/--height)")
+	flag.StringVar(&workspace, "workspace", "", "Workspace to run the command in (default workspace if empty)")
+	flag.BoolVar(&listWS, "list-workspaces", false, "List workspaces")
+	flag.StringVar(&createWS, "create-workspace", "", "Create a workspace with the specified name (optional --root-dir, --description, --width/--height and --format/--scale defaults)")
+	flag.StringVar(&rootDir, "root-dir", "", "With --create-workspace, directory to store the workspace's posts in")
 	flag.Parse()
 
 	// Load configuration
@@ -53,7 +212,10 @@ func main() {
 
 	// Create handler
 	screenshotSvc := screenshot.NewScreenshotter()
-	h := mcpHandler.NewHandler(cfg, screenshotSvc)
+	h, err := mcpHandler.NewHandler(cfg, screenshotSvc)
+	if err != nil {
+		log.Fatalf("Failed to create handler: %v", err)
+	}
 	ctx := context.Background()
 
 	// Terminal mode operations
@@ -61,15 +223,18 @@ func main() {
 		if htmlContent == "" {
 			log.Fatal("--html is required when creating a post")
 		}
-		if width <= 0 || height <= 0 {
-			log.Fatal("--width and --height are required when creating a post")
-		}
-		runTerminalCommand(ctx, h, "create_image_post", map[string]interface{}{
+		args := map[string]interface{}{
 			"name":         createPost,
 			"html_content": htmlContent,
-			"width":        float64(width),
-			"height":       float64(height),
-		})
+		}
+		// Omitted dimensions fall back to the workspace defaults
+		if width > 0 {
+			args["width"] = float64(width)
+		}
+		if height > 0 {
+			args["height"] = float64(height)
+		}
+		runTerminalCommand(ctx, h, "create_image_post", args)
 		return
 	}
 
@@ -84,8 +249,32 @@ func main() {
 		return
 	}
 
+	if editPost != "" {
+		var ops []interface{}
+		if err := json.Unmarshal([]byte(editOps), &ops); err != nil {
+			log.Fatalf("--ops must be a JSON array: %v", err)
+		}
+		runTerminalCommand(ctx, h, "edit_image_post", map[string]interface{}{
+			"post_id":    editPost,
+			"operations": ops,
+		})
+		return
+	}
+
 	if listPosts {
-		runTerminalCommand(ctx, h, "list_image_posts", map[string]interface{}{})
+		runTerminalCommand(ctx, h, "list_image_posts", map[string]interface{}{
+			"query":          listQuery,
+			"tag":            filterTag,
+			"collection":     filterColl,
+			"width":          float64(width),
+			"height":         float64(height),
+			"created_after":  listSince,
+			"created_before": listUntil,
+			"sort_by":        listSort,
+			"order":          listOrder,
+			"limit":          float64(listLimit),
+			"cursor":         listCursor,
+		})
 		return
 	}
 
@@ -102,22 +291,394 @@ func main() {
 		}
 		runTerminalCommand(ctx, h, "export_image", map[string]interface{}{
 			"post_id":     exportPost,
-			"output_path": exportOutput,
+			"output_path": absPath(exportOutput),
+			"format":      exportFormat,
+			"scale":       exportScale,
+			"quality":     float64(exportQuality),
 		})
 		return
 	}
 
 	if addMedia != "" {
-		if mediaPath == "" {
-			log.Fatal("--media-path is required when adding media")
+		if mediaPath == "" && mediaURL == "" {
+			log.Fatal("--media-path or --media-url is required when adding media")
+		}
+		args := map[string]interface{}{
+			"post_id": addMedia,
+		}
+		if mediaPath != "" {
+			args["source_path"] = absPath(mediaPath)
+		} else {
+			args["url"] = mediaURL
+		}
+		runTerminalCommand(ctx, h, "add_media", args)
+		return
+	}
+
+	if localize != "" {
+		runTerminalCommand(ctx, h, "localize_media", map[string]interface{}{
+			"post_id": localize,
+		})
+		return
+	}
+
+	if deletePost != "" {
+		runTerminalCommand(ctx, h, "delete_image_post", map[string]interface{}{
+			"post_id":   deletePost,
+			"permanent": permanent,
+		})
+		return
+	}
+
+	if listTrash {
+		runTerminalCommand(ctx, h, "list_trash", map[string]interface{}{})
+		return
+	}
+
+	if restorePost != "" {
+		runTerminalCommand(ctx, h, "restore_image_post", map[string]interface{}{
+			"post_id": restorePost,
+		})
+		return
+	}
+
+	if emptyTrash {
+		runTerminalCommand(ctx, h, "empty_trash", map[string]interface{}{
+			"older_than_days": olderThan,
+		})
+		return
+	}
+
+	if setMetadata != "" {
+		args := map[string]interface{}{"post_id": setMetadata}
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "name":
+				args["name"] = postName
+			case "description":
+				args["description"] = description
+			case "caption":
+				args["caption"] = caption
+			case "alt-text":
+				args["alt_text"] = altText
+			}
+		})
+		if len(customFields) > 0 {
+			fields := make(map[string]interface{}, len(customFields))
+			for _, field := range customFields {
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					log.Fatalf("Invalid --field %q: expected key=value", field)
+				}
+				fields[key] = value
+			}
+			args["custom_fields"] = fields
+		}
+		runTerminalCommand(ctx, h, "update_post_metadata", args)
+		return
+	}
+
+	if tagPost != "" {
+		runTerminalCommand(ctx, h, "tag_image_post", map[string]interface{}{
+			"post_id": tagPost,
+			"add":     toInterfaceSlice(addTags),
+			"remove":  toInterfaceSlice(removeTags),
+		})
+		return
+	}
+
+	if createColl != "" {
+		runTerminalCommand(ctx, h, "create_collection", map[string]interface{}{
+			"name":        createColl,
+			"description": description,
+		})
+		return
+	}
+
+	if listColls {
+		runTerminalCommand(ctx, h, "list_collections", map[string]interface{}{})
+		return
+	}
+
+	if moveColl != "" {
+		runTerminalCommand(ctx, h, "move_to_collection", map[string]interface{}{
+			"post_ids":      toInterfaceSlice(strings.Split(moveColl, ",")),
+			"collection_id": filterColl,
+		})
+		return
+	}
+
+	if deleteColl != "" {
+		runTerminalCommand(ctx, h, "delete_collection", map[string]interface{}{
+			"collection_id": deleteColl,
+		})
+		return
+	}
+
+	if resizePost != "" {
+		if width <= 0 || height <= 0 {
+			log.Fatal("--width and --height are required when resizing a post")
+		}
+		runTerminalCommand(ctx, h, "resize_image_post", map[string]interface{}{
+			"post_id": resizePost,
+			"width":   float64(width),
+			"height":  float64(height),
+			"mode":    resizeMode,
+		})
+		return
+	}
+
+	if clonePost != "" {
+		if postName == "" {
+			log.Fatal("--name is required when cloning a post")
+		}
+		runTerminalCommand(ctx, h, "clone_image_post", map[string]interface{}{
+			"post_id": clonePost,
+			"name":    postName,
+			"width":   float64(width),
+			"height":  float64(height),
+			"rescale": rescale,
+		})
+		return
+	}
+
+	if exportBundle != "" {
+		if exportOutput == "" {
+			log.Fatal("--output is required when exporting a bundle")
+		}
+		runTerminalCommand(ctx, h, "export_post_bundle", map[string]interface{}{
+			"post_id":          exportBundle,
+			"output_path":      absPath(exportOutput),
+			"format":           bundleFormat,
+			"include_versions": withVersions,
+		})
+		return
+	}
+
+	if importBundle != "" {
+		runTerminalCommand(ctx, h, "import_post_bundle", map[string]interface{}{
+			"bundle_path": absPath(importBundle),
+			"preserve_id": !newID,
+			"on_conflict": onConflict,
+		})
+		return
+	}
+
+	if migrate {
+		runTerminalCommand(ctx, h, "migrate_storage", map[string]interface{}{
+			"dry_run": dryRun,
+		})
+		return
+	}
+
+	if fsck {
+		runTerminalCommand(ctx, h, "check_storage", map[string]interface{}{
+			"repair": repair,
+		})
+		return
+	}
+
+	if gc {
+		args := map[string]interface{}{"dry_run": dryRun}
+		// Unset flags fall back to the configured retention policy
+		if maxAgeDays > 0 {
+			args["max_age_days"] = float64(maxAgeDays)
+		}
+		if keepVersions > 0 {
+			args["keep_versions"] = float64(keepVersions)
+		}
+		runTerminalCommand(ctx, h, "garbage_collect", args)
+		return
+	}
+
+	if postLog != "" {
+		args := map[string]interface{}{"post_id": postLog}
+		if listLimit > 0 {
+			args["limit"] = float64(listLimit)
 		}
-		runTerminalCommand(ctx, h, "add_media", map[string]interface{}{
-			"post_id":     addMedia,
-			"source_path": mediaPath,
+		runTerminalCommand(ctx, h, "get_post_log", args)
+		return
+	}
+
+	if checkoutPost != "" {
+		if revision == "" {
+			log.Fatal("--revision is required when checking out a post")
+		}
+		runTerminalCommand(ctx, h, "checkout_post_revision", map[string]interface{}{
+			"post_id":  checkoutPost,
+			"revision": revision,
+		})
+		return
+	}
+
+	if usage {
+		runTerminalCommand(ctx, h, "storage_usage", map[string]interface{}{
+			"limit": float64(listLimit),
+		})
+		return
+	}
+
+	if defineTmpl != "" {
+		var variables []interface{}
+		if err := json.Unmarshal([]byte(tmplVars), &variables); err != nil {
+			log.Fatalf("--variables must be a JSON array: %v", err)
+		}
+		runTerminalCommand(ctx, h, "define_template", map[string]interface{}{
+			"post_id":   defineTmpl,
+			"variables": variables,
+		})
+		return
+	}
+
+	if renderTmpl != "" {
+		values := map[string]interface{}{}
+		if tmplValues != "" {
+			if err := json.Unmarshal([]byte(tmplValues), &values); err != nil {
+				log.Fatalf("--values must be a JSON object: %v", err)
+			}
+		}
+		args := map[string]interface{}{
+			"template_id": renderTmpl,
+			"values":      values,
+			"name":        postName,
+			"format":      exportFormat,
+			"scale":       exportScale,
+			"quality":     float64(exportQuality),
+		}
+		if exportOutput != "" {
+			args["output_path"] = absPath(exportOutput)
+		}
+		runTerminalCommand(ctx, h, "render_template", args)
+		return
+	}
+
+	if batchTmpl != "" {
+		if batchData == "" || exportOutput == "" {
+			log.Fatal("--batch requires --data and --output")
+		}
+		runTerminalCommand(ctx, h, "batch_generate", map[string]interface{}{
+			"template_id":    batchTmpl,
+			"data_path":      absPath(batchData),
+			"output_dir":     absPath(exportOutput),
+			"output_pattern": batchPattern,
+			"media_columns":  toInterfaceSlice(mediaColumns),
+			"format":         exportFormat,
+			"scale":          exportScale,
+			"quality":        float64(exportQuality),
+			"dry_run":        dryRun,
+		})
+		return
+	}
+
+	if createKit != "" || updateKit != "" {
+		args := map[string]interface{}{}
+		if kitJSON != "" {
+			if err := json.Unmarshal([]byte(kitJSON), &args); err != nil {
+				log.Fatalf("--kit must be a JSON object: %v", err)
+			}
+		}
+		if logos, ok := args["logos"].(map[string]interface{}); ok {
+			for name, logoPath := range logos {
+				if p, ok := logoPath.(string); ok && p != "" {
+					logos[name] = absPath(p)
+				}
+			}
+		}
+		if description != "" {
+			args["description"] = description
+		}
+		if createKit != "" {
+			args["name"] = createKit
+			runTerminalCommand(ctx, h, "create_brand_kit", args)
+		} else {
+			args["kit_id"] = updateKit
+			runTerminalCommand(ctx, h, "update_brand_kit", args)
+		}
+		return
+	}
+
+	if listKits {
+		runTerminalCommand(ctx, h, "list_brand_kits", map[string]interface{}{})
+		return
+	}
+
+	if applyKit != "" {
+		runTerminalCommand(ctx, h, "apply_brand_kit", map[string]interface{}{
+			"post_id": applyKit,
+			"kit_id":  brandKit,
 		})
 		return
 	}
 
+	if regComponent != "" {
+		if htmlContent == "" {
+			log.Fatal("--register-component requires --html")
+		}
+		args := map[string]interface{}{
+			"name":        regComponent,
+			"html":        htmlContent,
+			"css":         componentCSS,
+			"description": description,
+		}
+		if componentArgs != "" {
+			var params []interface{}
+			if err := json.Unmarshal([]byte(componentArgs), &params); err != nil {
+				log.Fatalf("--params must be a JSON array: %v", err)
+			}
+			args["params"] = params
+		}
+		runTerminalCommand(ctx, h, "register_component", args)
+		return
+	}
+
+	if listComps {
+		runTerminalCommand(ctx, h, "list_components", map[string]interface{}{})
+		return
+	}
+
+	if previewComp != "" {
+		params := map[string]interface{}{}
+		if tmplValues != "" {
+			if err := json.Unmarshal([]byte(tmplValues), &params); err != nil {
+				log.Fatalf("--values must be a JSON object: %v", err)
+			}
+		}
+		args := map[string]interface{}{
+			"name":   previewComp,
+			"params": params,
+			"width":  float64(width),
+			"height": float64(height),
+			"format": exportFormat,
+			"scale":  exportScale,
+		}
+		if exportOutput != "" {
+			args["output_path"] = absPath(exportOutput)
+		}
+		runTerminalCommand(ctx, h, "preview_component", args)
+		return
+	}
+
+	if listWS {
+		runTerminalCommand(ctx, h, "list_workspaces", map[string]interface{}{})
+		return
+	}
+
+	if createWS != "" {
+		args := map[string]interface{}{
+			"name":           createWS,
+			"description":    description,
+			"default_width":  float64(width),
+			"default_height": float64(height),
+			"export_format":  exportFormat,
+			"export_scale":   exportScale,
+		}
+		if rootDir != "" {
+			args["root_dir"] = absPath(rootDir)
+		}
+		runTerminalCommand(ctx, h, "create_workspace", args)
+		return
+	}
+
 	// MCP Server mode (default)
 	registry := handler.NewHandlerRegistry()
 	registry.RegisterToolHandler(h)
@@ -133,8 +694,44 @@ func main() {
 	}
 }
 
+// stringList collects the values of a repeatable flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// toInterfaceSlice converts strings to the []interface{} form of decoded JSON tool arguments
+func toInterfaceSlice(values []string) []interface{} {
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		items[i] = v
+	}
+	return items
+}
+
+// absPath makes a command line path absolute relative to the working directory
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		log.Fatalf("Invalid path %s: %v", path, err)
+	}
+	return abs
+}
+
+// workspace is the --workspace flag, applied to every terminal command
+var workspace string
+
 // runTerminalCommand executes a tool command in terminal mode
 func runTerminalCommand(ctx context.Context, h *mcpHandler.Handler, toolName string, args map[string]interface{}) {
+	if workspace != "" {
+		args["workspace"] = workspace
+	}
 	req := &protocol.CallToolRequest{
 		Name:      toolName,
 		Arguments: args,
@@ -0,0 +1,194 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"html_image_creator/pkg/screenshot"
+)
+
+// DefaultMaxRows limits the number of rows read from a data file
+const DefaultMaxRows = 10000
+
+// DefaultPattern names outputs by row number, e.g. row-0001.png
+const DefaultPattern = `row-{{printf "%04d" .row}}`
+
+// Data file formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Row is one record of a data file. Number is 1-based and excludes the CSV header.
+type Row struct {
+	Number int
+	Values map[string]interface{}
+}
+
+// FormatFromPath picks the data format from a file name, defaulting to csv
+func FormatFromPath(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// ReadFile reads the rows of a CSV or JSON data file and returns them with
+// the column names in file order. CSV files need a header row; empty cells
+// are left out of a row. JSON files hold an array of objects.
+func ReadFile(path string, maxRows int) ([]string, []*Row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+	defer f.Close()
+
+	if FormatFromPath(path) == FormatJSON {
+		return readJSON(f, maxRows)
+	}
+	return readCSV(f, maxRows)
+}
+
+func readCSV(r io.Reader, maxRows int) ([]string, []*Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("data file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	// Spreadsheet exports often start with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	seen := make(map[string]bool)
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return nil, nil, fmt.Errorf("column %d has an empty name", i+1)
+		}
+		if seen[column] {
+			return nil, nil, fmt.Errorf("duplicate column %q", column)
+		}
+		seen[column] = true
+		header[i] = column
+	}
+
+	var rows []*Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(rows) == maxRows {
+			return nil, nil, fmt.Errorf("data file has more than %d rows", maxRows)
+		}
+		row := &Row{Number: len(rows) + 1, Values: make(map[string]interface{})}
+		for i, cell := range record {
+			if cell != "" {
+				row.Values[header[i]] = cell
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+func readJSON(r io.Reader, maxRows int) ([]string, []*Row, error) {
+	var records []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, nil, fmt.Errorf("data file must hold a JSON array of objects: %w", err)
+	}
+	if len(records) > maxRows {
+		return nil, nil, fmt.Errorf("data file has more than %d rows", maxRows)
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	rows := make([]*Row, len(records))
+	for i, record := range records {
+		if record == nil {
+			return nil, nil, fmt.Errorf("row %d is not an object", i+1)
+		}
+		// Object keys have no order once decoded, so new columns are added sorted
+		var keys []string
+		for key, value := range record {
+			if value == nil {
+				delete(record, key)
+			} else if !seen[key] {
+				keys = append(keys, key)
+				seen[key] = true
+			}
+		}
+		sort.Strings(keys)
+		columns = append(columns, keys...)
+		rows[i] = &Row{Number: i + 1, Values: record}
+	}
+	return columns, rows, nil
+}
+
+// Namer builds output file names from a text/template pattern over a row's
+// values. The row number is available as .row unless a column has that name.
+type Namer struct {
+	tmpl   *template.Template
+	format string
+}
+
+// NewNamer parses pattern for outputs in the given screenshot format. An
+// empty pattern selects DefaultPattern.
+func NewNamer(pattern, format string) (*Namer, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output pattern: %w", err)
+	}
+	return &Namer{tmpl: tmpl, format: format}, nil
+}
+
+// Name returns the output file name of row. Path separators are replaced so
+// every output stays in the output directory, and the format's extension is
+// appended unless the name already has it.
+func (n *Namer) Name(row *Row) (string, error) {
+	data := make(map[string]interface{}, len(row.Values)+1)
+	data["row"] = row.Number
+	for key, value := range row.Values {
+		data[key] = value
+	}
+
+	var buf bytes.Buffer
+	if err := n.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to apply output pattern: %w", err)
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '-'
+		}
+		return r
+	}, buf.String())
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "", fmt.Errorf("output pattern produced an empty file name")
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext != screenshot.Extension(n.format) && !(ext == ".jpeg" && n.format == screenshot.FormatJPEG) {
+		name += screenshot.Extension(n.format)
+	}
+	return name, nil
+}
@@ -0,0 +1,277 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"html_image_creator/pkg/post"
+)
+
+// ManifestName is the name of the manifest file at the root of a bundle
+const ManifestName = "manifest.json"
+
+// FormatVersion is the bundle layout version written to new manifests
+const FormatVersion = 1
+
+// DefaultMaxBytes limits the total uncompressed size of an imported bundle
+const DefaultMaxBytes = 512 * 1024 * 1024
+
+// Archive formats
+const (
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+)
+
+// FileEntry records a file in the bundle and its checksum
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a post bundle
+type Manifest struct {
+	FormatVersion    int         `json:"format_version"`
+	PostID           string      `json:"post_id"`
+	Name             string      `json:"name"`
+	Width            int         `json:"width"`
+	Height           int         `json:"height"`
+	ExportedAt       time.Time   `json:"exported_at"`
+	IncludesVersions bool        `json:"includes_versions"`
+	Files            []FileEntry `json:"files"`
+}
+
+// FormatFromPath picks the archive format from a file name, defaulting to zip
+func FormatFromPath(name string) string {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		return FormatTarGz
+	}
+	return FormatZip
+}
+
+// Write computes checksums for files, records them in manifest and writes
+// the bundle to w in the given format
+func Write(w io.Writer, format string, manifest *Manifest, files []*post.PostFile) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	manifest.FormatVersion = FormatVersion
+	manifest.Files = make([]FileEntry, len(files))
+	for i, f := range files {
+		sum := sha256.Sum256(f.Data)
+		manifest.Files[i] = FileEntry{
+			Path:   f.Path,
+			Size:   int64(len(f.Data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	entries := append([]*post.PostFile{{Path: ManifestName, Data: manifestData}}, files...)
+
+	switch format {
+	case FormatZip:
+		return writeZip(w, entries, manifest.ExportedAt)
+	case FormatTarGz:
+		return writeTarGz(w, entries, manifest.ExportedAt)
+	default:
+		return fmt.Errorf("unsupported bundle format %q: use %s or %s", format, FormatZip, FormatTarGz)
+	}
+}
+
+func writeZip(w io.Writer, entries []*post.PostFile, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     e.Path,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", e.Path, err)
+		}
+		if _, err := fw.Write(e.Data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.Path, err)
+		}
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, entries []*post.PostFile, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     e.Path,
+			Mode:     0644,
+			Size:     int64(len(e.Data)),
+			ModTime:  modTime,
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", e.Path, err)
+		}
+		if _, err := tw.Write(e.Data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.Path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read parses a zip or tar.gz bundle, verifies every file against the
+// manifest checksums and returns the manifest and post files. The total
+// uncompressed size is capped at maxBytes.
+func Read(data []byte, maxBytes int64) (*Manifest, []*post.PostFile, error) {
+	var (
+		entries map[string][]byte
+		err     error
+	)
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		entries, err = readZip(data, maxBytes)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		entries, err = readTarGz(data, maxBytes)
+	default:
+		return nil, nil, fmt.Errorf("unrecognized bundle format: expected zip or tar.gz")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifestData, ok := entries[ManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("bundle is missing %s", ManifestName)
+	}
+	delete(entries, ManifestName)
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, nil, fmt.Errorf("unsupported bundle format version %d", manifest.FormatVersion)
+	}
+
+	files := make([]*post.PostFile, 0, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		content, ok := entries[entry.Path]
+		if !ok {
+			return nil, nil, fmt.Errorf("bundle is missing %s listed in the manifest", entry.Path)
+		}
+		sum := sha256.Sum256(content)
+		if int64(len(content)) != entry.Size || hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", entry.Path)
+		}
+		delete(entries, entry.Path)
+		files = append(files, &post.PostFile{Path: entry.Path, Data: content})
+	}
+	if len(entries) > 0 {
+		extra := make([]string, 0, len(entries))
+		for name := range entries {
+			extra = append(extra, name)
+		}
+		sort.Strings(extra)
+		return nil, nil, fmt.Errorf("bundle contains files not listed in the manifest: %s", strings.Join(extra, ", "))
+	}
+
+	return &manifest, files, nil
+}
+
+func readZip(data []byte, maxBytes int64) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip bundle: %w", err)
+	}
+
+	entries := make(map[string][]byte)
+	var total int64
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		content, err := readLimited(rc, maxBytes-total)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		total += int64(len(content))
+		if _, dup := entries[f.Name]; dup {
+			return nil, fmt.Errorf("bundle contains %s more than once", f.Name)
+		}
+		entries[f.Name] = content
+	}
+	return entries, nil
+}
+
+func readTarGz(data []byte, maxBytes int64) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid tar.gz bundle: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	entries := make(map[string][]byte)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar.gz bundle: %w", err)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("bundle entry %s is not a regular file", hdr.Name)
+		}
+		content, err := readLimited(tr, maxBytes-total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+		total += int64(len(content))
+		if _, dup := entries[hdr.Name]; dup {
+			return nil, fmt.Errorf("bundle contains %s more than once", hdr.Name)
+		}
+		entries[hdr.Name] = content
+	}
+	return entries, nil
+}
+
+// readLimited reads r fully, failing if it holds more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit < 0 {
+		limit = 0
+	}
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("bundle exceeds maximum size")
+	}
+	return content, nil
+}
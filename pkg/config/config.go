@@ -4,13 +4,68 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"html_image_creator/pkg/media"
+	"html_image_creator/pkg/pathguard"
+)
+
+// Storage backends selectable with HTML_IMAGE_CREATOR_STORAGE
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
 )
 
+// S3Config holds the settings for the S3-compatible storage backend
+type S3Config struct {
+	Endpoint  string // e.g. http://localhost:9000 for MinIO; defaults to AWS
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+}
+
 // Config holds the configuration for the HTML Image Creator
 type Config struct {
-	RootDir string // Root directory for storing image posts
+	RootDir        string // Root directory for storing image posts
+	StorageBackend string // "local" or "s3"
+	S3             S3Config
+
+	MaxMediaBytes    int64         // Maximum size of a single imported media file
+	MaxMediaPixels   int64         // Maximum width times height of an imported raster image
+	FetchTimeout     time.Duration // Timeout for downloading remote media
+	AllowPrivateURLs bool          // Allow downloading media from loopback, private and link-local addresses
+	AllowedMedia     []string      // Content types accepted for media imports
+
+	AllowedSourceRoots []string // Directories media source_path may be read from (empty allows any)
+	AllowedOutputRoots []string // Directories export output_path may be written to (empty allows any)
+
+	WorkspacesFile string // JSON registry of named workspaces
+
+	MaxTotalBytes int64 // Storage quota for all posts and the trash of a workspace (0 is unlimited)
+	MaxPostBytes  int64 // Storage quota for a single post (0 is unlimited)
+	MaxPosts      int   // Maximum number of live posts in a workspace (0 is unlimited)
+
+	RetentionDays int // Default for garbage_collect: delete posts not updated in this many days (0 disables)
+	KeepVersions  int // Default for garbage_collect: keep only this many versions per post (0 disables)
+
+	defaultRootDir string // RootDir of the default workspace, kept by ForWorkspace
+
+	GitHistory     bool   // Record every change as a commit in a git repository at the root directory (local backend only)
+	GitAuthorName  string // Author of history commits
+	GitAuthorEmail string
 }
 
+// Default author of history commits, overridden with HTML_IMAGE_CREATOR_GIT_AUTHOR
+const (
+	defaultGitAuthorName  = "HTML Image Creator"
+	defaultGitAuthorEmail = "html-image-creator@localhost"
+)
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	rootDir := os.Getenv("HTML_IMAGE_CREATOR_ROOT_DIR")
@@ -27,7 +82,241 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to create root directory %s: %w", rootDir, err)
 	}
 
+	maxMediaBytes, err := getEnvInt64("HTML_IMAGE_CREATOR_MAX_MEDIA_BYTES", media.DefaultMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	maxMediaPixels, err := getEnvInt64("HTML_IMAGE_CREATOR_MAX_MEDIA_PIXELS", media.DefaultMaxPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchTimeout, err := getEnvDuration("HTML_IMAGE_CREATOR_FETCH_TIMEOUT", media.DefaultFetchTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	allowPrivateURLs := false
+	if raw := os.Getenv("HTML_IMAGE_CREATOR_ALLOW_PRIVATE_URLS"); raw != "" {
+		allowPrivateURLs, err = strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("HTML_IMAGE_CREATOR_ALLOW_PRIVATE_URLS must be a boolean, got %q", raw)
+		}
+	}
+
+	backend := os.Getenv("HTML_IMAGE_CREATOR_STORAGE")
+	if backend == "" {
+		backend = BackendLocal
+	}
+	if backend != BackendLocal && backend != BackendS3 {
+		return nil, fmt.Errorf("HTML_IMAGE_CREATOR_STORAGE must be %q or %q, got %q", BackendLocal, BackendS3, backend)
+	}
+
+	s3 := S3Config{
+		Endpoint:  os.Getenv("HTML_IMAGE_CREATOR_S3_ENDPOINT"),
+		Region:    os.Getenv("HTML_IMAGE_CREATOR_S3_REGION"),
+		Bucket:    os.Getenv("HTML_IMAGE_CREATOR_S3_BUCKET"),
+		Prefix:    os.Getenv("HTML_IMAGE_CREATOR_S3_PREFIX"),
+		AccessKey: getEnvFallback("HTML_IMAGE_CREATOR_S3_ACCESS_KEY", "AWS_ACCESS_KEY_ID"),
+		SecretKey: getEnvFallback("HTML_IMAGE_CREATOR_S3_SECRET_KEY", "AWS_SECRET_ACCESS_KEY"),
+		// Custom endpoints (MinIO and friends) generally need path-style addressing
+		PathStyle: os.Getenv("HTML_IMAGE_CREATOR_S3_ENDPOINT") != "",
+	}
+	if raw := os.Getenv("HTML_IMAGE_CREATOR_S3_PATH_STYLE"); raw != "" {
+		pathStyle, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("HTML_IMAGE_CREATOR_S3_PATH_STYLE must be a boolean, got %q", raw)
+		}
+		s3.PathStyle = pathStyle
+	}
+	if backend == BackendS3 && s3.Bucket == "" {
+		return nil, fmt.Errorf("HTML_IMAGE_CREATOR_S3_BUCKET is required when using the s3 storage backend")
+	}
+
+	workspacesFile := os.Getenv("HTML_IMAGE_CREATOR_WORKSPACES_FILE")
+	if workspacesFile == "" {
+		if configDir, err := os.UserConfigDir(); err == nil {
+			workspacesFile = filepath.Join(configDir, "html_image_creator", "workspaces.json")
+		} else {
+			workspacesFile = filepath.Join(rootDir, ".workspaces.json")
+		}
+	}
+
+	maxTotalBytes, err := getEnvInt64("HTML_IMAGE_CREATOR_MAX_TOTAL_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxPostBytes, err := getEnvInt64("HTML_IMAGE_CREATOR_MAX_POST_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+	maxPosts, err := getEnvInt64("HTML_IMAGE_CREATOR_MAX_POSTS", 0)
+	if err != nil {
+		return nil, err
+	}
+	retentionDays, err := getEnvInt64("HTML_IMAGE_CREATOR_RETENTION_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+	keepVersions, err := getEnvInt64("HTML_IMAGE_CREATOR_KEEP_VERSIONS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	gitHistory := false
+	if raw := os.Getenv("HTML_IMAGE_CREATOR_GIT_HISTORY"); raw != "" {
+		gitHistory, err = strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("HTML_IMAGE_CREATOR_GIT_HISTORY must be a boolean, got %q", raw)
+		}
+	}
+	if gitHistory && backend != BackendLocal {
+		return nil, fmt.Errorf("HTML_IMAGE_CREATOR_GIT_HISTORY requires the local storage backend")
+	}
+	gitAuthorName, gitAuthorEmail := defaultGitAuthorName, defaultGitAuthorEmail
+	if raw := os.Getenv("HTML_IMAGE_CREATOR_GIT_AUTHOR"); raw != "" {
+		gitAuthorName, gitAuthorEmail, err = parseAuthor(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	allowedMedia := media.DefaultAllowedTypes
+	if raw := os.Getenv("HTML_IMAGE_CREATOR_ALLOWED_MEDIA_TYPES"); raw != "" {
+		allowedMedia = splitList(raw)
+	}
+
 	return &Config{
-		RootDir: rootDir,
+		RootDir:            rootDir,
+		StorageBackend:     backend,
+		S3:                 s3,
+		MaxMediaBytes:      maxMediaBytes,
+		MaxMediaPixels:     maxMediaPixels,
+		FetchTimeout:       fetchTimeout,
+		AllowPrivateURLs:   allowPrivateURLs,
+		AllowedMedia:       allowedMedia,
+		AllowedSourceRoots: filepath.SplitList(os.Getenv("HTML_IMAGE_CREATOR_ALLOWED_SOURCE_ROOTS")),
+		AllowedOutputRoots: filepath.SplitList(os.Getenv("HTML_IMAGE_CREATOR_ALLOWED_OUTPUT_ROOTS")),
+		WorkspacesFile:     workspacesFile,
+		MaxTotalBytes:      maxTotalBytes,
+		MaxPostBytes:       maxPostBytes,
+		MaxPosts:           int(maxPosts),
+		RetentionDays:      int(retentionDays),
+		KeepVersions:       int(keepVersions),
+		defaultRootDir:     rootDir,
+		GitHistory:         gitHistory,
+		GitAuthorName:      gitAuthorName,
+		GitAuthorEmail:     gitAuthorEmail,
 	}, nil
 }
+
+// CheckSourcePath resolves a media source path and verifies it lies in an allowed source root
+func (c *Config) CheckSourcePath(path string) (string, error) {
+	return pathguard.CheckAllowed(path, c.AllowedSourceRoots)
+}
+
+// CheckOutputPath resolves an export output path and verifies it lies in an
+// allowed output root. Exports may never be written inside the storage root
+// of any workspace, where they could overwrite a post.
+func (c *Config) CheckOutputPath(path string) (string, error) {
+	resolved, err := pathguard.CheckAllowed(path, c.AllowedOutputRoots)
+	if err != nil {
+		return "", err
+	}
+
+	workspaces, err := c.Workspaces()
+	if err != nil {
+		return "", err
+	}
+	// The default root also holds the workspaces without their own root_dir
+	roots := []string{c.RootDir, c.DefaultRootDir()}
+	for _, ws := range workspaces {
+		if ws.RootDir != "" {
+			roots = append(roots, ws.RootDir)
+		}
+	}
+	for _, root := range roots {
+		inRoot, err := pathguard.Within(root, resolved)
+		if err != nil {
+			return "", err
+		}
+		if inRoot {
+			return "", fmt.Errorf("output path %s must not be inside the storage root %s", path, root)
+		}
+	}
+	return resolved, nil
+}
+
+// DefaultRootDir returns the storage root of the default workspace
+func (c *Config) DefaultRootDir() string {
+	if c.defaultRootDir != "" {
+		return c.defaultRootDir
+	}
+	return c.RootDir
+}
+
+// MediaPolicy returns the validation policy for imported media
+func (c *Config) MediaPolicy() media.Policy {
+	return media.Policy{
+		MaxBytes:     c.MaxMediaBytes,
+		MaxPixels:    c.MaxMediaPixels,
+		AllowedTypes: c.AllowedMedia,
+	}
+}
+
+// getEnvFallback returns the first non-empty environment variable among keys
+func getEnvFallback(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// getEnvInt64 reads a positive integer from the environment, falling back to def when unset
+func getEnvInt64(key string, def int64) (int64, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", key, raw)
+	}
+	return value, nil
+}
+
+// getEnvDuration reads a positive duration (e.g. "30s") from the environment, falling back to def when unset
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", key, raw)
+	}
+	return value, nil
+}
+
+// parseAuthor splits a "Name <email>" git author
+func parseAuthor(raw string) (string, string, error) {
+	open, end := strings.LastIndex(raw, "<"), strings.LastIndex(raw, ">")
+	if open < 0 || end < open || strings.TrimSpace(raw[:open]) == "" {
+		return "", "", fmt.Errorf("HTML_IMAGE_CREATOR_GIT_AUTHOR must look like \"Name <email>\", got %q", raw)
+	}
+	return strings.TrimSpace(raw[:open]), strings.TrimSpace(raw[open+1 : end]), nil
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"html_image_creator/pkg/pathguard"
+)
+
+// DefaultWorkspace is the name of the workspace configured by the environment
+const DefaultWorkspace = "default"
+
+// workspacesDir holds workspaces created without an explicit root directory,
+// inside the default workspace's storage root
+const workspacesDir = ".workspaces"
+
+var workspaceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,39}$`)
+
+// Workspace is a named project with its own storage root and defaults
+type Workspace struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	RootDir       string    `json:"root_dir,omitempty"`       // Local backend only; defaults to .workspaces/<name> in the default root
+	DefaultWidth  int       `json:"default_width,omitempty"`  // Canvas width used when create_image_post omits width
+	DefaultHeight int       `json:"default_height,omitempty"` // Canvas height used when create_image_post omits height
+	ExportFormat  string    `json:"export_format,omitempty"`  // Format used when export_image omits format
+	ExportScale   float64   `json:"export_scale,omitempty"`   // Scale used when export_image omits scale
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
+
+// workspacesFile is the structure of the workspace registry
+type workspacesFile struct {
+	Workspaces []*Workspace `json:"workspaces"`
+}
+
+// ValidateWorkspaceName checks that name can be used for a workspace
+func ValidateWorkspaceName(name string) error {
+	if !workspaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use up to 40 lowercase letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+// Workspaces returns the default workspace followed by the workspaces in the registry
+func (c *Config) Workspaces() ([]*Workspace, error) {
+	workspaces := []*Workspace{{Name: DefaultWorkspace, RootDir: c.RootDir}}
+	if c.StorageBackend != BackendLocal {
+		workspaces[0].RootDir = ""
+	}
+
+	data, err := os.ReadFile(c.WorkspacesFile)
+	if os.IsNotExist(err) {
+		return workspaces, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace registry: %w", err)
+	}
+	var file workspacesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace registry %s: %w", c.WorkspacesFile, err)
+	}
+	return append(workspaces, file.Workspaces...), nil
+}
+
+// Workspace returns the workspace with the given name; an empty name selects the default workspace
+func (c *Config) Workspace(name string) (*Workspace, error) {
+	if name == "" {
+		name = DefaultWorkspace
+	}
+	workspaces, err := c.Workspaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, ws := range workspaces {
+		if ws.Name == name {
+			return ws, nil
+		}
+	}
+	return nil, fmt.Errorf("workspace %s does not exist", name)
+}
+
+// AddWorkspace validates a new workspace, creates its root directory and
+// saves it in the registry
+func (c *Config) AddWorkspace(ws *Workspace) error {
+	if err := ValidateWorkspaceName(ws.Name); err != nil {
+		return err
+	}
+	if ws.DefaultWidth < 0 || ws.DefaultHeight < 0 {
+		return fmt.Errorf("default width and height must be positive integers")
+	}
+
+	workspaces, err := c.Workspaces()
+	if err != nil {
+		return err
+	}
+	for _, existing := range workspaces {
+		if existing.Name == ws.Name {
+			return fmt.Errorf("workspace %s already exists", ws.Name)
+		}
+	}
+
+	if ws.RootDir != "" {
+		if c.StorageBackend != BackendLocal {
+			return fmt.Errorf("root_dir is only supported with the local storage backend")
+		}
+		if !filepath.IsAbs(ws.RootDir) {
+			return fmt.Errorf("root_dir must be an absolute path")
+		}
+		root, err := pathguard.Resolve(ws.RootDir)
+		if err != nil {
+			return err
+		}
+		// Post directories of another workspace would show up as broken posts
+		inDefault, err := pathguard.Within(c.RootDir, root)
+		if err != nil {
+			return err
+		}
+		if inDefault {
+			return fmt.Errorf("root_dir must not be inside the default storage root %s", c.RootDir)
+		}
+		for _, existing := range workspaces {
+			if existing.RootDir != "" && filepath.Clean(existing.RootDir) == root {
+				return fmt.Errorf("root_dir is already used by workspace %s", existing.Name)
+			}
+		}
+		ws.RootDir = root
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return fmt.Errorf("failed to create workspace root %s: %w", root, err)
+		}
+	}
+	ws.CreatedAt = time.Now()
+
+	file := workspacesFile{Workspaces: append(workspaces[1:], ws)}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace registry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.WorkspacesFile), 0755); err != nil {
+		return fmt.Errorf("failed to create workspace registry directory: %w", err)
+	}
+	if err := os.WriteFile(c.WorkspacesFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+	return nil
+}
+
+// ForWorkspace returns a copy of the configuration whose storage points at
+// the workspace. Workspaces without a root directory are stored under
+// .workspaces/<name> in the default storage root or S3 prefix.
+func (c *Config) ForWorkspace(ws *Workspace) *Config {
+	if ws.Name == DefaultWorkspace {
+		return c
+	}
+	wc := *c
+	switch {
+	case ws.RootDir != "":
+		wc.RootDir = ws.RootDir
+	case c.StorageBackend == BackendS3:
+		wc.S3.Prefix = path.Join(c.S3.Prefix, workspacesDir, ws.Name)
+	default:
+		wc.RootDir = filepath.Join(c.RootDir, workspacesDir, ws.Name)
+	}
+	return &wc
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"html_image_creator/pkg/batch"
+	"html_image_creator/pkg/post"
+	"html_image_creator/pkg/screenshot"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+// SessionStarter is implemented by screenshot services that can share one
+// browser between many screenshots
+type SessionStarter interface {
+	NewSession() (*screenshot.Session, error)
+}
+
+// Batch row statuses
+const (
+	rowSucceeded = "succeeded"
+	rowFailed    = "failed"
+	rowSkipped   = "skipped"
+)
+
+// batchJob holds the state shared by the rows of a batch
+type batchJob struct {
+	tmpl      *post.PreparedTemplate // Parsed and copied once for every row
+	namer     *batch.Namer
+	declared  map[string]bool
+	isMedia   map[string]bool
+	dataDir   string // Base of relative media paths
+	outputDir string
+	opts      screenshot.Options
+	shooter   ScreenshotService
+	dryRun    bool
+	usedNames map[string]int // Output name to the row that claimed it
+}
+
+// batchRow is the outcome of one data row
+type batchRow struct {
+	Row        int    `json:"row"`
+	Status     string `json:"status"`
+	OutputPath string `json:"output_path,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (h *Handler) handleBatchGenerate(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	templateID, ok := args["template_id"].(string)
+	if !ok || templateID == "" {
+		return nil, fmt.Errorf("template_id is required and must be a string")
+	}
+	dataPath, ok := args["data_path"].(string)
+	if !ok || dataPath == "" {
+		return nil, fmt.Errorf("data_path is required and must be a string")
+	}
+	outputDir, ok := args["output_dir"].(string)
+	if !ok || outputDir == "" {
+		return nil, fmt.Errorf("output_dir is required and must be a string")
+	}
+	pattern, _ := args["output_pattern"].(string)
+	dryRun, _ := args["dry_run"].(bool)
+	var mediaColumns []string
+	if raw, ok := args["media_columns"].([]interface{}); ok {
+		for _, item := range raw {
+			column, ok := item.(string)
+			if !ok || column == "" {
+				return nil, fmt.Errorf("media_columns must be an array of column names")
+			}
+			mediaColumns = append(mediaColumns, column)
+		}
+	}
+
+	opts, err := h.exportOptions(args, pattern)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid export options: %v", err)), nil
+	}
+	namer, err := batch.NewNamer(pattern, opts.Format)
+	if err != nil {
+		return h.errorResponse(err.Error()), nil
+	}
+	if dataPath, err = h.config.CheckSourcePath(dataPath); err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid data_path: %v", err)), nil
+	}
+	if outputDir, err = h.config.CheckOutputPath(outputDir); err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid output_dir: %v", err)), nil
+	}
+
+	tmpl, err := h.postSvc.PrepareTemplate(templateID)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to prepare template: %v", err)), nil
+	}
+	defer tmpl.Close()
+	declared := make(map[string]bool)
+	for _, v := range tmpl.Post.Template.Variables {
+		declared[v.Name] = true
+	}
+	isMedia := make(map[string]bool)
+	for _, column := range mediaColumns {
+		if !declared[column] {
+			return h.errorResponse(fmt.Sprintf("Media column %s is not a variable of template %s", column, templateID)), nil
+		}
+		isMedia[column] = true
+	}
+
+	columns, rows, err := batch.ReadFile(dataPath, batch.DefaultMaxRows)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to read data file: %v", err)), nil
+	}
+	ignored := []string{}
+	for _, column := range columns {
+		if !declared[column] {
+			ignored = append(ignored, column)
+		}
+	}
+
+	// One browser renders every row; services without sessions shoot one by one
+	shooter := h.screenshotSvc
+	if starter, ok := h.screenshotSvc.(SessionStarter); ok && !dryRun && len(rows) > 0 {
+		session, err := starter.NewSession()
+		if err != nil {
+			return h.errorResponse(fmt.Sprintf("Failed to start browser: %v", err)), nil
+		}
+		defer session.Close()
+		shooter = session
+	}
+	if !dryRun {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return h.errorResponse(fmt.Sprintf("Failed to create output directory: %v", err)), nil
+		}
+	}
+
+	job := &batchJob{
+		tmpl:      tmpl,
+		namer:     namer,
+		declared:  declared,
+		isMedia:   isMedia,
+		dataDir:   filepath.Dir(dataPath),
+		outputDir: outputDir,
+		opts:      opts,
+		shooter:   shooter,
+		dryRun:    dryRun,
+		usedNames: make(map[string]int),
+	}
+	results := make([]*batchRow, 0, len(rows))
+	succeeded, failed := 0, 0
+	for _, row := range rows {
+		result := &batchRow{Row: row.Number}
+		results = append(results, result)
+		if ctx.Err() != nil {
+			result.Status = rowSkipped
+			result.Error = "batch was cancelled"
+			continue
+		}
+
+		if err := h.generateRow(job, row, result); err != nil {
+			result.Status = rowFailed
+			result.Error = err.Error()
+			failed++
+			continue
+		}
+		result.Status = rowSucceeded
+		succeeded++
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"status":          "succeeded",
+		"template_id":     templateID,
+		"output_dir":      outputDir,
+		"format":          opts.Format,
+		"scale":           opts.Scale,
+		"dry_run":         dryRun,
+		"total":           len(rows),
+		"succeeded":       succeeded,
+		"failed":          failed,
+		"ignored_columns": ignored,
+		"rows":            results,
+	}), nil
+}
+
+// generateRow renders one data row through the template and exports it into
+// the output directory, filling in result. In a dry run the row is rendered
+// and named but not exported.
+func (h *Handler) generateRow(job *batchJob, row *batch.Row, result *batchRow) error {
+	name, err := job.namer.Name(row)
+	if err != nil {
+		return err
+	}
+	if other, ok := job.usedNames[name]; ok {
+		return fmt.Errorf("output name %s is already used by row %d", name, other)
+	}
+	job.usedNames[name] = row.Number
+	result.OutputPath = filepath.Join(job.outputDir, name)
+
+	values := make(map[string]interface{})
+	mediaPaths := make(map[string]string)
+	for column, value := range row.Values {
+		switch {
+		case job.isMedia[column]:
+			path, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("media column %s must hold a file path", column)
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(job.dataDir, path)
+			}
+			if path, err = h.config.CheckSourcePath(path); err != nil {
+				return fmt.Errorf("invalid media path in column %s: %w", column, err)
+			}
+			mediaPaths[column] = path
+		case job.declared[column]:
+			values[column] = value
+		}
+	}
+	if values, err = job.tmpl.Post.Template.CoerceValues(values); err != nil {
+		return err
+	}
+
+	dir, err := job.tmpl.Materialize(values, mediaPaths)
+	if err != nil {
+		return err
+	}
+	if job.dryRun {
+		return nil
+	}
+
+	record, err := renderWith(job.shooter, dir, job.tmpl.Post.Width, job.tmpl.Post.Height, result.OutputPath, job.opts)
+	if err != nil {
+		return fmt.Errorf("failed to export image: %w", err)
+	}
+	result.Size = record.Size
+	return nil
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"html_image_creator/pkg/post"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleCreateBrandKit(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+
+	update, err := brandKitUpdateArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if update.Logos, err = h.logoPaths(update.Logos); err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid logos: %v", err)), nil
+	}
+
+	kit, err := h.postSvc.CreateBrandKit(name, update)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to create brand kit: %v", err)), nil
+	}
+
+	result := brandKitInfo(kit)
+	result["status"] = "succeeded"
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleUpdateBrandKit(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	kitID, ok := args["kit_id"].(string)
+	if !ok || kitID == "" {
+		return nil, fmt.Errorf("kit_id is required and must be a string")
+	}
+
+	update, err := brandKitUpdateArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := args["name"].(string); ok {
+		update.Name = &name
+	}
+	if update.Logos, err = h.logoPaths(update.Logos); err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid logos: %v", err)), nil
+	}
+
+	kit, synced, err := h.postSvc.UpdateBrandKit(kitID, update)
+	if err != nil {
+		if kit != nil {
+			return h.errorResponse(fmt.Sprintf("Failed to update posts using brand kit %s (updated: %v): %v", kitID, synced, err)), nil
+		}
+		return h.errorResponse(fmt.Sprintf("Failed to update brand kit: %v", err)), nil
+	}
+
+	result := brandKitInfo(kit)
+	result["status"] = "succeeded"
+	result["updated_posts"] = synced
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleListBrandKits(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	kits, err := h.postSvc.ListBrandKits()
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to list brand kits: %v", err)), nil
+	}
+
+	kitsList := make([]map[string]interface{}, len(kits))
+	for i, kit := range kits {
+		kitsList[i] = brandKitInfo(kit)
+	}
+
+	result := map[string]interface{}{
+		"status":     "succeeded",
+		"count":      len(kitsList),
+		"brand_kits": kitsList,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleApplyBrandKit(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+	kitID, ok := args["kit_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("kit_id is required and must be a string")
+	}
+
+	p, err := h.postSvc.ApplyBrandKit(postID, kitID)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to apply brand kit: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":     "succeeded",
+		"post_id":    p.ID,
+		"kit_id":     p.BrandKit,
+		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if p.BrandKit != "" {
+		kit, err := h.postSvc.GetBrandKit(p.BrandKit)
+		if err != nil {
+			return h.errorResponse(fmt.Sprintf("Failed to apply brand kit: %v", err)), nil
+		}
+		result["css"] = post.BrandCSS(kit)
+		logos := make(map[string]string, len(kit.Logos))
+		for _, logo := range kit.Logos {
+			logos[logo.Name] = post.BrandLogoPath(logo.Name)
+		}
+		result["logo_paths"] = logos
+	}
+
+	return h.successResponse(result), nil
+}
+
+// brandKitUpdateArgs reads the description, token and logo arguments of
+// create_brand_kit and update_brand_kit
+func brandKitUpdateArgs(args map[string]interface{}) (post.BrandKitUpdate, error) {
+	var update post.BrandKitUpdate
+	if description, ok := args["description"].(string); ok {
+		update.Description = &description
+	}
+
+	var err error
+	for key, field := range map[string]*map[string]string{
+		"palette":    &update.Palette,
+		"typography": &update.Typography,
+		"spacing":    &update.Spacing,
+		"logos":      &update.Logos,
+	} {
+		if *field, err = stringMapArg(args, key); err != nil {
+			return update, err
+		}
+	}
+	return update, nil
+}
+
+// stringMapArg returns an optional object argument whose values are strings.
+// Null values become empty strings, which remove the entry.
+func stringMapArg(args map[string]interface{}, key string) (map[string]string, error) {
+	raw, present := args[key]
+	if !present || raw == nil {
+		return nil, nil
+	}
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", key)
+	}
+	values := make(map[string]string, len(fields))
+	for name, rawValue := range fields {
+		if rawValue == nil {
+			values[name] = ""
+			continue
+		}
+		value, ok := rawValue.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a string or null", key, name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// logoPaths checks that logo source paths lie in an allowed source root
+func (h *Handler) logoPaths(logos map[string]string) (map[string]string, error) {
+	for name, sourcePath := range logos {
+		if sourcePath == "" {
+			continue
+		}
+		resolved, err := h.config.CheckSourcePath(sourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		logos[name] = resolved
+	}
+	return logos, nil
+}
+
+// brandKitInfo describes a brand kit for tool results
+func brandKitInfo(kit *post.BrandKit) map[string]interface{} {
+	info := map[string]interface{}{
+		"kit_id":     kit.ID,
+		"name":       kit.Name,
+		"palette":    kit.Palette,
+		"typography": kit.Typography,
+		"spacing":    kit.Spacing,
+		"logos":      kit.Logos,
+		"created_at": kit.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		"updated_at": kit.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if kit.Description != "" {
+		info["description"] = kit.Description
+	}
+	if kit.Logos == nil {
+		info["logos"] = []*post.BrandLogo{}
+	}
+	return info
+}
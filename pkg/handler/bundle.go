@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"html_image_creator/pkg/bundle"
+	"html_image_creator/pkg/post"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleExportPostBundle(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	outputPath, ok := args["output_path"].(string)
+	if !ok || outputPath == "" {
+		return nil, fmt.Errorf("output_path is required and must be a string")
+	}
+
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = bundle.FormatFromPath(outputPath)
+	}
+	includeVersions, _ := args["include_versions"].(bool)
+
+	outputPath, err := h.config.CheckOutputPath(outputPath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid output_path: %v", err)), nil
+	}
+
+	p, files, err := h.postSvc.ExportFiles(postID, includeVersions)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to export post bundle: %v", err)), nil
+	}
+
+	manifest := &bundle.Manifest{
+		PostID:           p.ID,
+		Name:             p.Name,
+		Width:            p.Width,
+		Height:           p.Height,
+		ExportedAt:       time.Now().UTC(),
+		IncludesVersions: includeVersions,
+	}
+	var buf bytes.Buffer
+	if err := bundle.Write(&buf, format, manifest, files); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to export post bundle: %v", err)), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to create output directory: %v", err)), nil
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to write post bundle: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":      "succeeded",
+		"post_id":     p.ID,
+		"output_path": outputPath,
+		"format":      format,
+		"file_count":  len(manifest.Files),
+		"size":        buf.Len(),
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleImportPostBundle(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	bundlePath, ok := args["bundle_path"].(string)
+	if !ok || bundlePath == "" {
+		return nil, fmt.Errorf("bundle_path is required and must be a string")
+	}
+
+	opts := post.ImportOptions{PreserveID: true}
+	if v, ok := args["preserve_id"].(bool); ok {
+		opts.PreserveID = v
+	}
+	opts.OnConflict, _ = args["on_conflict"].(string)
+	opts.Name, _ = args["name"].(string)
+
+	bundlePath, err := h.config.CheckSourcePath(bundlePath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid bundle_path: %v", err)), nil
+	}
+
+	stat, err := os.Stat(bundlePath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to read post bundle: %v", err)), nil
+	}
+	if stat.Size() > bundle.DefaultMaxBytes {
+		return h.errorResponse(fmt.Sprintf("Post bundle is %d bytes, exceeding maximum size of %d bytes", stat.Size(), bundle.DefaultMaxBytes)), nil
+	}
+	data, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to read post bundle: %v", err)), nil
+	}
+
+	manifest, files, err := bundle.Read(data, bundle.DefaultMaxBytes)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid post bundle: %v", err)), nil
+	}
+
+	p, err := h.postSvc.ImportFiles(manifest.PostID, files, opts)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to import post bundle: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":           "succeeded",
+		"post_id":          p.ID,
+		"original_post_id": manifest.PostID,
+		"name":             p.Name,
+		"width":            p.Width,
+		"height":           p.Height,
+		"file_path":        h.postSvc.PostLocation(p.ID),
+		"file_count":       len(files),
+	}
+
+	return h.successResponse(result), nil
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"html_image_creator/pkg/post"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleRegisterComponent(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+	htmlContent, ok := args["html"].(string)
+	if !ok || htmlContent == "" {
+		return nil, fmt.Errorf("html is required and must be a string")
+	}
+	c := &post.Component{Name: name, HTML: htmlContent}
+	c.CSS, _ = args["css"].(string)
+	c.Description, _ = args["description"].(string)
+
+	if raw, ok := args["params"].([]interface{}); ok {
+		// Round-trip through JSON to decode the parameter objects
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+		if err := json.Unmarshal(data, &c.Params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+
+	c, err := h.postSvc.RegisterComponent(c)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to register component: %v", err)), nil
+	}
+
+	result := componentInfo(c)
+	result["status"] = "succeeded"
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleListComponents(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	components, err := h.postSvc.ListComponents()
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to list components: %v", err)), nil
+	}
+
+	componentsList := make([]map[string]interface{}, len(components))
+	for i, c := range components {
+		componentsList[i] = componentInfo(c)
+	}
+
+	result := map[string]interface{}{
+		"status":     "succeeded",
+		"count":      len(componentsList),
+		"components": componentsList,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handlePreviewComponent(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+	params, err := stringMapArg(args, "params")
+	if err != nil {
+		return nil, err
+	}
+	content, _ := args["content"].(string)
+
+	rendered, err := h.postSvc.PreviewComponent(name, params, content)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to preview component: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status": "succeeded",
+		"name":   name,
+		"html":   rendered,
+	}
+
+	outputPath, _ := args["output_path"].(string)
+	if outputPath == "" {
+		return h.successResponse(result), nil
+	}
+
+	width, _ := args["width"].(float64)
+	height, _ := args["height"].(float64)
+	if width == 0 {
+		width = float64(h.workspace.DefaultWidth)
+	}
+	if height == 0 {
+		height = float64(h.workspace.DefaultHeight)
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("width and height are required with output_path when the workspace has no default canvas size")
+	}
+	opts, err := h.exportOptions(args, outputPath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid export options: %v", err)), nil
+	}
+	if outputPath, err = h.config.CheckOutputPath(outputPath); err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid output_path: %v", err)), nil
+	}
+
+	dir, err := os.MkdirTemp("", "html-image-component-")
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to prepare preview: %v", err)), nil
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(rendered), 0644); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to prepare preview: %v", err)), nil
+	}
+
+	record, err := h.renderExport(dir, int(width), int(height), outputPath, opts)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to export image: %v", err)), nil
+	}
+	result["output_path"] = record.OutputPath
+	result["format"] = record.Format
+	result["scale"] = record.Scale
+	result["size"] = record.Size
+	return h.successResponse(result), nil
+}
+
+// componentInfo describes a component for tool results
+func componentInfo(c *post.Component) map[string]interface{} {
+	info := map[string]interface{}{
+		"name":       c.Name,
+		"html":       c.HTML,
+		"params":     c.Params,
+		"created_at": c.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		"updated_at": c.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if c.Description != "" {
+		info["description"] = c.Description
+	}
+	if c.CSS != "" {
+		info["css"] = c.CSS
+	}
+	if c.Params == nil {
+		info["params"] = []*post.ComponentParam{}
+	}
+	return info
+}
@@ -5,33 +5,94 @@ import (
 	"encoding/json"
 	"fmt"
 	"html_image_creator/pkg/config"
+	"html_image_creator/pkg/htmledit"
+	"html_image_creator/pkg/media"
 	"html_image_creator/pkg/post"
+	"html_image_creator/pkg/screenshot"
 	"html_image_creator/pkg/storage"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/gomcpgo/mcp/pkg/protocol"
 )
 
-// Handler implements the MCP protocol for HTML Image Creator
+// Handler implements the MCP protocol for HTML Image Creator. Each handler
+// serves one workspace; calls naming another workspace are routed to a
+// handler created for it on first use.
 type Handler struct {
 	config        *config.Config
+	workspace     *config.Workspace
 	postSvc       *post.Service
 	screenshotSvc ScreenshotService
+	fetcher       *media.Fetcher
+
+	mu         sync.Mutex
+	workspaces map[string]*Handler
 }
 
 // ScreenshotService defines the interface for screenshot functionality
 type ScreenshotService interface {
-	TakeScreenshot(postDir string, width, height int, outputPath string) error
+	TakeScreenshot(postDir string, width, height int, outputPath string, opts screenshot.Options) error
 }
 
-// NewHandler creates a new handler instance
-func NewHandler(cfg *config.Config, screenshotSvc ScreenshotService) *Handler {
-	store := storage.NewStorage(cfg.RootDir)
-	postSvc := post.NewService(store)
+// NewHandler creates a new handler instance serving the default workspace
+func NewHandler(cfg *config.Config, screenshotSvc ScreenshotService) (*Handler, error) {
+	ws, err := cfg.Workspace(config.DefaultWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	h, err := newWorkspaceHandler(cfg, ws, screenshotSvc, media.NewFetcher(cfg.FetchTimeout, cfg.MaxMediaBytes, cfg.AllowPrivateURLs))
+	if err != nil {
+		return nil, err
+	}
+	h.workspaces = make(map[string]*Handler)
+	return h, nil
+}
+
+// newWorkspaceHandler creates a handler whose storage points at ws
+func newWorkspaceHandler(cfg *config.Config, ws *config.Workspace, screenshotSvc ScreenshotService, fetcher *media.Fetcher) (*Handler, error) {
+	wsCfg := cfg.ForWorkspace(ws)
+	backend, err := newBackend(wsCfg)
+	if err != nil {
+		return nil, err
+	}
+	store := storage.NewStorage(backend, storage.Quota{
+		MaxTotalBytes: wsCfg.MaxTotalBytes,
+		MaxPostBytes:  wsCfg.MaxPostBytes,
+		MaxPosts:      wsCfg.MaxPosts,
+	}, wsCfg.MediaPolicy())
+	postSvc := post.NewService(store, wsCfg.MediaPolicy())
 
 	return &Handler{
-		config:        cfg,
+		config:        wsCfg,
+		workspace:     ws,
 		postSvc:       postSvc,
 		screenshotSvc: screenshotSvc,
+		fetcher:       fetcher,
+	}, nil
+}
+
+// newBackend creates the storage backend selected in the configuration
+func newBackend(cfg *config.Config) (storage.Backend, error) {
+	switch cfg.StorageBackend {
+	case config.BackendLocal:
+		if cfg.GitHistory {
+			return storage.NewGitBackend(cfg.RootDir, cfg.GitAuthorName, cfg.GitAuthorEmail)
+		}
+		return storage.NewLocalBackend(cfg.RootDir), nil
+	case config.BackendS3:
+		return storage.NewS3Backend(storage.S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			Prefix:    cfg.S3.Prefix,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			PathStyle: cfg.S3.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.StorageBackend)
 	}
 }
 
@@ -45,11 +106,36 @@ func (h *Handler) ListTools(ctx context.Context) (*protocol.ListToolsResponse, e
 
 // CallTool handles tool invocations
 func (h *Handler) CallTool(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResponse, error) {
+	switch req.Name {
+	case "list_workspaces":
+		return h.handleListWorkspaces(ctx, req.Arguments)
+	case "create_workspace":
+		return h.handleCreateWorkspace(ctx, req.Arguments)
+	}
+
+	name, _ := req.Arguments["workspace"].(string)
+	wh, err := h.forWorkspace(name)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid workspace: %v", err)), nil
+	}
+	return wh.callTool(ctx, req)
+}
+
+// callTool dispatches a tool call within this handler's workspace
+func (h *Handler) callTool(ctx context.Context, req *protocol.CallToolRequest) (*protocol.CallToolResponse, error) {
 	switch req.Name {
 	case "create_image_post":
 		return h.handleCreateImagePost(ctx, req.Arguments)
 	case "update_image_post":
 		return h.handleUpdateImagePost(ctx, req.Arguments)
+	case "edit_image_post":
+		return h.handleEditImagePost(ctx, req.Arguments)
+	case "update_post_metadata":
+		return h.handleUpdatePostMetadata(ctx, req.Arguments)
+	case "resize_image_post":
+		return h.handleResizeImagePost(ctx, req.Arguments)
+	case "clone_image_post":
+		return h.handleCloneImagePost(ctx, req.Arguments)
 	case "get_image_post":
 		return h.handleGetImagePost(ctx, req.Arguments)
 	case "list_image_posts":
@@ -58,6 +144,62 @@ func (h *Handler) CallTool(ctx context.Context, req *protocol.CallToolRequest) (
 		return h.handleExportImage(ctx, req.Arguments)
 	case "add_media":
 		return h.handleAddMedia(ctx, req.Arguments)
+	case "localize_media":
+		return h.handleLocalizeMedia(ctx, req.Arguments)
+	case "delete_image_post":
+		return h.handleDeleteImagePost(ctx, req.Arguments)
+	case "list_trash":
+		return h.handleListTrash(ctx, req.Arguments)
+	case "restore_image_post":
+		return h.handleRestoreImagePost(ctx, req.Arguments)
+	case "empty_trash":
+		return h.handleEmptyTrash(ctx, req.Arguments)
+	case "tag_image_post":
+		return h.handleTagImagePost(ctx, req.Arguments)
+	case "create_collection":
+		return h.handleCreateCollection(ctx, req.Arguments)
+	case "list_collections":
+		return h.handleListCollections(ctx, req.Arguments)
+	case "move_to_collection":
+		return h.handleMoveToCollection(ctx, req.Arguments)
+	case "delete_collection":
+		return h.handleDeleteCollection(ctx, req.Arguments)
+	case "create_brand_kit":
+		return h.handleCreateBrandKit(ctx, req.Arguments)
+	case "update_brand_kit":
+		return h.handleUpdateBrandKit(ctx, req.Arguments)
+	case "list_brand_kits":
+		return h.handleListBrandKits(ctx, req.Arguments)
+	case "apply_brand_kit":
+		return h.handleApplyBrandKit(ctx, req.Arguments)
+	case "register_component":
+		return h.handleRegisterComponent(ctx, req.Arguments)
+	case "list_components":
+		return h.handleListComponents(ctx, req.Arguments)
+	case "preview_component":
+		return h.handlePreviewComponent(ctx, req.Arguments)
+	case "migrate_storage":
+		return h.handleMigrateStorage(ctx, req.Arguments)
+	case "check_storage":
+		return h.handleCheckStorage(ctx, req.Arguments)
+	case "garbage_collect":
+		return h.handleGarbageCollect(ctx, req.Arguments)
+	case "define_template":
+		return h.handleDefineTemplate(ctx, req.Arguments)
+	case "render_template":
+		return h.handleRenderTemplate(ctx, req.Arguments)
+	case "batch_generate":
+		return h.handleBatchGenerate(ctx, req.Arguments)
+	case "storage_usage":
+		return h.handleStorageUsage(ctx, req.Arguments)
+	case "get_post_log":
+		return h.handleGetPostLog(ctx, req.Arguments)
+	case "checkout_post_revision":
+		return h.handleCheckoutPostRevision(ctx, req.Arguments)
+	case "export_post_bundle":
+		return h.handleExportPostBundle(ctx, req.Arguments)
+	case "import_post_bundle":
+		return h.handleImportPostBundle(ctx, req.Arguments)
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", req.Name)
 	}
@@ -74,17 +216,19 @@ func (h *Handler) handleCreateImagePost(ctx context.Context, args map[string]int
 		return nil, fmt.Errorf("html_content is required and must be a string")
 	}
 
-	widthFloat, ok := args["width"].(float64)
-	if !ok {
+	width := h.workspace.DefaultWidth
+	if widthFloat, ok := args["width"].(float64); ok {
+		width = int(widthFloat)
+	} else if width == 0 {
 		return nil, fmt.Errorf("width is required and must be an integer")
 	}
-	width := int(widthFloat)
 
-	heightFloat, ok := args["height"].(float64)
-	if !ok {
+	height := h.workspace.DefaultHeight
+	if heightFloat, ok := args["height"].(float64); ok {
+		height = int(heightFloat)
+	} else if height == 0 {
 		return nil, fmt.Errorf("height is required and must be an integer")
 	}
-	height := int(heightFloat)
 
 	p, err := h.postSvc.CreatePost(name, htmlContent, width, height)
 	if err != nil {
@@ -93,18 +237,20 @@ func (h *Handler) handleCreateImagePost(ctx context.Context, args map[string]int
 
 	// Process optional media_files
 	var mediaPaths map[string]string
+	var mediaInfos []*post.MediaInfo
 	if mediaFilesRaw, ok := args["media_files"].([]interface{}); ok && len(mediaFilesRaw) > 0 {
+		sources, err := parseMediaFiles(mediaFilesRaw)
+		if err != nil {
+			return h.errorResponse(fmt.Sprintf("Invalid media_files: %v", err)), nil
+		}
 		mediaPaths = make(map[string]string)
-		for _, mf := range mediaFilesRaw {
-			sourcePath, ok := mf.(string)
-			if !ok || sourcePath == "" {
-				continue
-			}
-			relativePath, err := h.postSvc.AddMedia(p.ID, sourcePath)
+		for _, src := range sources {
+			info, err := h.importMedia(ctx, p.ID, src)
 			if err != nil {
-				return h.errorResponse(fmt.Sprintf("Failed to add media file %s: %v", sourcePath, err)), nil
+				return h.errorResponse(fmt.Sprintf("Failed to add media file %s: %v", src.label(), err)), nil
 			}
-			mediaPaths[sourcePath] = relativePath
+			mediaPaths[src.label()] = info.Path
+			mediaInfos = append(mediaInfos, info)
 		}
 	}
 
@@ -114,13 +260,14 @@ func (h *Handler) handleCreateImagePost(ctx context.Context, args map[string]int
 		"name":       p.Name,
 		"width":      p.Width,
 		"height":     p.Height,
-		"file_path":  h.postSvc.GetHTMLPath(p.ID),
+		"file_path":  h.postSvc.PostLocation(p.ID),
 		"created_at": p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 
 	if len(mediaPaths) > 0 {
 		result["media_paths"] = mediaPaths
+		result["media"] = mediaInfos
 	}
 
 	return h.successResponse(result), nil
@@ -148,9 +295,179 @@ func (h *Handler) handleUpdateImagePost(ctx context.Context, args map[string]int
 		"name":       p.Name,
 		"width":      p.Width,
 		"height":     p.Height,
-		"file_path":  h.postSvc.GetHTMLPath(p.ID),
+		"file_path":  h.postSvc.PostLocation(p.ID),
+		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleEditImagePost(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	raw, ok := args["operations"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("operations is required and must be a non-empty array")
+	}
+	// Round-trip through JSON to decode the operation objects
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operations: %w", err)
+	}
+	var ops []*htmledit.Operation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("invalid operations: %w", err)
+	}
+
+	p, edit, err := h.postSvc.EditPost(postID, ops)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to edit image post: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":     "succeeded",
+		"post_id":    p.ID,
+		"operations": edit.Results,
+		"file_path":  h.postSvc.PostLocation(p.ID),
+		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if edit.Normalization != "" {
+		result["warning"] = fmt.Sprintf("Re-rendering the HTML also changed markup outside the edited elements, first at %s", edit.Normalization)
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleUpdatePostMetadata(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	var update post.MetadataUpdate
+	for key, field := range map[string]**string{
+		"name":        &update.Name,
+		"description": &update.Description,
+		"caption":     &update.Caption,
+		"alt_text":    &update.AltText,
+	} {
+		raw, present := args[key]
+		if !present {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", key)
+		}
+		*field = &value
+	}
+
+	if raw, present := args["custom_fields"]; present {
+		fields, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("custom_fields must be an object")
+		}
+		update.CustomFields = make(map[string]*string, len(fields))
+		for key, rawValue := range fields {
+			if rawValue == nil {
+				update.CustomFields[key] = nil
+				continue
+			}
+			value, ok := rawValue.(string)
+			if !ok {
+				return nil, fmt.Errorf("custom_fields.%s must be a string or null", key)
+			}
+			update.CustomFields[key] = &value
+		}
+	}
+
+	p, err := h.postSvc.UpdateMetadata(postID, update)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to update post metadata: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":     "succeeded",
+		"post_id":    p.ID,
+		"name":       p.Name,
+		"file_path":  h.postSvc.PostLocation(p.ID),
 		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
+	addPostDetails(result, p.PostDetails)
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleResizeImagePost(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	widthFloat, ok := args["width"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("width is required and must be an integer")
+	}
+
+	heightFloat, ok := args["height"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("height is required and must be an integer")
+	}
+
+	mode, _ := args["mode"].(string)
+
+	p, err := h.postSvc.ResizePost(postID, int(widthFloat), int(heightFloat), mode)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to resize image post: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":     "succeeded",
+		"post_id":    p.ID,
+		"name":       p.Name,
+		"width":      p.Width,
+		"height":     p.Height,
+		"file_path":  h.postSvc.PostLocation(p.ID),
+		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		"resize":     p.History[len(p.History)-1].Details,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleCloneImagePost(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+
+	widthFloat, _ := args["width"].(float64)
+	heightFloat, _ := args["height"].(float64)
+	rescale, _ := args["rescale"].(bool)
+
+	p, err := h.postSvc.ClonePost(postID, name, int(widthFloat), int(heightFloat), rescale)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to clone image post: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":         "succeeded",
+		"post_id":        p.ID,
+		"source_post_id": postID,
+		"name":           p.Name,
+		"width":          p.Width,
+		"height":         p.Height,
+		"file_path":      h.postSvc.PostLocation(p.ID),
+		"created_at":     p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
 
 	return h.successResponse(result), nil
 }
@@ -173,19 +490,45 @@ func (h *Handler) handleGetImagePost(ctx context.Context, args map[string]interf
 		"html_content": p.HTMLContent,
 		"width":        p.Width,
 		"height":       p.Height,
-		"file_path":    h.postSvc.GetHTMLPath(p.ID),
+		"file_path":    h.postSvc.PostLocation(p.ID),
 		"created_at":   p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		"updated_at":   p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 
+	if mediaFiles, err := h.postSvc.ListMedia(postID); err == nil && len(mediaFiles) > 0 {
+		result["media"] = mediaFiles
+	}
+
+	addPostDetails(result, p.PostDetails)
+
+	if len(p.History) > 0 {
+		result["history"] = p.History
+	}
+
+	if latest := p.LatestExport(); latest != nil {
+		result["latest_export"] = latest
+		result["export_count"] = len(p.Exports)
+	}
+	digest, err := h.postSvc.MediaDigest(postID)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to read media: %v", err)), nil
+	}
+	result["export_stale"] = p.ExportStale(digest)
+
 	return h.successResponse(result), nil
 }
 
 func (h *Handler) handleListImagePosts(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
-	posts, err := h.postSvc.ListPosts()
+	opts, err := parseListOptions(args)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := h.postSvc.ListPosts(opts)
 	if err != nil {
 		return h.errorResponse(fmt.Sprintf("Failed to list image posts: %v", err)), nil
 	}
+	posts := page.Posts
 
 	postsList := make([]map[string]interface{}, len(posts))
 	for i, p := range posts {
@@ -194,17 +537,22 @@ func (h *Handler) handleListImagePosts(ctx context.Context, args map[string]inte
 			"name":       p.Name,
 			"width":      p.Width,
 			"height":     p.Height,
-			"file_path":  h.postSvc.GetHTMLPath(p.ID),
+			"file_path":  h.postSvc.PostLocation(p.ID),
 			"created_at": p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		}
+		addPostDetails(postsList[i], p.PostDetails)
 	}
 
 	result := map[string]interface{}{
 		"status": "succeeded",
 		"count":  len(postsList),
+		"total":  page.Total,
 		"posts":  postsList,
 	}
+	if page.NextCursor != "" {
+		result["next_cursor"] = page.NextCursor
+	}
 
 	return h.successResponse(result), nil
 }
@@ -220,15 +568,24 @@ func (h *Handler) handleExportImage(ctx context.Context, args map[string]interfa
 		return nil, fmt.Errorf("output_path is required and must be a string")
 	}
 
+	opts, err := h.exportOptions(args, outputPath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid export options: %v", err)), nil
+	}
+
+	outputPath, err = h.config.CheckOutputPath(outputPath)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Invalid output_path: %v", err)), nil
+	}
+
 	// Get post to read dimensions
 	p, err := h.postSvc.GetPost(postID)
 	if err != nil {
 		return h.errorResponse(fmt.Sprintf("Failed to get post: %v", err)), nil
 	}
 
-	// Take screenshot
-	postDir := h.postSvc.GetPostPath(postID)
-	if err := h.screenshotSvc.TakeScreenshot(postDir, p.Width, p.Height, outputPath); err != nil {
+	record, err := h.exportPost(p, outputPath, opts)
+	if err != nil {
 		return h.errorResponse(fmt.Sprintf("Failed to export image: %v", err)), nil
 	}
 
@@ -236,23 +593,106 @@ func (h *Handler) handleExportImage(ctx context.Context, args map[string]interfa
 		"status":      "succeeded",
 		"post_id":     postID,
 		"output_path": outputPath,
+		"format":      record.Format,
+		"scale":       record.Scale,
+		"size":        record.Size,
+		"html_hash":   record.HTMLHash,
+	}
+
+	if err := h.postSvc.RecordExport(postID, record); err != nil {
+		result["warning"] = fmt.Sprintf("Image exported but not recorded in export history: %v", err)
 	}
 
 	return h.successResponse(result), nil
 }
 
+// exportOptions reads the format, scale and quality arguments of an export.
+// An explicit argument wins over an image extension on outputPath, which
+// wins over the workspace default. Extensions that aren't an image format,
+// like the end of a name template, are ignored.
+func (h *Handler) exportOptions(args map[string]interface{}, outputPath string) (screenshot.Options, error) {
+	opts := screenshot.Options{Format: h.workspace.ExportFormat, Scale: h.workspace.ExportScale}
+	if format, ok := screenshot.FormatOfExtension(outputPath); ok {
+		opts.Format = format
+	} else if opts.Format == "" {
+		opts.Format = screenshot.FormatPNG
+	}
+	if format, ok := args["format"].(string); ok && format != "" {
+		opts.Format = format
+	}
+	if scale, ok := args["scale"].(float64); ok && scale != 0 {
+		opts.Scale = scale
+	}
+	if quality, ok := args["quality"].(float64); ok {
+		opts.Quality = int(quality)
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, err
+	}
+	return opts.WithDefaults(), nil
+}
+
+// exportPost renders a post to outputPath and returns the export record,
+// which the caller records in the post's history
+func (h *Handler) exportPost(p *post.ImagePost, outputPath string, opts screenshot.Options) (*post.ExportRecord, error) {
+	postDir, cleanup, err := h.postSvc.MaterializePost(p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare post for rendering: %w", err)
+	}
+	defer cleanup()
+
+	digest, err := h.postSvc.MediaDigest(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := h.renderExport(postDir, p.Width, p.Height, outputPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	record.Width, record.Height = p.Width, p.Height
+	record.HTMLHash = post.HashHTML(p.HTMLContent)
+	record.MediaDigest = digest
+	return record, nil
+}
+
+// renderExport screenshots the HTML in postDir to outputPath
+func (h *Handler) renderExport(postDir string, width, height int, outputPath string, opts screenshot.Options) (*post.ExportRecord, error) {
+	return renderWith(h.screenshotSvc, postDir, width, height, outputPath, opts)
+}
+
+// renderWith screenshots the HTML in postDir to outputPath using shooter
+func renderWith(shooter ScreenshotService, postDir string, width, height int, outputPath string, opts screenshot.Options) (*post.ExportRecord, error) {
+	if err := shooter.TakeScreenshot(postDir, width, height, outputPath, opts); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported image: %w", err)
+	}
+
+	return &post.ExportRecord{
+		OutputPath: outputPath,
+		Format:     opts.Format,
+		Scale:      opts.Scale,
+		Size:       stat.Size(),
+		ExportedAt: time.Now(),
+	}, nil
+}
+
 func (h *Handler) handleAddMedia(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
 	postID, ok := args["post_id"].(string)
 	if !ok || postID == "" {
 		return nil, fmt.Errorf("post_id is required and must be a string")
 	}
 
-	sourcePath, ok := args["source_path"].(string)
-	if !ok || sourcePath == "" {
-		return nil, fmt.Errorf("source_path is required and must be a string")
+	src, err := parseMediaSource(args)
+	if err != nil {
+		return nil, err
 	}
 
-	relativePath, err := h.postSvc.AddMedia(postID, sourcePath)
+	info, err := h.importMedia(ctx, postID, src)
 	if err != nil {
 		return h.errorResponse(fmt.Sprintf("Failed to add media: %v", err)), nil
 	}
@@ -260,7 +700,37 @@ func (h *Handler) handleAddMedia(ctx context.Context, args map[string]interface{
 	result := map[string]interface{}{
 		"status":        "succeeded",
 		"post_id":       postID,
-		"relative_path": relativePath,
+		"relative_path": info.Path,
+		"content_type":  info.ContentType,
+		"size":          info.Size,
+	}
+	if info.Width > 0 && info.Height > 0 {
+		result["width"] = info.Width
+		result["height"] = info.Height
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleLocalizeMedia(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	localized, failed, err := h.localizeRemoteMedia(ctx, postID)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to localize media: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":    "succeeded",
+		"post_id":   postID,
+		"localized": localized,
+		"count":     len(localized),
+	}
+	if len(failed) > 0 {
+		result["failed"] = failed
 	}
 
 	return h.successResponse(result), nil
@@ -268,6 +738,34 @@ func (h *Handler) handleAddMedia(ctx context.Context, args map[string]interface{
 
 // Helper methods
 
+// addPostDetails adds the non-empty descriptive metadata fields to a result
+func addPostDetails(result map[string]interface{}, details post.PostDetails) {
+	if details.Description != "" {
+		result["description"] = details.Description
+	}
+	if details.Caption != "" {
+		result["caption"] = details.Caption
+	}
+	if details.AltText != "" {
+		result["alt_text"] = details.AltText
+	}
+	if len(details.CustomFields) > 0 {
+		result["custom_fields"] = details.CustomFields
+	}
+	if len(details.Tags) > 0 {
+		result["tags"] = details.Tags
+	}
+	if details.Collection != "" {
+		result["collection"] = details.Collection
+	}
+	if details.BrandKit != "" {
+		result["brand_kit"] = details.BrandKit
+	}
+	if details.Template != nil {
+		result["template"] = details.Template
+	}
+}
+
 func (h *Handler) successResponse(data map[string]interface{}) *protocol.CallToolResponse {
 	jsonData, _ := json.MarshalIndent(data, "", "  ")
 	return &protocol.CallToolResponse{
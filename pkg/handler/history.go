@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+// defaultLogLimit is the number of revisions get_post_log returns when no limit is given
+const defaultLogLimit = 20
+
+func (h *Handler) handleGetPostLog(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, _ := args["post_id"].(string)
+	limit := defaultLogLimit
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	revisions, err := h.postSvc.PostLog(postID, limit)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to get post log: %v", err)), nil
+	}
+
+	list := make([]map[string]interface{}, len(revisions))
+	for i, r := range revisions {
+		list[i] = map[string]interface{}{
+			"hash":    r.Hash,
+			"message": r.Message,
+			"author":  r.Author,
+			"at":      r.At.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
+	result := map[string]interface{}{
+		"status":    "succeeded",
+		"revisions": list,
+		"count":     len(list),
+	}
+	if postID != "" {
+		result["post_id"] = postID
+	}
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleCheckoutPostRevision(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+	revision, ok := args["revision"].(string)
+	if !ok {
+		return nil, fmt.Errorf("revision is required and must be a string")
+	}
+
+	p, hash, err := h.postSvc.CheckoutRevision(postID, revision)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to check out revision: %v", err)), nil
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"status":     "succeeded",
+		"post_id":    p.ID,
+		"name":       p.Name,
+		"revision":   hash,
+		"width":      p.Width,
+		"height":     p.Height,
+		"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		"file_path":  h.postSvc.PostLocation(p.ID),
+	}), nil
+}
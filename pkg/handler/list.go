@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"html_image_creator/pkg/post"
+)
+
+// parseListOptions reads the list_image_posts query arguments
+func parseListOptions(args map[string]interface{}) (post.ListOptions, error) {
+	var opts post.ListOptions
+	opts.Query, _ = args["query"].(string)
+	opts.Collection, _ = args["collection"].(string)
+	opts.SortBy, _ = args["sort_by"].(string)
+	opts.Cursor, _ = args["cursor"].(string)
+
+	tags, err := stringSliceArg(args, "tags")
+	if err != nil {
+		return opts, err
+	}
+	if tag, _ := args["tag"].(string); tag != "" {
+		tags = append(tags, tag)
+	}
+	opts.Tags = tags
+
+	if v, ok := args["width"].(float64); ok {
+		opts.Width = int(v)
+	}
+	if v, ok := args["height"].(float64); ok {
+		opts.Height = int(v)
+	}
+	if v, ok := args["limit"].(float64); ok {
+		opts.Limit = int(v)
+	}
+
+	switch order, _ := args["order"].(string); order {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return opts, fmt.Errorf("order must be asc or desc")
+	}
+
+	for key, field := range map[string]*time.Time{
+		"created_after":  &opts.CreatedAfter,
+		"created_before": &opts.CreatedBefore,
+		"updated_after":  &opts.UpdatedAfter,
+		"updated_before": &opts.UpdatedBefore,
+	} {
+		value, _ := args[key].(string)
+		if value == "" {
+			continue
+		}
+		t, err := parseTimeArg(value)
+		if err != nil {
+			return opts, fmt.Errorf("%s: %v", key, err)
+		}
+		*field = t
+	}
+
+	return opts, nil
+}
+
+// parseTimeArg parses an RFC 3339 timestamp or a YYYY-MM-DD date (midnight UTC)
+func parseTimeArg(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"html_image_creator/pkg/post"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleMigrateStorage(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	dryRun, _ := args["dry_run"].(bool)
+
+	report, err := h.postSvc.MigrateStorage(dryRun)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to migrate storage: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":          "succeeded",
+		"dry_run":         report.DryRun,
+		"current_version": report.CurrentVersion,
+		"migrated":        report.Migrated,
+		"up_to_date":      report.UpToDate,
+		"failed":          report.Failed,
+		"posts":           report.Posts,
+	}
+	if report.Posts == nil {
+		result["posts"] = []interface{}{}
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleCheckStorage(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	repair, _ := args["repair"].(string)
+	if repair == "none" {
+		repair = post.RepairNone
+	}
+
+	report, err := h.postSvc.CheckStorage(repair)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to check storage: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":        "succeeded",
+		"checked_posts": report.CheckedPosts,
+		"issue_count":   len(report.Issues),
+		"repaired":      report.Repaired,
+		"issues":        report.Issues,
+	}
+	if report.Repair != "" {
+		result["repair"] = report.Repair
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleGarbageCollect(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	policy := post.RetentionPolicy{
+		MaxAgeDays:   h.config.RetentionDays,
+		KeepVersions: h.config.KeepVersions,
+	}
+	if days, ok := args["max_age_days"].(float64); ok {
+		policy.MaxAgeDays = int(days)
+	}
+	if keep, ok := args["keep_versions"].(float64); ok {
+		policy.KeepVersions = int(keep)
+	}
+	dryRun, _ := args["dry_run"].(bool)
+
+	report, err := h.postSvc.GarbageCollect(policy, dryRun)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to collect garbage: %v", err)), nil
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"status":           "succeeded",
+		"dry_run":          report.DryRun,
+		"policy":           report.Policy,
+		"deleted_posts":    report.DeletedPosts,
+		"deleted_versions": report.DeletedVersions,
+		"freed_bytes":      report.FreedBytes,
+		"actions":          report.Actions,
+	}), nil
+}
+
+// defaultLargestMedia is the number of media files storage_usage lists when not specified
+const defaultLargestMedia = 10
+
+func (h *Handler) handleStorageUsage(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, _ := args["post_id"].(string)
+	limit := 0
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+	largestMedia := defaultLargestMedia
+	if n, ok := args["largest_media"].(float64); ok {
+		largestMedia = int(n)
+	}
+
+	usage, err := h.postSvc.StorageUsage(postID, limit, largestMedia)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to get storage usage: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":        "succeeded",
+		"workspace":     h.workspace.Name,
+		"post_count":    usage.PostCount,
+		"posts":         usage.Posts,
+		"totals":        usage.Totals,
+		"largest_media": usage.LargestMedia,
+	}
+	if postID == "" {
+		result["trash_bytes"] = usage.TrashBytes
+
+		quota := map[string]interface{}{}
+		if h.config.MaxTotalBytes > 0 {
+			quota["max_total_bytes"] = h.config.MaxTotalBytes
+			quota["used_bytes"] = usage.Totals.TotalBytes + usage.TrashBytes
+		}
+		if h.config.MaxPostBytes > 0 {
+			quota["max_post_bytes"] = h.config.MaxPostBytes
+		}
+		if h.config.MaxPosts > 0 {
+			quota["max_posts"] = h.config.MaxPosts
+		}
+		if len(quota) > 0 {
+			result["quota"] = quota
+		}
+	}
+
+	return h.successResponse(result), nil
+}
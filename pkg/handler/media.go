@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"html_image_creator/pkg/media"
+	"html_image_creator/pkg/post"
+)
+
+// mediaSource describes a single media item passed to add_media or
+// create_image_post. Exactly one of SourcePath, URL, Data or DataURI is set.
+type mediaSource struct {
+	SourcePath string
+	URL        string
+	Data       string
+	DataURI    string
+	Filename   string
+	Transform  *media.Transform
+}
+
+// label returns a human readable identifier for the media source
+func (m *mediaSource) label() string {
+	switch {
+	case m.SourcePath != "":
+		return m.SourcePath
+	case m.URL != "":
+		return m.URL
+	case m.Filename != "":
+		return m.Filename
+	case m.DataURI != "":
+		return "data URI"
+	default:
+		return "base64 data"
+	}
+}
+
+// parseMediaSource reads media source fields from tool arguments
+func parseMediaSource(args map[string]interface{}) (*mediaSource, error) {
+	src := &mediaSource{}
+	src.SourcePath, _ = args["source_path"].(string)
+	src.URL, _ = args["url"].(string)
+	src.Data, _ = args["data"].(string)
+	src.DataURI, _ = args["data_uri"].(string)
+	src.Filename, _ = args["filename"].(string)
+
+	count := 0
+	for _, v := range []string{src.SourcePath, src.URL, src.Data, src.DataURI} {
+		if v != "" {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("one of source_path, url, data or data_uri is required")
+	}
+	if count > 1 {
+		return nil, fmt.Errorf("only one of source_path, url, data or data_uri may be provided")
+	}
+
+	transform, err := parseTransform(args)
+	if err != nil {
+		return nil, err
+	}
+	src.Transform = transform
+
+	return src, nil
+}
+
+// parseTransform reads the optional image transform arguments
+func parseTransform(args map[string]interface{}) (*media.Transform, error) {
+	t := &media.Transform{}
+	if v, ok := args["max_dimension"].(float64); ok {
+		t.MaxDimension = int(v)
+	}
+	if v, ok := args["aspect_ratio"].(string); ok {
+		t.AspectRatio = v
+	}
+	if v, ok := args["auto_orient"].(bool); ok {
+		t.AutoOrient = v
+	}
+	if v, ok := args["format"].(string); ok {
+		t.Format = v
+	}
+	if v, ok := args["quality"].(float64); ok {
+		t.Quality = int(v)
+	}
+	if raw, ok := args["crop"].(map[string]interface{}); ok {
+		crop := &media.CropBox{}
+		for key, dest := range map[string]*int{"x": &crop.X, "y": &crop.Y, "width": &crop.Width, "height": &crop.Height} {
+			v, ok := raw[key].(float64)
+			if !ok {
+				return nil, fmt.Errorf("crop.%s is required and must be an integer", key)
+			}
+			*dest = int(v)
+		}
+		t.Crop = crop
+	}
+
+	if t.IsZero() {
+		return nil, nil
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseMediaFiles reads the media_files argument, where each entry is either
+// a file path string or an object with media source fields
+func parseMediaFiles(raw []interface{}) ([]*mediaSource, error) {
+	var sources []*mediaSource
+	for i, item := range raw {
+		switch v := item.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+			sources = append(sources, &mediaSource{SourcePath: v})
+		case map[string]interface{}:
+			src, err := parseMediaSource(v)
+			if err != nil {
+				return nil, fmt.Errorf("media_files[%d]: %w", i, err)
+			}
+			sources = append(sources, src)
+		default:
+			return nil, fmt.Errorf("media_files[%d] must be a string or an object", i)
+		}
+	}
+	return sources, nil
+}
+
+// importMedia adds a media source to a post and returns the stored media info
+func (h *Handler) importMedia(ctx context.Context, postID string, src *mediaSource) (*post.MediaInfo, error) {
+	if src.SourcePath != "" {
+		sourcePath, err := h.config.CheckSourcePath(src.SourcePath)
+		if err != nil {
+			return nil, err
+		}
+		return h.postSvc.AddMedia(postID, sourcePath, src.Transform)
+	}
+
+	filename, data, err := h.loadMediaSource(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.postSvc.AddMediaData(postID, filename, data, src.Transform)
+}
+
+// loadMediaSource fetches or decodes a non-file media source and resolves
+// the filename it should be stored under
+func (h *Handler) loadMediaSource(ctx context.Context, src *mediaSource) (string, []byte, error) {
+	var (
+		data     []byte
+		declared string
+		filename = src.Filename
+		err      error
+	)
+	switch {
+	case src.URL != "":
+		var fetched *media.Fetched
+		fetched, err = h.fetcher.Fetch(ctx, src.URL)
+		if err == nil {
+			data, declared = fetched.Data, fetched.ContentType
+			if filename == "" {
+				filename = fetched.Filename
+			}
+		}
+	case src.DataURI != "":
+		data, declared, err = media.ParseDataURI(src.DataURI, h.config.MaxMediaBytes)
+	default:
+		data, err = media.DecodeBase64(src.Data, h.config.MaxMediaBytes)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	contentType := media.SniffContentType(data)
+	if contentType == "application/octet-stream" && declared != "" {
+		contentType = declared
+	}
+
+	filename, err = media.ResolveFilename(filename, contentType, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return filename, data, nil
+}
+
+// localizeRemoteMedia downloads every remote <img src> and CSS url() in a
+// post's HTML into its media folder and rewrites the references. It returns
+// the URL to relative path mapping and the URLs that could not be fetched.
+func (h *Handler) localizeRemoteMedia(ctx context.Context, postID string) (map[string]string, map[string]string, error) {
+	p, err := h.postSvc.GetPost(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localized := make(map[string]string)
+	failed := make(map[string]string)
+	usedNames := make(map[string]bool)
+	for _, remoteURL := range media.FindRemoteURLs(p.HTMLContent) {
+		filename, data, err := h.loadMediaSource(ctx, &mediaSource{URL: remoteURL})
+		if err != nil {
+			failed[remoteURL] = err.Error()
+			continue
+		}
+
+		// Distinct URLs often share a basename (e.g. image.jpg), so prefix repeats with a URL hash
+		if usedNames[filename] {
+			sum := sha256.Sum256([]byte(remoteURL))
+			filename = hex.EncodeToString(sum[:])[:8] + "-" + filename
+		}
+		usedNames[filename] = true
+
+		info, err := h.postSvc.AddMediaData(postID, filename, data, nil)
+		if err != nil {
+			failed[remoteURL] = err.Error()
+			continue
+		}
+		localized[remoteURL] = info.Path
+	}
+
+	if len(localized) > 0 {
+		updated := media.ReplaceRemoteURLs(p.HTMLContent, localized)
+		if _, err := h.postSvc.UpdatePost(postID, updated); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return localized, failed, nil
+}
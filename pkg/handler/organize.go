@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"html_image_creator/pkg/post"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleTagImagePost(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	add, err := stringSliceArg(args, "add")
+	if err != nil {
+		return nil, err
+	}
+	remove, err := stringSliceArg(args, "remove")
+	if err != nil {
+		return nil, err
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, fmt.Errorf("at least one of add or remove is required")
+	}
+
+	p, err := h.postSvc.TagPost(postID, add, remove)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to tag image post: %v", err)), nil
+	}
+
+	tags := p.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	result := map[string]interface{}{
+		"status":  "succeeded",
+		"post_id": p.ID,
+		"tags":    tags,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleCreateCollection(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+	description, _ := args["description"].(string)
+
+	c, err := h.postSvc.CreateCollection(name, description)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to create collection: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":        "succeeded",
+		"collection_id": c.ID,
+		"name":          c.Name,
+		"created_at":    c.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleListCollections(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	collections, err := h.postSvc.ListCollections()
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to list collections: %v", err)), nil
+	}
+
+	page, err := h.postSvc.ListPosts(post.ListOptions{})
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to list collections: %v", err)), nil
+	}
+	counts := make(map[string]int)
+	for _, p := range page.Posts {
+		if p.Collection != "" {
+			counts[p.Collection]++
+		}
+	}
+
+	collectionsList := make([]map[string]interface{}, len(collections))
+	for i, c := range collections {
+		collectionsList[i] = map[string]interface{}{
+			"collection_id": c.ID,
+			"name":          c.Name,
+			"post_count":    counts[c.ID],
+			"created_at":    c.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if c.Description != "" {
+			collectionsList[i]["description"] = c.Description
+		}
+	}
+
+	result := map[string]interface{}{
+		"status":      "succeeded",
+		"count":       len(collectionsList),
+		"collections": collectionsList,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleMoveToCollection(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postIDs, err := stringSliceArg(args, "post_ids")
+	if err != nil {
+		return nil, err
+	}
+	if len(postIDs) == 0 {
+		return nil, fmt.Errorf("post_ids is required and must be a non-empty array of strings")
+	}
+
+	collectionID, ok := args["collection_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("collection_id is required and must be a string")
+	}
+
+	if err := h.postSvc.MoveToCollection(postIDs, collectionID); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to move posts: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":        "succeeded",
+		"post_ids":      postIDs,
+		"collection_id": collectionID,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleDeleteCollection(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	collectionID, ok := args["collection_id"].(string)
+	if !ok || collectionID == "" {
+		return nil, fmt.Errorf("collection_id is required and must be a string")
+	}
+
+	if err := h.postSvc.DeleteCollection(collectionID); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to delete collection: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":        "succeeded",
+		"collection_id": collectionID,
+	}
+
+	return h.successResponse(result), nil
+}
+
+// stringSliceArg returns an optional array-of-strings argument
+func stringSliceArg(args map[string]interface{}, key string) ([]string, error) {
+	raw, present := args[key]
+	if !present || raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"html_image_creator/pkg/post"
+	"html_image_creator/pkg/screenshot"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleDefineTemplate(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	var spec *post.TemplateSpec
+	if clear, _ := args["clear"].(bool); !clear {
+		raw, ok := args["variables"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("variables is required and must be an array unless clear is set")
+		}
+		// Round-trip through JSON to decode the variable objects
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid variables: %w", err)
+		}
+		spec = &post.TemplateSpec{}
+		if err := json.Unmarshal(data, &spec.Variables); err != nil {
+			return nil, fmt.Errorf("invalid variables: %w", err)
+		}
+	}
+
+	p, err := h.postSvc.DefineTemplate(postID, spec)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to define template: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":      "succeeded",
+		"post_id":     p.ID,
+		"name":        p.Name,
+		"is_template": p.Template != nil,
+		"updated_at":  p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if p.Template != nil {
+		result["variables"] = p.Template.Variables
+	}
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleRenderTemplate(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	templateID, ok := args["template_id"].(string)
+	if !ok || templateID == "" {
+		return nil, fmt.Errorf("template_id is required and must be a string")
+	}
+	values, _ := args["values"].(map[string]interface{})
+	name, _ := args["name"].(string)
+	outputPath, _ := args["output_path"].(string)
+	if name == "" && outputPath == "" {
+		return nil, fmt.Errorf("name (to create a post) or output_path (to export) is required")
+	}
+
+	var sources []*mediaSource
+	var err error
+	if raw, ok := args["media_files"].([]interface{}); ok && len(raw) > 0 {
+		if name == "" {
+			return nil, fmt.Errorf("media_files requires name, since media is added to the created post")
+		}
+		if sources, err = parseMediaFiles(raw); err != nil {
+			return h.errorResponse(fmt.Sprintf("Invalid media_files: %v", err)), nil
+		}
+	}
+
+	var opts screenshot.Options
+	if outputPath != "" {
+		if opts, err = h.exportOptions(args, outputPath); err != nil {
+			return h.errorResponse(fmt.Sprintf("Invalid export options: %v", err)), nil
+		}
+		if outputPath, err = h.config.CheckOutputPath(outputPath); err != nil {
+			return h.errorResponse(fmt.Sprintf("Invalid output_path: %v", err)), nil
+		}
+	}
+
+	result := map[string]interface{}{
+		"status":      "succeeded",
+		"template_id": templateID,
+	}
+
+	if name == "" {
+		// Export only: render into a scratch directory, nothing is stored
+		tmpl, dir, cleanup, err := h.postSvc.MaterializeTemplate(templateID, values, nil)
+		if err != nil {
+			return h.errorResponse(fmt.Sprintf("Failed to render template: %v", err)), nil
+		}
+		defer cleanup()
+
+		record, err := h.renderExport(dir, tmpl.Width, tmpl.Height, outputPath, opts)
+		if err != nil {
+			return h.errorResponse(fmt.Sprintf("Failed to export image: %v", err)), nil
+		}
+		result["output_path"] = record.OutputPath
+		result["format"] = record.Format
+		result["scale"] = record.Scale
+		result["size"] = record.Size
+		return h.successResponse(result), nil
+	}
+
+	p, err := h.postSvc.InstantiateTemplate(templateID, name, values)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to render template: %v", err)), nil
+	}
+	result["post_id"] = p.ID
+	result["name"] = p.Name
+	result["width"] = p.Width
+	result["height"] = p.Height
+	result["file_path"] = h.postSvc.PostLocation(p.ID)
+	result["created_at"] = p.CreatedAt.Format("2006-01-02T15:04:05Z07:00")
+
+	if len(sources) > 0 {
+		mediaPaths := make(map[string]string)
+		for _, src := range sources {
+			info, err := h.importMedia(ctx, p.ID, src)
+			if err != nil {
+				return h.errorResponse(fmt.Sprintf("Post %s created but failed to add media file %s: %v", p.ID, src.label(), err)), nil
+			}
+			mediaPaths[src.label()] = info.Path
+		}
+		result["media_paths"] = mediaPaths
+	}
+
+	if outputPath != "" {
+		record, err := h.exportPost(p, outputPath, opts)
+		if err != nil {
+			return h.errorResponse(fmt.Sprintf("Post %s created but failed to export image: %v", p.ID, err)), nil
+		}
+		result["output_path"] = record.OutputPath
+		result["format"] = record.Format
+		result["scale"] = record.Scale
+		result["size"] = record.Size
+		if err := h.postSvc.RecordExport(p.ID, record); err != nil {
+			result["warning"] = fmt.Sprintf("Image exported but not recorded in export history: %v", err)
+		}
+	}
+
+	return h.successResponse(result), nil
+}
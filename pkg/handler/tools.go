@@ -6,8 +6,8 @@ import (
 	"github.com/gomcpgo/mcp/pkg/protocol"
 )
 
-// GetTools returns the list of available MCP tools
-func (h *Handler) GetTools() []protocol.Tool {
+// postTools returns the tools that operate within a workspace
+func postTools() []protocol.Tool {
 	return []protocol.Tool{
 		{
 			Name:        "create_image_post",
@@ -21,28 +21,47 @@ func (h *Handler) GetTools() []protocol.Tool {
 					},
 					"html_content": {
 						"type": "string",
-						"description": "Full HTML/CSS content for the image. Use inline styles or <style> tags. The content will be rendered at the exact specified dimensions. Reference media files as media/filename.ext."
+						"description": "Full HTML/CSS content for the image. Use inline styles or <style> tags. The content will be rendered at the exact specified dimensions. Reference media files as media/filename.ext. Registered components can be included with <x-component name=\"...\" param=\"...\">; they are expanded before the HTML is stored."
 					},
 					"width": {
 						"type": "integer",
-						"description": "Canvas width in pixels (e.g., 1080)"
+						"description": "Canvas width in pixels (e.g., 1080). Required unless the workspace sets a default width."
 					},
 					"height": {
 						"type": "integer",
-						"description": "Canvas height in pixels (e.g., 1080)"
+						"description": "Canvas height in pixels (e.g., 1080). Required unless the workspace sets a default height."
 					},
 					"media_files": {
 						"type": "array",
-						"items": { "type": "string" },
-						"description": "Optional list of absolute file paths to copy into the post's media folder. Each file becomes available as media/filename.ext in the HTML."
+						"items": {
+							"oneOf": [
+								{ "type": "string" },
+								{
+									"type": "object",
+									"properties": {
+										"source_path": { "type": "string" },
+										"url": { "type": "string" },
+										"data": { "type": "string" },
+										"data_uri": { "type": "string" },
+										"filename": { "type": "string" },
+										"max_dimension": { "type": "integer" },
+										"aspect_ratio": { "type": "string" },
+										"auto_orient": { "type": "boolean" },
+										"format": { "type": "string" },
+										"quality": { "type": "integer" }
+									}
+								}
+							]
+						},
+						"description": "Optional list of media to copy into the post's media folder. Each entry is either an absolute file path or an object with one of source_path, url (http/https), data (base64) or data_uri, plus an optional filename. Each file becomes available as media/filename.ext in the HTML."
 					}
 				},
-				"required": ["name", "html_content", "width", "height"]
+				"required": ["name", "html_content"]
 			}`),
 		},
 		{
 			Name:        "update_image_post",
-			Description: "Update the HTML content of an existing image post. Canvas dimensions cannot be changed after creation.",
+			Description: "Update the HTML content of an existing image post. Canvas dimensions cannot be changed after creation. <x-component> tags are expanded as in create_image_post.",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -58,9 +77,164 @@ func (h *Handler) GetTools() []protocol.Tool {
 				"required": ["post_id", "html_content"]
 			}`),
 		},
+		{
+			Name:        "edit_image_post",
+			Description: "Edit parts of a post's HTML without resending all of it. Each operation targets elements by CSS selector, which must match exactly one element unless all is true. Operations run in order and are applied together: if any fails (for example a selector matches nothing), the post is left unchanged. Ops: set_text (value), set_attribute (name, value), remove_attribute (name), set_style (value replaces the style attribute), merge_style (style object; an empty value removes that property), set_inner_html (value), insert (value at position before, after, prepend or append), remove, replace_text (find, replace; in text and <style> contents, selector optional). HTML without <html>, <head> or <body> tags is edited as a fragment whose top level is addressed as body. The HTML is parsed and re-rendered as a whole, so markup outside the edited elements is normalized: omitted end tags are closed, attribute values are double-quoted, entities may be re-encoded and a document gains any missing <html>, <head> or <body> tags. When that changes anything, the response has a warning naming the first difference.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"operations": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"op": {
+									"type": "string",
+									"enum": ["set_text", "set_attribute", "remove_attribute", "set_style", "merge_style", "set_inner_html", "insert", "remove", "replace_text"],
+									"description": "The edit to make"
+								},
+								"selector": {
+									"type": "string",
+									"description": "CSS selector of the element to edit, e.g. h1.title or #price"
+								},
+								"all": {
+									"type": "boolean",
+									"description": "Edit every matching element instead of requiring exactly one (default false)"
+								},
+								"name": {
+									"type": "string",
+									"description": "Attribute name for set_attribute and remove_attribute"
+								},
+								"value": {
+									"type": "string",
+									"description": "Text, attribute value, inline style or HTML, depending on op"
+								},
+								"style": {
+									"type": "object",
+									"additionalProperties": {"type": "string"},
+									"description": "CSS properties to set for merge_style, e.g. {\"color\": \"#fff\"}"
+								},
+								"position": {
+									"type": "string",
+									"enum": ["before", "after", "prepend", "append"],
+									"description": "Where insert places the HTML relative to the element"
+								},
+								"find": {
+									"type": "string",
+									"description": "Text to find for replace_text"
+								},
+								"replace": {
+									"type": "string",
+									"description": "Replacement text for replace_text"
+								}
+							},
+							"required": ["op"]
+						},
+						"description": "Edits to apply in order"
+					}
+				},
+				"required": ["post_id", "operations"]
+			}`),
+		},
+		{
+			Name:        "update_post_metadata",
+			Description: "Edit a post's name, description, caption text, alt text and custom key/value fields. Only the fields provided are changed; the post ID and HTML stay the same.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"name": {
+						"type": "string",
+						"description": "New display name (the post ID does not change)"
+					},
+					"description": {
+						"type": "string",
+						"description": "Longer description of the post. An empty string clears it."
+					},
+					"caption": {
+						"type": "string",
+						"description": "Caption text to publish with the image. An empty string clears it."
+					},
+					"alt_text": {
+						"type": "string",
+						"description": "Accessibility alt text for the image. An empty string clears it."
+					},
+					"custom_fields": {
+						"type": "object",
+						"additionalProperties": {"type": ["string", "null"]},
+						"description": "Custom fields to set, e.g. {\"client\": \"Acme\"}. A null or empty value removes the field."
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "resize_image_post",
+			Description: "Change an existing post's canvas dimensions. Mode 'keep' leaves the layout as-is, 'scale' wraps the layout in a uniformly scaled CSS transform centered on the new canvas, and 'rewrite' scales absolute px values in the CSS. The resize is recorded in the post's history.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"width": {
+						"type": "integer",
+						"description": "New canvas width in pixels"
+					},
+					"height": {
+						"type": "integer",
+						"description": "New canvas height in pixels"
+					},
+					"mode": {
+						"type": "string",
+						"enum": ["keep", "scale", "rewrite"],
+						"description": "How to adapt the layout (default keep)"
+					}
+				},
+				"required": ["post_id", "width", "height"]
+			}`),
+		},
+		{
+			Name:        "clone_image_post",
+			Description: "Duplicate a post's HTML and media into a new post with a new name. The copy can optionally use different canvas dimensions, with an opt-in proportional rescale of px values in its CSS.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The post ID to clone"
+					},
+					"name": {
+						"type": "string",
+						"description": "Name for the new post"
+					},
+					"width": {
+						"type": "integer",
+						"description": "Canvas width of the new post in pixels (defaults to the source width)"
+					},
+					"height": {
+						"type": "integer",
+						"description": "Canvas height of the new post in pixels (defaults to the source height)"
+					},
+					"rescale": {
+						"type": "boolean",
+						"description": "Scale px values in <style> blocks and style attributes by the largest uniform factor that fits the source canvas into the new one (default false)"
+					}
+				},
+				"required": ["post_id", "name"]
+			}`),
+		},
 		{
 			Name:        "get_image_post",
-			Description: "Retrieve an image post's content and metadata by ID.",
+			Description: "Retrieve an image post's content and metadata by ID, including the media files added to it with their content type, size and image dimensions.",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -74,15 +248,75 @@ func (h *Handler) GetTools() []protocol.Tool {
 		},
 		{
 			Name:        "list_image_posts",
-			Description: "List all image posts with their metadata.",
+			Description: "List image posts with their metadata. Supports full-text search, filters on dimensions, dates, tags and collection, sorting, and cursor pagination. Posts are sorted by most recently updated first by default.",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
-				"properties": {}
+				"properties": {
+					"query": {
+						"type": "string",
+						"description": "Search words that must all appear (case-insensitive) in the post name or the visible text of its HTML"
+					},
+					"tag": {
+						"type": "string",
+						"description": "Only list posts with this tag"
+					},
+					"tags": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Only list posts with all of these tags"
+					},
+					"collection": {
+						"type": "string",
+						"description": "Only list posts in the collection with this ID"
+					},
+					"width": {
+						"type": "integer",
+						"description": "Only list posts with this canvas width"
+					},
+					"height": {
+						"type": "integer",
+						"description": "Only list posts with this canvas height"
+					},
+					"created_after": {
+						"type": "string",
+						"description": "Only posts created at or after this time (RFC 3339 or YYYY-MM-DD)"
+					},
+					"created_before": {
+						"type": "string",
+						"description": "Only posts created before this time (RFC 3339 or YYYY-MM-DD)"
+					},
+					"updated_after": {
+						"type": "string",
+						"description": "Only posts updated at or after this time (RFC 3339 or YYYY-MM-DD)"
+					},
+					"updated_before": {
+						"type": "string",
+						"description": "Only posts updated before this time (RFC 3339 or YYYY-MM-DD)"
+					},
+					"sort_by": {
+						"type": "string",
+						"enum": ["created", "updated", "name"],
+						"description": "Sort field (default updated)"
+					},
+					"order": {
+						"type": "string",
+						"enum": ["asc", "desc"],
+						"description": "Sort order (default desc: newest or Z-A first)"
+					},
+					"limit": {
+						"type": "integer",
+						"description": "Maximum number of posts to return (default all)"
+					},
+					"cursor": {
+						"type": "string",
+						"description": "next_cursor from a previous response, to fetch the following page with the same filters and sort"
+					}
+				}
 			}`),
 		},
 		{
 			Name:        "export_image",
-			Description: "Export an image post as a PNG file. Renders the HTML at exact canvas dimensions using headless Chrome and saves as a pixel-accurate screenshot.",
+			Description: "Export an image post as a PNG, JPEG or WebP file. Renders the HTML at exact canvas dimensions using headless Chrome and saves as a pixel-accurate screenshot. Each export is recorded in the post's export history, and get_image_post reports when the latest export is out of date.",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -92,7 +326,20 @@ func (h *Handler) GetTools() []protocol.Tool {
 					},
 					"output_path": {
 						"type": "string",
-						"description": "Absolute path for the output PNG file"
+						"description": "Absolute path for the output image file"
+					},
+					"format": {
+						"type": "string",
+						"enum": ["png", "jpeg", "webp"],
+						"description": "Image format. Defaults from the output_path extension when it is .png, .jpg, .jpeg or .webp, then the workspace export format, then png."
+					},
+					"scale": {
+						"type": "number",
+						"description": "Device scale factor; the image is the canvas size times scale (default from the workspace, else 2; max 4)"
+					},
+					"quality": {
+						"type": "integer",
+						"description": "Compression quality 1-100 for jpeg and webp (default 90)"
 					}
 				},
 				"required": ["post_id", "output_path"]
@@ -100,7 +347,7 @@ func (h *Handler) GetTools() []protocol.Tool {
 		},
 		{
 			Name:        "add_media",
-			Description: "Add an image file to a post's media folder. Copies the file and returns the relative path to use in HTML (e.g., in <img src=\"media/photo.jpg\">).",
+			Description: "Add an image file to a post's media folder and return the relative path to use in HTML (e.g., in <img src=\"media/photo.jpg\">). Provide exactly one of source_path (a file on the server), url (an http/https URL to download), data (base64 encoded bytes) or data_uri (e.g., data:image/png;base64,...). The content type is sniffed from the bytes to pick an extension when filename is omitted. Files are validated against the allowed media types and size limit, and images are decoded to verify them. Raster images can optionally be resized, cropped, auto-oriented and converted before they are stored, which keeps large camera photos from bloating the post. The response includes content_type, size, and for images the pixel width and height so layouts can match the aspect ratio.",
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -111,9 +358,692 @@ func (h *Handler) GetTools() []protocol.Tool {
 					"source_path": {
 						"type": "string",
 						"description": "The absolute path to the source media file"
+					},
+					"url": {
+						"type": "string",
+						"description": "An http or https URL to download into the media folder"
+					},
+					"data": {
+						"type": "string",
+						"description": "Base64 encoded file contents"
+					},
+					"data_uri": {
+						"type": "string",
+						"description": "A data URI containing the file contents"
+					},
+					"filename": {
+						"type": "string",
+						"description": "Optional filename for url, data or data_uri input (e.g., photo.png). Defaults to the URL's basename or a name derived from the content hash."
+					},
+					"max_dimension": {
+						"type": "integer",
+						"description": "Optional. Downscale the image so its longest side is at most this many pixels"
+					},
+					"crop": {
+						"type": "object",
+						"properties": {
+							"x": { "type": "integer" },
+							"y": { "type": "integer" },
+							"width": { "type": "integer" },
+							"height": { "type": "integer" }
+						},
+						"required": ["x", "y", "width", "height"],
+						"description": "Optional crop rectangle in pixels, applied after orientation and before resizing"
+					},
+					"aspect_ratio": {
+						"type": "string",
+						"description": "Optional center crop to an aspect ratio such as \"16:9\", \"1:1\" or \"1.91\". Cannot be combined with crop."
+					},
+					"auto_orient": {
+						"type": "boolean",
+						"description": "Rotate the image upright according to its EXIF orientation. Always applied when any other transform is used."
+					},
+					"format": {
+						"type": "string",
+						"enum": ["png", "jpeg", "gif"],
+						"description": "Optional output format. Defaults to the source format (WebP and BMP become PNG). The file extension is updated to match."
+					},
+					"quality": {
+						"type": "integer",
+						"description": "JPEG quality from 1 to 100 (default 90)"
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "localize_media",
+			Description: "Download every remote image referenced in a post's HTML (<img src=\"https://...\"> and CSS url(https://...)) into the post's media folder and rewrite the references to the local media/ paths, so rendering doesn't depend on remote servers.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "delete_image_post",
+			Description: "Delete an image post. By default the post is moved to the trash and can be brought back with restore_image_post; set permanent to delete it immediately.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"permanent": {
+						"type": "boolean",
+						"description": "Delete the post permanently instead of moving it to the trash (default false)"
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "list_trash",
+			Description: "List posts in the trash with the time they were deleted.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {}
+			}`),
+		},
+		{
+			Name:        "restore_image_post",
+			Description: "Restore a post from the trash. Fails if a post with the same ID already exists.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The ID of the trashed post"
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "empty_trash",
+			Description: "Permanently delete posts from the trash. Without older_than_days every trashed post is purged.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"older_than_days": {
+						"type": "number",
+						"description": "Only purge posts that were deleted more than this many days ago"
+					}
+				}
+			}`),
+		},
+		{
+			Name:        "tag_image_post",
+			Description: "Add or remove tags on a post. Tags are case-insensitive and stored lowercased.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"add": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Tags to add"
+					},
+					"remove": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Tags to remove"
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "create_collection",
+			Description: "Create a named collection (e.g. a campaign or client) for grouping posts. The collection ID is derived from the name.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "Collection name"
+					},
+					"description": {
+						"type": "string",
+						"description": "Optional description"
+					}
+				},
+				"required": ["name"]
+			}`),
+		},
+		{
+			Name:        "list_collections",
+			Description: "List all collections with the number of posts in each.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {}
+			}`),
+		},
+		{
+			Name:        "move_to_collection",
+			Description: "Move posts into a collection. A post belongs to at most one collection; an empty collection_id removes the posts from their collection.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_ids": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "IDs of the posts to move"
+					},
+					"collection_id": {
+						"type": "string",
+						"description": "Target collection ID, or an empty string to remove the posts from their collection"
+					}
+				},
+				"required": ["post_ids", "collection_id"]
+			}`),
+		},
+		{
+			Name:        "delete_collection",
+			Description: "Delete a collection. Its posts are kept and removed from the collection.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"collection_id": {
+						"type": "string",
+						"description": "The collection ID"
+					}
+				},
+				"required": ["collection_id"]
+			}`),
+		},
+		{
+			Name:        "create_brand_kit",
+			Description: "Create a brand kit: a named palette, typography, spacing tokens and logo files shared by posts. Names use lowercase letters, digits and hyphens. Apply it to posts with apply_brand_kit instead of repeating colors, fonts and logo paths in every post.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "Brand kit name; its ID is derived from it"
+					},
+					"description": {
+						"type": "string",
+						"description": "Optional description"
+					},
+					"palette": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Color name to CSS color, e.g. {\"primary\": \"#0b5fff\"}, exposed as --color-<name>"
+					},
+					"typography": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Font role to CSS font-family, e.g. {\"heading\": \"'Inter', sans-serif\"}, exposed as --font-<role>"
+					},
+					"spacing": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Spacing token to CSS length, e.g. {\"md\": \"24px\"}, exposed as --spacing-<token>"
+					},
+					"logos": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Logo name to absolute path of an image file. Posts using the kit get it as media/brand/<name>, a path that stays the same when the logo changes format, and --logo-<name>"
+					}
+				},
+				"required": ["name"]
+			}`),
+		},
+		{
+			Name:        "update_brand_kit",
+			Description: "Edit a brand kit. Given tokens and logos are added or replaced, others are kept. Every post using the kit is updated to match.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"kit_id": {
+						"type": "string",
+						"description": "The brand kit ID"
+					},
+					"name": {
+						"type": "string",
+						"description": "New name; the ID does not change"
+					},
+					"description": {
+						"type": "string",
+						"description": "New description"
+					},
+					"palette": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Color name to CSS color, e.g. {\"primary\": \"#0b5fff\"}, exposed as --color-<name>. An empty string or null removes it."
+					},
+					"typography": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Font role to CSS font-family, e.g. {\"heading\": \"'Inter', sans-serif\"}, exposed as --font-<role>. An empty string or null removes it."
+					},
+					"spacing": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Spacing token to CSS length, e.g. {\"md\": \"24px\"}, exposed as --spacing-<token>. An empty string or null removes it."
+					},
+					"logos": {
+						"type": "object",
+						"additionalProperties": { "type": ["string", "null"] },
+						"description": "Logo name to absolute path of an image file. Posts using the kit get it as media/brand/<name>, a path that stays the same when the logo changes format, and --logo-<name>. An empty string or null removes it."
+					}
+				},
+				"required": ["kit_id"]
+			}`),
+		},
+		{
+			Name:        "list_brand_kits",
+			Description: "List brand kits with their tokens and logos",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {}
+			}`),
+		},
+		{
+			Name:        "apply_brand_kit",
+			Description: "Assign a brand kit to a post. A <style id=\"brand-kit\"> block declaring the kit's tokens as :root CSS custom properties (--color-*, --font-*, --spacing-*, --logo-*) is placed at the top of the post's HTML and kept there on later updates, and the kit's logos are copied to media/brand/<name>. Only those copies are replaced or removed later; uploaded media is never touched. Use the properties with var(), e.g. color: var(--color-primary). Pass an empty kit_id to remove the kit, its style block and logos.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The post ID"
+					},
+					"kit_id": {
+						"type": "string",
+						"description": "The brand kit ID, or an empty string to remove the post's kit"
+					}
+				},
+				"required": ["post_id", "kit_id"]
+			}`),
+		},
+		{
+			Name:        "register_component",
+			Description: "Register a reusable HTML/CSS snippet, such as a badge, price tag or footer bar, or replace the component of the same name. Posts include it with <x-component name=\"price-tag\" amount=\"$19\"></x-component> (or self-closing), which is expanded when the post is created or updated. The HTML is a Go html/template: reference parameters as {{.amount}} and the tag's inner HTML as {{.content}}; values are escaped automatically. Components may include other components.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "Component name: lowercase letters, digits and hyphens"
+					},
+					"html": {
+						"type": "string",
+						"description": "HTML template of the component"
+					},
+					"css": {
+						"type": "string",
+						"description": "CSS added once to every post using the component"
+					},
+					"description": {
+						"type": "string",
+						"description": "What the component is for"
+					},
+					"params": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"name": { "type": "string", "description": "Parameter and attribute name: lowercase letters, digits and underscores" },
+								"description": { "type": "string" },
+								"required": { "type": "boolean", "description": "Whether the tag must set the attribute" },
+								"default": { "type": "string", "description": "Value used when an optional attribute is omitted" }
+							},
+							"required": ["name"]
+						},
+						"description": "Parameters passed as attributes of the <x-component> tag"
+					}
+				},
+				"required": ["name", "html"]
+			}`),
+		},
+		{
+			Name:        "list_components",
+			Description: "List registered components with their parameters, HTML and CSS",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {}
+			}`),
+		},
+		{
+			Name:        "preview_component",
+			Description: "Render a component with the given parameters and return the expanded HTML, optionally exporting it to an image",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "Component name"
+					},
+					"params": {
+						"type": "object",
+						"additionalProperties": { "type": "string" },
+						"description": "Parameter values keyed by name"
+					},
+					"content": {
+						"type": "string",
+						"description": "Inner HTML, available to the component as {{.content}}"
+					},
+					"output_path": {
+						"type": "string",
+						"description": "Absolute path to export a preview image to"
+					},
+					"width": {
+						"type": "integer",
+						"description": "Preview canvas width (default from the workspace)"
+					},
+					"height": {
+						"type": "integer",
+						"description": "Preview canvas height (default from the workspace)"
+					},
+					"format": {
+						"type": "string",
+						"enum": ["png", "jpeg", "webp"],
+						"description": "Image format. Defaults from the output_path extension when it is .png, .jpg, .jpeg or .webp, then the workspace export format, then png."
+					},
+					"scale": {
+						"type": "number",
+						"description": "Device scale factor (default from the workspace, else 2; max 4)"
+					}
+				},
+				"required": ["name"]
+			}`),
+		},
+		{
+			Name:        "migrate_storage",
+			Description: "Upgrade every post's metadata.json to the current schema version. Old posts are readable as they are and are upgraded on their next change; this applies all pending migrations at once. Use dry_run to see which posts and steps are pending without changing anything.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"dry_run": {
+						"type": "boolean",
+						"description": "Only report pending migrations (default false)"
+					}
+				}
+			}`),
+		},
+		{
+			Name:        "check_storage",
+			Description: "Check the storage root for problems that hide or break posts: directories that are not valid posts, missing or corrupt index.html, metadata.json or media.json, media.json entries and HTML references to media files that do not exist, and leftover temp_screenshot.html files. Reports issues with the repairs available for each, and optionally applies a repair.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"repair": {
+						"type": "string",
+						"enum": ["none", "regenerate", "quarantine", "delete"],
+						"description": "none (default) only reports. regenerate rebuilds missing or corrupt metadata.json from defaults (1080x1080 canvas, name from the post ID) and media.json from the media folder. quarantine moves broken directories to .quarantine/. delete removes them. Leftover temp files are removed by every repair mode; missing media referenced by HTML is only reported."
+					}
+				}
+			}`),
+		},
+		{
+			Name:        "garbage_collect",
+			Description: "Apply retention policies to reclaim storage: permanently delete posts not updated in max_age_days and all but the newest keep_versions HTML versions of each remaining post. Deleted posts leave their collections; each action names the collection it left. Defaults come from HTML_IMAGE_CREATOR_RETENTION_DAYS and HTML_IMAGE_CREATOR_KEEP_VERSIONS. Use dry_run first to see what would be deleted.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"max_age_days": {
+						"type": "integer",
+						"description": "Delete posts whose last update is older than this many days (0 disables)"
+					},
+					"keep_versions": {
+						"type": "integer",
+						"description": "Number of newest versions to keep per post (0 disables)"
+					},
+					"dry_run": {
+						"type": "boolean",
+						"description": "Only report what would be deleted (default false)"
+					}
+				}
+			}`),
+		},
+		{
+			Name:        "define_template",
+			Description: "Turn a post into a template by declaring the variables its HTML uses. Placeholders use Go html/template syntax: {{.title}}, {{if .show_badge}}...{{end}}, {{range .items}}<li>{{.}}</li>{{end}}. Values are escaped for where they appear, such as text, attributes, URLs or <style> blocks; declare variables used as CSS values with type css. Write the template HTML with create_image_post or update_image_post, then declare its variables here. The HTML must parse and may only reference declared variables.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The post whose HTML is the template"
+					},
+					"variables": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"name": { "type": "string", "description": "Placeholder name: letters, digits and '_'" },
+								"type": {
+									"type": "string",
+									"enum": ["string", "html", "css", "number", "boolean", "list"],
+									"description": "string (default) is escaped for its context; in <style> blocks and style attributes it only accepts keywords, #hex colors and lengths, anything else renders as ZgotmplZ. css is a CSS value such as rgb(0, 0, 0), var(--color-primary) or calc(100% - 4px) for those places; it may not contain quotes, ';', braces, ':', '<', comments, url() or expression(). html is inserted verbatim; list is a list of strings for {{range}}"
+								},
+								"description": { "type": "string" },
+								"required": { "type": "boolean", "description": "Whether render_template must supply a value" },
+								"default": { "description": "Value used when an optional variable is omitted" }
+							},
+							"required": ["name"]
+						},
+						"description": "The template's variables"
+					},
+					"clear": {
+						"type": "boolean",
+						"description": "Turn the template back into a plain post (default false)"
+					}
+				},
+				"required": ["post_id"]
+			}`),
+		},
+		{
+			Name:        "render_template",
+			Description: "Fill a template post's variables from a JSON map of values and create a new post from it (name), export it directly to an image (output_path), or both. Unknown variables, missing required variables and values of the wrong type are rejected. The new post keeps the template's canvas size and media.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"template_id": {
+						"type": "string",
+						"description": "The template post ID"
+					},
+					"values": {
+						"type": "object",
+						"description": "Variable values keyed by name"
+					},
+					"name": {
+						"type": "string",
+						"description": "Name of the post to create. Omit to only export."
+					},
+					"output_path": {
+						"type": "string",
+						"description": "Absolute path to export the rendered image to. Without name, nothing is stored."
+					},
+					"format": {
+						"type": "string",
+						"enum": ["png", "jpeg", "webp"],
+						"description": "Image format. Defaults from the output_path extension when it is .png, .jpg, .jpeg or .webp, then the workspace export format, then png."
+					},
+					"scale": {
+						"type": "number",
+						"description": "Device scale factor (default from the workspace, else 2; max 4)"
+					},
+					"quality": {
+						"type": "integer",
+						"description": "JPEG/WebP quality 1-100 (default 90)"
+					},
+					"media_files": {
+						"type": "array",
+						"items": { "type": "string" },
+						"description": "Absolute paths of media files to add to the created post (requires name). Reference them in values as media/filename.ext."
+					}
+				},
+				"required": ["template_id"]
+			}`),
+		},
+		{
+			Name:        "batch_generate",
+			Description: "Render one image per row of a CSV or JSON data file through a template post, sharing a single browser session. CSV files need a header row naming the template variables; number, boolean and list cells (items separated by |) are converted to the declared types and empty cells use the defaults. Columns that are not variables are ignored. Media columns hold image paths, relative to the data file, that are placed in the template's media folder. Returns a success/failure report per row; a failed row does not stop the batch. Nothing is stored.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"template_id": {
+						"type": "string",
+						"description": "The template post ID"
+					},
+					"data_path": {
+						"type": "string",
+						"description": "Absolute path of the data file: .csv with a header row, or .json holding an array of objects"
+					},
+					"output_dir": {
+						"type": "string",
+						"description": "Absolute path of the directory to write the images to"
+					},
+					"output_pattern": {
+						"type": "string",
+						"description": "Go template for output file names over the row's columns and .row, the 1-based row number, e.g. {{.name}}-card. The format's extension is added if missing. Default row-{{printf \"%04d\" .row}}."
+					},
+					"media_columns": {
+						"type": "array",
+						"items": { "type": "string" },
+						"description": "Columns holding media file paths; each must be a template variable"
+					},
+					"format": {
+						"type": "string",
+						"enum": ["png", "jpeg", "webp"],
+						"description": "Image format. Defaults from the output_pattern extension when it is .png, .jpg, .jpeg or .webp, then the workspace export format, then png."
+					},
+					"scale": {
+						"type": "number",
+						"description": "Device scale factor (default from the workspace, else 2; max 4)"
+					},
+					"quality": {
+						"type": "integer",
+						"description": "JPEG/WebP quality 1-100 (default 90)"
+					},
+					"dry_run": {
+						"type": "boolean",
+						"description": "Validate every row and report the output paths without exporting (default false)"
+					}
+				},
+				"required": ["template_id", "data_path", "output_dir"]
+			}`),
+		},
+		{
+			Name:        "storage_usage",
+			Description: "Report disk usage per post, largest first, split into HTML, media, saved versions, metadata and recorded exports, with workspace totals, trash size, configured quotas and the largest media files. Only file sizes and metadata are read.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "Report a single post instead of the whole workspace"
+					},
+					"limit": {
+						"type": "integer",
+						"description": "Maximum number of posts to list (default all). Totals always cover every post."
+					},
+					"largest_media": {
+						"type": "integer",
+						"description": "Number of largest media files to list (default 10)"
+					}
+				}
+			}`),
+		},
+		{
+			Name:        "get_post_log",
+			Description: "Show the git history of a post: every create, update, media add, delete and checkout recorded as a commit. Requires HTML_IMAGE_CREATOR_GIT_HISTORY=true.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID. Omit to show the history of the whole storage."
+					},
+					"limit": {
+						"type": "integer",
+						"description": "Maximum number of revisions to return, newest first (default 20, 0 for all)"
+					}
+				}
+			}`),
+		},
+		{
+			Name:        "checkout_post_revision",
+			Description: "Restore a post's HTML, metadata and media to a past revision from get_post_log. The restore is recorded as a new commit, so it can be undone. Posts deleted since the revision are recreated.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"revision": {
+						"type": "string",
+						"description": "Commit hash (full or abbreviated) or revision such as HEAD~1"
+					}
+				},
+				"required": ["post_id", "revision"]
+			}`),
+		},
+		{
+			Name:        "export_post_bundle",
+			Description: "Export a post as a single self-contained bundle (zip or tar.gz) containing its HTML, metadata, media and optionally its version history, with a manifest of SHA-256 checksums. Use import_post_bundle to load it on another machine.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"post_id": {
+						"type": "string",
+						"description": "The unique post ID"
+					},
+					"output_path": {
+						"type": "string",
+						"description": "Absolute path for the bundle file (e.g., /tmp/my-post.zip)"
+					},
+					"format": {
+						"type": "string",
+						"enum": ["zip", "tar.gz"],
+						"description": "Archive format. Defaults to tar.gz for .tar.gz/.tgz paths and zip otherwise."
+					},
+					"include_versions": {
+						"type": "boolean",
+						"description": "Include previous HTML versions (default false)"
+					}
+				},
+				"required": ["post_id", "output_path"]
+			}`),
+		},
+		{
+			Name:        "import_post_bundle",
+			Description: "Import a post bundle created by export_post_bundle. Checksums are verified and media is re-validated before anything is written.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"bundle_path": {
+						"type": "string",
+						"description": "Absolute path to the zip or tar.gz bundle"
+					},
+					"preserve_id": {
+						"type": "boolean",
+						"description": "Keep the post ID from the bundle (default true). When false a new ID is generated from the name."
+					},
+					"on_conflict": {
+						"type": "string",
+						"enum": ["error", "rename", "overwrite"],
+						"description": "What to do when the preserved ID already exists: fail (default), import under a new ID, or replace the existing post"
+					},
+					"name": {
+						"type": "string",
+						"description": "Optional new name for the imported post"
 					}
 				},
-				"required": ["post_id", "source_path"]
+				"required": ["bundle_path"]
 			}`),
 		},
 	}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+func (h *Handler) handleDeleteImagePost(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+	permanent, _ := args["permanent"].(bool)
+
+	var err error
+	if permanent {
+		err = h.postSvc.DeletePost(postID)
+	} else {
+		err = h.postSvc.TrashPost(postID)
+	}
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to delete image post: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":    "succeeded",
+		"post_id":   postID,
+		"permanent": permanent,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleListTrash(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	trashed, err := h.postSvc.ListTrash()
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to list trash: %v", err)), nil
+	}
+
+	postsList := make([]map[string]interface{}, len(trashed))
+	for i, p := range trashed {
+		postsList[i] = map[string]interface{}{
+			"post_id":    p.ID,
+			"name":       p.Name,
+			"width":      p.Width,
+			"height":     p.Height,
+			"created_at": p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			"updated_at": p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			"deleted_at": p.DeletedAt.Format("2006-01-02T15:04:05Z07:00"),
+		}
+	}
+
+	result := map[string]interface{}{
+		"status": "succeeded",
+		"count":  len(postsList),
+		"posts":  postsList,
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleRestoreImagePost(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	postID, ok := args["post_id"].(string)
+	if !ok || postID == "" {
+		return nil, fmt.Errorf("post_id is required and must be a string")
+	}
+
+	if err := h.postSvc.RestorePost(postID); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to restore image post: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"status":    "succeeded",
+		"post_id":   postID,
+		"file_path": h.postSvc.PostLocation(postID),
+	}
+
+	return h.successResponse(result), nil
+}
+
+func (h *Handler) handleEmptyTrash(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	var olderThan time.Duration
+	if days, ok := args["older_than_days"].(float64); ok {
+		if days < 0 {
+			return nil, fmt.Errorf("older_than_days cannot be negative")
+		}
+		olderThan = time.Duration(days * float64(24*time.Hour))
+	}
+
+	purged, err := h.postSvc.EmptyTrash(olderThan)
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to empty trash: %v", err)), nil
+	}
+	if purged == nil {
+		purged = []string{}
+	}
+
+	result := map[string]interface{}{
+		"status":   "succeeded",
+		"count":    len(purged),
+		"post_ids": purged,
+	}
+
+	return h.successResponse(result), nil
+}
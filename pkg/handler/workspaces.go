@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"html_image_creator/pkg/config"
+	"html_image_creator/pkg/screenshot"
+
+	"github.com/gomcpgo/mcp/pkg/protocol"
+)
+
+// workspaceProperty is added to the input schema of every workspace-scoped tool
+var workspaceProperty = json.RawMessage(`{
+	"type": "string",
+	"description": "Workspace to operate in (see list_workspaces). Defaults to the default workspace."
+}`)
+
+// GetTools returns the list of available MCP tools
+func (h *Handler) GetTools() []protocol.Tool {
+	tools := postTools()
+	for i := range tools {
+		tools[i].InputSchema = withWorkspaceProperty(tools[i].InputSchema)
+	}
+	return append(tools, workspaceTools()...)
+}
+
+// withWorkspaceProperty adds the optional workspace argument to a tool's input schema
+func withWorkspaceProperty(schema json.RawMessage) json.RawMessage {
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return schema
+	}
+	props, ok := s["properties"].(map[string]interface{})
+	if !ok {
+		props = make(map[string]interface{})
+		s["properties"] = props
+	}
+	props["workspace"] = workspaceProperty
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return schema
+	}
+	return data
+}
+
+// workspaceTools returns the tools that manage workspaces themselves
+func workspaceTools() []protocol.Tool {
+	return []protocol.Tool{
+		{
+			Name:        "list_workspaces",
+			Description: "List the configured workspaces with their storage location and defaults. Pass a workspace name as the workspace argument of any other tool to operate in it.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {}
+			}`),
+		},
+		{
+			Name:        "create_workspace",
+			Description: "Create a named workspace with its own storage and optional defaults for canvas size and export settings.",
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "Workspace name: up to 40 lowercase letters, digits, '-' or '_'"
+					},
+					"description": {
+						"type": "string",
+						"description": "Optional description"
+					},
+					"root_dir": {
+						"type": "string",
+						"description": "Absolute directory to store the workspace's posts in (local storage only). Defaults to .workspaces/<name> inside the default storage root."
+					},
+					"default_width": {
+						"type": "integer",
+						"description": "Canvas width used when create_image_post omits width"
+					},
+					"default_height": {
+						"type": "integer",
+						"description": "Canvas height used when create_image_post omits height"
+					},
+					"export_format": {
+						"type": "string",
+						"enum": ["png", "jpeg", "webp"],
+						"description": "Format used when export_image omits format and the output path has no extension"
+					},
+					"export_scale": {
+						"type": "number",
+						"description": "Device scale factor used when export_image omits scale"
+					}
+				},
+				"required": ["name"]
+			}`),
+		},
+	}
+}
+
+// forWorkspace returns the handler serving the named workspace, creating it
+// on first use. An empty name selects this handler's own workspace.
+func (h *Handler) forWorkspace(name string) (*Handler, error) {
+	if name == "" || name == h.workspace.Name {
+		return h, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if wh, ok := h.workspaces[name]; ok {
+		return wh, nil
+	}
+
+	ws, err := h.config.Workspace(name)
+	if err != nil {
+		return nil, err
+	}
+	wh, err := newWorkspaceHandler(h.config, ws, h.screenshotSvc, h.fetcher)
+	if err != nil {
+		return nil, err
+	}
+	h.workspaces[name] = wh
+	return wh, nil
+}
+
+func (h *Handler) handleListWorkspaces(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	workspaces, err := h.config.Workspaces()
+	if err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to list workspaces: %v", err)), nil
+	}
+
+	list := make([]map[string]interface{}, len(workspaces))
+	for i, ws := range workspaces {
+		list[i] = workspaceInfo(h.config, ws)
+	}
+
+	return h.successResponse(map[string]interface{}{
+		"status":     "succeeded",
+		"workspaces": list,
+		"count":      len(list),
+	}), nil
+}
+
+func (h *Handler) handleCreateWorkspace(ctx context.Context, args map[string]interface{}) (*protocol.CallToolResponse, error) {
+	name, ok := args["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("name is required and must be a string")
+	}
+
+	ws := &config.Workspace{Name: name}
+	ws.Description, _ = args["description"].(string)
+	ws.RootDir, _ = args["root_dir"].(string)
+	if width, ok := args["default_width"].(float64); ok {
+		ws.DefaultWidth = int(width)
+	}
+	if height, ok := args["default_height"].(float64); ok {
+		ws.DefaultHeight = int(height)
+	}
+	ws.ExportFormat, _ = args["export_format"].(string)
+	ws.ExportScale, _ = args["export_scale"].(float64)
+	if err := (screenshot.Options{Format: ws.ExportFormat, Scale: ws.ExportScale}).Validate(); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to create workspace: %v", err)), nil
+	}
+
+	if err := h.config.AddWorkspace(ws); err != nil {
+		return h.errorResponse(fmt.Sprintf("Failed to create workspace: %v", err)), nil
+	}
+
+	result := workspaceInfo(h.config, ws)
+	result["status"] = "succeeded"
+	return h.successResponse(result), nil
+}
+
+// workspaceInfo describes a workspace for tool responses
+func workspaceInfo(cfg *config.Config, ws *config.Workspace) map[string]interface{} {
+	wsCfg := cfg.ForWorkspace(ws)
+	info := map[string]interface{}{
+		"name":       ws.Name,
+		"is_default": ws.Name == config.DefaultWorkspace,
+	}
+	if wsCfg.StorageBackend == config.BackendS3 {
+		info["location"] = fmt.Sprintf("s3://%s/%s", wsCfg.S3.Bucket, wsCfg.S3.Prefix)
+	} else {
+		info["location"] = wsCfg.RootDir
+	}
+	if ws.Description != "" {
+		info["description"] = ws.Description
+	}
+	if ws.DefaultWidth > 0 {
+		info["default_width"] = ws.DefaultWidth
+	}
+	if ws.DefaultHeight > 0 {
+		info["default_height"] = ws.DefaultHeight
+	}
+	if ws.ExportFormat != "" {
+		info["export_format"] = ws.ExportFormat
+	}
+	if ws.ExportScale > 0 {
+		info["export_scale"] = ws.ExportScale
+	}
+	if !ws.CreatedAt.IsZero() {
+		info["created_at"] = ws.CreatedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return info
+}
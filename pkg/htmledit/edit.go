@@ -0,0 +1,455 @@
+package htmledit
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Operation kinds
+const (
+	OpSetText         = "set_text"         // Replace the element's content with plain text
+	OpSetAttribute    = "set_attribute"    // Set attribute Name to Value
+	OpRemoveAttribute = "remove_attribute" // Remove attribute Name
+	OpSetStyle        = "set_style"        // Replace the style attribute with Value
+	OpMergeStyle      = "merge_style"      // Set or remove the properties in Style, keeping the others
+	OpSetInnerHTML    = "set_inner_html"   // Replace the element's content with the HTML in Value
+	OpInsert          = "insert"           // Insert the HTML in Value at Position
+	OpRemove          = "remove"           // Remove the element
+	OpReplaceText     = "replace_text"     // Replace Find with Replace in the text below the element
+)
+
+// Insert positions
+const (
+	PositionBefore  = "before"  // Before the element, as a sibling
+	PositionAfter   = "after"   // After the element, as a sibling
+	PositionPrepend = "prepend" // Inside the element, before its first child
+	PositionAppend  = "append"  // Inside the element, after its last child
+)
+
+// Operation is one edit of an HTML document, applied to the elements
+// matching Selector. Unless All is set the selector must match exactly one
+// element, so an edit never lands somewhere unexpected.
+type Operation struct {
+	Op       string            `json:"op"`
+	Selector string            `json:"selector"` // CSS selector; replace_text defaults to the whole document
+	All      bool              `json:"all,omitempty"`
+	Name     string            `json:"name,omitempty"`     // Attribute name
+	Value    string            `json:"value,omitempty"`    // Text, attribute value, style or HTML
+	Style    map[string]string `json:"style,omitempty"`    // For merge_style; an empty value removes the property
+	Position string            `json:"position,omitempty"` // For insert
+	Find     string            `json:"find,omitempty"`     // For replace_text
+	Replace  string            `json:"replace,omitempty"`  // For replace_text
+}
+
+// Edit is the outcome of applying a list of operations
+type Edit struct {
+	HTML    string    // The re-rendered HTML
+	Results []*Result // One per operation, in order
+	// Normalization describes the first change re-rendering made outside
+	// the edited elements, as reported by RoundTrip, or is empty when the
+	// rest of the markup came through unchanged
+	Normalization string
+}
+
+// Result reports the effect of an operation
+type Result struct {
+	Op       string `json:"op"`
+	Selector string `json:"selector,omitempty"`
+	Matched  int    `json:"matched"`
+	Replaced int    `json:"replaced,omitempty"` // Occurrences replaced by replace_text
+}
+
+var (
+	documentPattern  = regexp.MustCompile(`(?i)<!doctype|<html[\s>]|<head[\s>]|<body[\s>]`)
+	attrNamePattern  = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+	propertyPattern  = regexp.MustCompile(`^(--[-a-zA-Z0-9_]+|-?[a-zA-Z][-a-zA-Z0-9]*)$`)
+	errNothingToEdit = fmt.Errorf("no operations given")
+)
+
+// Apply parses htmlContent, applies ops in order and returns the re-rendered
+// HTML. If any operation fails, the error names it and nothing is returned,
+// so the list is applied entirely or not at all. Fragments without an
+// <html>, <head> or <body> tag stay fragments; their top level can be
+// addressed as body. The whole document is re-rendered, so the
+// normalization reported by RoundTrip applies outside the edited elements
+// and is returned in Edit.Normalization.
+func Apply(htmlContent string, ops []*Operation) (*Edit, error) {
+	if len(ops) == 0 {
+		return nil, errNothingToEdit
+	}
+
+	root, isDocument, err := parse(htmlContent)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &Edit{Results: make([]*Result, len(ops))}
+	for i, op := range ops {
+		result, err := apply(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i+1, op.Op, err)
+		}
+		edit.Results[i] = result
+	}
+
+	if edit.HTML, err = render(root, isDocument); err != nil {
+		return nil, err
+	}
+	if edit.Normalization, err = RoundTrip(htmlContent); err != nil {
+		return nil, err
+	}
+	return edit, nil
+}
+
+// RoundTrip reports what parsing and re-rendering htmlContent changes on its
+// own, such as closing omitted end tags, quoting attributes or re-encoding
+// entities. It returns an empty string when htmlContent survives unchanged
+// and otherwise describes the first difference.
+func RoundTrip(htmlContent string) (string, error) {
+	root, isDocument, err := parse(htmlContent)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := render(root, isDocument)
+	if err != nil {
+		return "", err
+	}
+	if rendered == htmlContent {
+		return "", nil
+	}
+
+	i := 0
+	for i < len(htmlContent) && i < len(rendered) && htmlContent[i] == rendered[i] {
+		i++
+	}
+	line := strings.Count(htmlContent[:i], "\n") + 1
+	start := max(0, i-20)
+	return fmt.Sprintf("line %d: `%s` became `%s`", line, excerpt(htmlContent, start), excerpt(rendered, start)), nil
+}
+
+// excerpt returns up to 60 bytes of s from start
+func excerpt(s string, start int) string {
+	return s[start:min(len(s), start+60)]
+}
+
+// parse parses htmlContent as a document, or as a fragment under a body
+// element when it has no <html>, <head> or <body> tag
+func parse(htmlContent string) (*html.Node, bool, error) {
+	if documentPattern.MatchString(htmlContent) {
+		doc, err := html.Parse(strings.NewReader(htmlContent))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		return doc, true, nil
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(htmlContent), root)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	return root, false, nil
+}
+
+// render serializes a tree built by parse, leaving out the body element
+// wrapped around a fragment
+func render(root *html.Node, isDocument bool) (string, error) {
+	var buf bytes.Buffer
+	nodes := []*html.Node{root}
+	if !isDocument {
+		nodes = nil
+		for c := root.FirstChild; c != nil; c = c.NextSibling {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, n := range nodes {
+		if err := html.Render(&buf, n); err != nil {
+			return "", fmt.Errorf("failed to render HTML: %w", err)
+		}
+	}
+	return buf.String(), nil
+}
+
+// apply runs one operation against the document
+func apply(root *html.Node, op *Operation) (*Result, error) {
+	switch op.Op {
+	case OpSetText, OpSetAttribute, OpRemoveAttribute, OpSetStyle, OpMergeStyle,
+		OpSetInnerHTML, OpInsert, OpRemove, OpReplaceText:
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+	result := &Result{Op: op.Op, Selector: op.Selector}
+
+	var targets []*html.Node
+	if op.Op == OpReplaceText && op.Selector == "" {
+		targets = []*html.Node{root}
+	} else {
+		if op.Selector == "" {
+			return nil, fmt.Errorf("selector is required")
+		}
+		sel, err := cascadia.Compile(op.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", op.Selector, err)
+		}
+		targets = sel.MatchAll(root)
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("selector %q matches no elements", op.Selector)
+		}
+		if len(targets) > 1 && !op.All {
+			return nil, fmt.Errorf("selector %q matches %d elements; use a more specific selector or set all", op.Selector, len(targets))
+		}
+	}
+	result.Matched = len(targets)
+
+	switch op.Op {
+	case OpSetText:
+		for _, n := range targets {
+			removeChildren(n)
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: op.Value})
+		}
+	case OpSetAttribute, OpRemoveAttribute:
+		name := strings.ToLower(op.Name)
+		if !attrNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid attribute name %q", op.Name)
+		}
+		for _, n := range targets {
+			if op.Op == OpSetAttribute {
+				setAttr(n, name, op.Value)
+			} else {
+				removeAttr(n, name)
+			}
+		}
+	case OpSetStyle:
+		for _, n := range targets {
+			if strings.TrimSpace(op.Value) == "" {
+				removeAttr(n, "style")
+			} else {
+				setAttr(n, "style", op.Value)
+			}
+		}
+	case OpMergeStyle:
+		if len(op.Style) == 0 {
+			return nil, fmt.Errorf("style is required")
+		}
+		for property := range op.Style {
+			if !propertyPattern.MatchString(property) {
+				return nil, fmt.Errorf("invalid CSS property %q", property)
+			}
+		}
+		for _, n := range targets {
+			style := mergeStyle(getAttr(n, "style"), op.Style)
+			if style == "" {
+				removeAttr(n, "style")
+			} else {
+				setAttr(n, "style", style)
+			}
+		}
+	case OpSetInnerHTML:
+		for _, n := range targets {
+			nodes, err := html.ParseFragment(strings.NewReader(op.Value), n)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value: %w", err)
+			}
+			removeChildren(n)
+			for _, c := range nodes {
+				n.AppendChild(c)
+			}
+		}
+	case OpInsert:
+		for _, n := range targets {
+			if err := insert(root, n, op.Position, op.Value); err != nil {
+				return nil, err
+			}
+		}
+	case OpRemove:
+		for _, n := range targets {
+			if n == root || n.Parent == nil || n.Parent.Type == html.DocumentNode {
+				return nil, fmt.Errorf("cannot remove the root element")
+			}
+			n.Parent.RemoveChild(n)
+		}
+	case OpReplaceText:
+		if op.Find == "" {
+			return nil, fmt.Errorf("find is required")
+		}
+		for _, n := range targets {
+			result.Replaced += replaceText(n, op.Find, op.Replace)
+		}
+		if result.Replaced == 0 {
+			return nil, fmt.Errorf("%q was not found in the text", op.Find)
+		}
+	}
+	return result, nil
+}
+
+// insert parses htmlContent in the context it is inserted into and places it
+// relative to n
+func insert(root, n *html.Node, position, htmlContent string) error {
+	context := n
+	switch position {
+	case PositionBefore, PositionAfter:
+		if n == root || n.Parent == nil || n.Parent.Type != html.ElementNode {
+			return fmt.Errorf("cannot insert %s the root element", position)
+		}
+		context = n.Parent
+	case PositionPrepend, PositionAppend:
+	default:
+		return fmt.Errorf("position must be %s, %s, %s or %s", PositionBefore, PositionAfter, PositionPrepend, PositionAppend)
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(htmlContent), context)
+	if err != nil {
+		return fmt.Errorf("failed to parse value: %w", err)
+	}
+	// Inserting every node before the same reference keeps them in order
+	parent, ref := n.Parent, n
+	switch position {
+	case PositionAfter:
+		ref = n.NextSibling
+	case PositionPrepend:
+		parent, ref = n, n.FirstChild
+	case PositionAppend:
+		parent, ref = n, nil
+	}
+	for _, c := range nodes {
+		parent.InsertBefore(c, ref)
+	}
+	return nil
+}
+
+// replaceText replaces find in every text node below n and returns the
+// number of replacements
+func replaceText(n *html.Node, find, replace string) int {
+	if n.Type == html.TextNode {
+		count := strings.Count(n.Data, find)
+		n.Data = strings.ReplaceAll(n.Data, find, replace)
+		return count
+	}
+	count := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		count += replaceText(c, find, replace)
+	}
+	return count
+}
+
+// mergeStyle sets or removes properties in an inline style, keeping the
+// order of existing declarations and appending new ones sorted by name
+func mergeStyle(style string, edits map[string]string) string {
+	type declaration struct{ property, value string }
+	var decls []declaration
+	for _, d := range splitDeclarations(style) {
+		property, value, ok := strings.Cut(d, ":")
+		if !ok {
+			continue
+		}
+		decls = append(decls, declaration{strings.TrimSpace(property), strings.TrimSpace(value)})
+	}
+
+	properties := make([]string, 0, len(edits))
+	for property := range edits {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+	for _, property := range properties {
+		value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(edits[property]), ";"))
+		kept := decls[:0]
+		found := false
+		for _, d := range decls {
+			if !strings.EqualFold(d.property, property) {
+				kept = append(kept, d)
+			} else if value != "" && !found {
+				kept = append(kept, declaration{d.property, value})
+				found = true
+			}
+		}
+		decls = kept
+		if value != "" && !found {
+			decls = append(decls, declaration{property, value})
+		}
+	}
+
+	parts := make([]string, len(decls))
+	for i, d := range decls {
+		parts[i] = d.property + ": " + d.value
+	}
+	return strings.Join(parts, "; ")
+}
+
+// splitDeclarations splits an inline style on semicolons that are not inside
+// quotes or parentheses, such as those of a data URL
+func splitDeclarations(style string) []string {
+	var decls []string
+	var quote rune
+	depth, start := 0, 0
+	for i, r := range style {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == ';' && depth == 0:
+			decls = append(decls, style[start:i])
+			start = i + 1
+		}
+	}
+	decls = append(decls, style[start:])
+
+	nonEmpty := decls[:0]
+	for _, d := range decls {
+		if strings.TrimSpace(d) != "" {
+			nonEmpty = append(nonEmpty, d)
+		}
+	}
+	return nonEmpty
+}
+
+func removeChildren(n *html.Node) {
+	for n.FirstChild != nil {
+		n.RemoveChild(n.FirstChild)
+	}
+}
+
+func getAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, name, value string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: name, Val: value})
+}
+
+func removeAttr(n *html.Node, name string) {
+	kept := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Namespace != "" || a.Key != name {
+			kept = append(kept, a)
+		}
+	}
+	n.Attr = kept
+}
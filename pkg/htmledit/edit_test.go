@@ -0,0 +1,321 @@
+package htmledit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		op   *Operation
+		want string
+	}{
+		{
+			"set_text escapes markup",
+			`<h1 id="t">Old</h1>`,
+			&Operation{Op: OpSetText, Selector: "#t", Value: "a < b & <i>c</i>"},
+			`<h1 id="t">a &lt; b &amp; &lt;i&gt;c&lt;/i&gt;</h1>`,
+		},
+		{
+			"set_attribute adds",
+			`<img src="a.png"/>`,
+			&Operation{Op: OpSetAttribute, Selector: "img", Name: "alt", Value: `say "hi"`},
+			`<img src="a.png" alt="say &#34;hi&#34;"/>`,
+		},
+		{
+			"set_attribute replaces",
+			`<a href="/old">x</a>`,
+			&Operation{Op: OpSetAttribute, Selector: "a", Name: "HREF", Value: "/new"},
+			`<a href="/new">x</a>`,
+		},
+		{
+			"remove_attribute",
+			`<p class="x" id="p">x</p>`,
+			&Operation{Op: OpRemoveAttribute, Selector: "#p", Name: "class"},
+			`<p id="p">x</p>`,
+		},
+		{
+			"set_style",
+			`<p style="color: red">x</p>`,
+			&Operation{Op: OpSetStyle, Selector: "p", Value: "margin: 0"},
+			`<p style="margin: 0">x</p>`,
+		},
+		{
+			"set_style empty removes the attribute",
+			`<p style="color: red">x</p>`,
+			&Operation{Op: OpSetStyle, Selector: "p", Value: " "},
+			`<p>x</p>`,
+		},
+		{
+			"merge_style keeps order and appends sorted",
+			`<p style="color: red; margin: 0">x</p>`,
+			&Operation{Op: OpMergeStyle, Selector: "p", Style: map[string]string{"margin": "4px;", "padding": "1px", "border": "0"}},
+			`<p style="color: red; margin: 4px; border: 0; padding: 1px">x</p>`,
+		},
+		{
+			"merge_style removes and keeps data URLs whole",
+			`<p style="background: url(data:image/png;base64,AA==); color: red">x</p>`,
+			&Operation{Op: OpMergeStyle, Selector: "p", Style: map[string]string{"COLOR": ""}},
+			`<p style="background: url(data:image/png;base64,AA==)">x</p>`,
+		},
+		{
+			"merge_style removing the last property removes the attribute",
+			`<p style="color: red">x</p>`,
+			&Operation{Op: OpMergeStyle, Selector: "p", Style: map[string]string{"color": ""}},
+			`<p>x</p>`,
+		},
+		{
+			"set_inner_html",
+			`<div><p>old</p></div>`,
+			&Operation{Op: OpSetInnerHTML, Selector: "div", Value: `<b>new</b> text`},
+			`<div><b>new</b> text</div>`,
+		},
+		{
+			"insert before",
+			`<ul><li id="b">b</li></ul>`,
+			&Operation{Op: OpInsert, Selector: "#b", Position: PositionBefore, Value: `<li>a1</li><li>a2</li>`},
+			`<ul><li>a1</li><li>a2</li><li id="b">b</li></ul>`,
+		},
+		{
+			"insert after",
+			`<ul><li id="a">a</li><li>c</li></ul>`,
+			&Operation{Op: OpInsert, Selector: "#a", Position: PositionAfter, Value: `<li>b</li>`},
+			`<ul><li id="a">a</li><li>b</li><li>c</li></ul>`,
+		},
+		{
+			"insert prepend parses in the element's context",
+			`<table><tbody><tr><td>2</td></tr></tbody></table>`,
+			&Operation{Op: OpInsert, Selector: "tbody", Position: PositionPrepend, Value: `<tr><td>1</td></tr>`},
+			`<table><tbody><tr><td>1</td></tr><tr><td>2</td></tr></tbody></table>`,
+		},
+		{
+			"insert append",
+			`<div><p>a</p></div>`,
+			&Operation{Op: OpInsert, Selector: "div", Position: PositionAppend, Value: `<p>b</p>`},
+			`<div><p>a</p><p>b</p></div>`,
+		},
+		{
+			"insert at the top level of a fragment",
+			`<p>a</p>`,
+			&Operation{Op: OpInsert, Selector: "body", Position: PositionAppend, Value: `<p>b</p>`},
+			`<p>a</p><p>b</p>`,
+		},
+		{
+			"remove",
+			`<div><p class="ad">x</p><p>y</p></div>`,
+			&Operation{Op: OpRemove, Selector: ".ad"},
+			`<div><p>y</p></div>`,
+		},
+		{
+			"replace_text in text and style contents",
+			`<style>.a{color:#111}</style><p>#111 and #111</p>`,
+			&Operation{Op: OpReplaceText, Find: "#111", Replace: "#222"},
+			`<style>.a{color:#222}</style><p>#222 and #222</p>`,
+		},
+		{
+			"replace_text leaves attributes alone",
+			`<p title="old">old</p>`,
+			&Operation{Op: OpReplaceText, Selector: "p", Find: "old", Replace: "new"},
+			`<p title="old">new</p>`,
+		},
+		{
+			"document keeps its structure",
+			"<!DOCTYPE html><html><head><title>T</title></head><body><h1>Old</h1></body></html>",
+			&Operation{Op: OpSetText, Selector: "h1", Value: "New"},
+			"<!DOCTYPE html><html><head><title>T</title></head><body><h1>New</h1></body></html>",
+		},
+	}
+
+	for _, tt := range tests {
+		edit, err := Apply(tt.html, []*Operation{tt.op})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if edit.HTML != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, edit.HTML, tt.want)
+		}
+		if len(edit.Results) != 1 || edit.Results[0].Op != tt.op.Op {
+			t.Errorf("%s: results %+v, want one for %s", tt.name, edit.Results, tt.op.Op)
+		}
+	}
+}
+
+func TestApplyMatchCount(t *testing.T) {
+	const doc = `<p class="x">a</p><p class="x">b</p><p id="c">c</p>`
+	tests := []struct {
+		name    string
+		op      *Operation
+		matched int
+		err     string
+	}{
+		{"one match", &Operation{Op: OpSetText, Selector: "#c", Value: "z"}, 1, ""},
+		{"several matches without all", &Operation{Op: OpSetText, Selector: ".x", Value: "z"}, 0, "matches 2 elements"},
+		{"several matches with all", &Operation{Op: OpSetText, Selector: ".x", Value: "z", All: true}, 2, ""},
+		{"no match", &Operation{Op: OpSetText, Selector: ".missing", Value: "z"}, 0, "matches no elements"},
+		{"no match with all", &Operation{Op: OpRemove, Selector: ".missing", All: true}, 0, "matches no elements"},
+		{"missing selector", &Operation{Op: OpSetText, Value: "z"}, 0, "selector is required"},
+		{"invalid selector", &Operation{Op: OpSetText, Selector: "p[", Value: "z"}, 0, "invalid selector"},
+		{"replace_text counts", &Operation{Op: OpReplaceText, Selector: "p", All: true, Find: "a", Replace: "b"}, 3, ""},
+	}
+
+	for _, tt := range tests {
+		edit, err := Apply(doc, []*Operation{tt.op})
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := edit.Results[0].Matched; got != tt.matched {
+			t.Errorf("%s: matched %d, want %d", tt.name, got, tt.matched)
+		}
+	}
+}
+
+func TestApplyInvalidOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		op   *Operation
+		err  string
+	}{
+		{"unknown op", `<p>x</p>`, &Operation{Op: "rename", Selector: "p"}, "unknown operation"},
+		{"bad attribute name", `<p>x</p>`, &Operation{Op: OpSetAttribute, Selector: "p", Name: `on"x`}, "invalid attribute name"},
+		{"bad property", `<p>x</p>`, &Operation{Op: OpMergeStyle, Selector: "p", Style: map[string]string{"color;x": "red"}}, "invalid CSS property"},
+		{"empty merge", `<p>x</p>`, &Operation{Op: OpMergeStyle, Selector: "p"}, "style is required"},
+		{"bad position", `<p>x</p>`, &Operation{Op: OpInsert, Selector: "p", Position: "inside"}, "position must be"},
+		{"insert beside the root", `<p>x</p>`, &Operation{Op: OpInsert, Selector: "body", Position: PositionBefore}, "cannot insert before the root"},
+		{"remove the root", `<p>x</p>`, &Operation{Op: OpRemove, Selector: "body"}, "cannot remove the root"},
+		{"remove html", `<html><body></body></html>`, &Operation{Op: OpRemove, Selector: "html"}, "cannot remove the root"},
+		{"empty find", `<p>x</p>`, &Operation{Op: OpReplaceText, Selector: "p"}, "find is required"},
+		{"find not present", `<p>x</p>`, &Operation{Op: OpReplaceText, Find: "y"}, "was not found"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Apply(tt.html, []*Operation{tt.op}); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := Apply(`<p>x</p>`, nil); err != errNothingToEdit {
+		t.Errorf("no operations: got %v, want %v", err, errNothingToEdit)
+	}
+}
+
+func TestApplyRollsBack(t *testing.T) {
+	const doc = `<h1>Title</h1><p>Body</p>`
+	tests := []struct {
+		name string
+		ops  []*Operation
+		err  string
+	}{
+		{
+			"second op fails",
+			[]*Operation{
+				{Op: OpSetText, Selector: "h1", Value: "New"},
+				{Op: OpSetText, Selector: "h2", Value: "New"},
+			},
+			"operation 2 (set_text)",
+		},
+		{
+			"last of several fails",
+			[]*Operation{
+				{Op: OpRemove, Selector: "p"},
+				{Op: OpInsert, Selector: "h1", Position: PositionAfter, Value: "<p>Other</p>"},
+				{Op: OpReplaceText, Find: "missing"},
+			},
+			"operation 3 (replace_text)",
+		},
+		{
+			"later op depends on an earlier one and fails",
+			[]*Operation{
+				{Op: OpRemove, Selector: "p"},
+				{Op: OpSetText, Selector: "p", Value: "x"},
+			},
+			"operation 2 (set_text)",
+		},
+	}
+
+	for _, tt := range tests {
+		edit, err := Apply(doc, tt.ops)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want one starting %q", tt.name, err, tt.err)
+		}
+		if edit != nil {
+			t.Errorf("%s: got an edit %q alongside the error", tt.name, edit.HTML)
+		}
+	}
+
+	// The same operations succeed once the failing one is dropped
+	edit, err := Apply(doc, []*Operation{
+		{Op: OpRemove, Selector: "p"},
+		{Op: OpInsert, Selector: "h1", Position: PositionAfter, Value: "<p>Other</p>"},
+		{Op: OpSetText, Selector: "p", Value: "Last"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<h1>Title</h1><p>Last</p>`; edit.HTML != want {
+		t.Errorf("got %s, want %s", edit.HTML, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		changed string // Substring of the reported difference; empty when unchanged
+	}{
+		{"plain fragment", `<div class="a"><p>Hi &amp; bye</p></div>`, ""},
+		{"several top-level nodes", "<style>p{color:red}</style>\n<p>a</p>\n<p>b</p>", ""},
+		{"text only", "just text", ""},
+		{"void elements", `<p>a<br/>b</p><img src="a.png"/>`, ""},
+		{"full document", "<!DOCTYPE html><html><head><title>T</title></head><body><p>x</p></body></html>", ""},
+		{"unquoted attribute", `<p class=x>a</p>`, "line 1"},
+		{"omitted end tag", "<ul>\n<li>a\n<li>b\n</ul>", "line 3"},
+		{"re-encoded entity", `<p>&copy; 2024</p>`, "©"},
+		{"missing head and body", "<html><p>x</p></html>", "<head>"},
+	}
+
+	for _, tt := range tests {
+		got, err := RoundTrip(tt.html)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.changed == "" && got != "" {
+			t.Errorf("%s: reported %s, want no change", tt.name, got)
+		}
+		if tt.changed != "" && !strings.Contains(got, tt.changed) {
+			t.Errorf("%s: reported %q, want it to contain %q", tt.name, got, tt.changed)
+		}
+	}
+}
+
+func TestApplyReportsNormalization(t *testing.T) {
+	tests := []struct {
+		name       string
+		html       string
+		normalized bool
+	}{
+		{"clean markup", `<h1>a</h1><p class="x">b</p>`, false},
+		{"unquoted attribute outside the edit", `<h1>a</h1><p class=x>b</p>`, true},
+	}
+
+	for _, tt := range tests {
+		edit, err := Apply(tt.html, []*Operation{{Op: OpSetText, Selector: "h1", Value: "z"}})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if (edit.Normalization != "") != tt.normalized {
+			t.Errorf("%s: normalization %q, want reported = %v", tt.name, edit.Normalization, tt.normalized)
+		}
+	}
+}
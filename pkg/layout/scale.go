@@ -0,0 +1,57 @@
+package layout
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+)
+
+var (
+	styleBlockPattern = regexp.MustCompile(`(?is)(<style\b[^>]*>)(.*?)(</style>)`)
+	styleAttrPattern  = regexp.MustCompile(`(?is)(\sstyle\s*=\s*)("[^"]*"|'[^']*')`)
+	pxValuePattern    = regexp.MustCompile(`(?i)(^|[^\w.#-])(-?(?:\d+\.?\d*|\.\d+))px\b`)
+)
+
+// ScalePx multiplies every px length in the HTML's <style> blocks and inline
+// style attributes by factor. Text content and other units are left alone.
+func ScalePx(html string, factor float64) string {
+	if factor == 1 {
+		return html
+	}
+
+	html = styleBlockPattern.ReplaceAllStringFunc(html, func(block string) string {
+		m := styleBlockPattern.FindStringSubmatch(block)
+		return m[1] + scaleCSS(m[2], factor) + m[3]
+	})
+	return styleAttrPattern.ReplaceAllStringFunc(html, func(attr string) string {
+		m := styleAttrPattern.FindStringSubmatch(attr)
+		return m[1] + scaleCSS(m[2], factor)
+	})
+}
+
+// ProportionalFactor returns the uniform factor that fits a fromW x fromH
+// canvas into toW x toH
+func ProportionalFactor(fromW, fromH, toW, toH int) float64 {
+	return math.Min(float64(toW)/float64(fromW), float64(toH)/float64(fromH))
+}
+
+// scaleCSS rewrites px values in a fragment of CSS
+func scaleCSS(css string, factor float64) string {
+	return pxValuePattern.ReplaceAllStringFunc(css, func(value string) string {
+		m := pxValuePattern.FindStringSubmatch(value)
+		n, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return value
+		}
+		return m[1] + formatLength(n*factor) + "px"
+	})
+}
+
+// formatLength formats a length with at most two decimals, dropping trailing zeros
+func formatLength(n float64) string {
+	n = math.Round(n*100) / 100
+	if n == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
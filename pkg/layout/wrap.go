@@ -0,0 +1,57 @@
+package layout
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scaleWrapperClose marks the end of the wrapper inserted by WrapScaled
+const scaleWrapperClose = `</div><!--/data-canvas-scale-->`
+
+var (
+	scaleWrapperOpenPattern = regexp.MustCompile(`<div data-canvas-scale data-base-width="(\d+)" data-base-height="(\d+)"[^>]*>`)
+	bodyOpenPattern         = regexp.MustCompile(`(?is)<body\b[^>]*>`)
+	bodyClosePattern        = regexp.MustCompile(`(?is)</body\s*>`)
+)
+
+// WrapScaled fits a layout designed for a fromW x fromH canvas into a toW x
+// toH canvas by wrapping the body content in a div scaled uniformly with a
+// CSS transform and centered on the new canvas. Content that was already
+// wrapped is rescaled from its original dimensions instead of being wrapped
+// again, and the wrapper is removed when it would be a no-op.
+func WrapScaled(html string, fromW, fromH, toW, toH int) string {
+	if loc := scaleWrapperOpenPattern.FindStringSubmatchIndex(html); loc != nil {
+		if end := strings.LastIndex(html, scaleWrapperClose); end > loc[1] {
+			fromW, _ = strconv.Atoi(html[loc[2]:loc[3]])
+			fromH, _ = strconv.Atoi(html[loc[4]:loc[5]])
+			html = html[:loc[0]] + html[loc[1]:end] + html[end+len(scaleWrapperClose):]
+		}
+	}
+	if fromW == toW && fromH == toH {
+		return html
+	}
+
+	factor := ProportionalFactor(fromW, fromH, toW, toH)
+	left := (float64(toW) - float64(fromW)*factor) / 2
+	top := (float64(toH) - float64(fromH)*factor) / 2
+	open := fmt.Sprintf(
+		`<div data-canvas-scale data-base-width="%d" data-base-height="%d" style="position:absolute;left:%spx;top:%spx;width:%dpx;height:%dpx;transform:scale(%s);transform-origin:0 0">`,
+		fromW, fromH, formatLength(left), formatLength(top), fromW, fromH, strconv.FormatFloat(factor, 'f', -1, 64),
+	)
+
+	start, end := 0, len(html)
+	if loc := bodyOpenPattern.FindStringIndex(html); loc != nil {
+		start = loc[1]
+		if closeLoc := bodyClosePattern.FindStringIndex(html[start:]); closeLoc != nil {
+			end = start + closeLoc[0]
+		}
+	}
+	return html[:start] + open + html[start:end] + scaleWrapperClose + html[end:]
+}
+
+// IsScaleWrapped reports whether html contains a wrapper added by WrapScaled
+func IsScaleWrapped(html string) bool {
+	return scaleWrapperOpenPattern.MatchString(html) && strings.Contains(html, scaleWrapperClose)
+}
@@ -0,0 +1,158 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultFetchTimeout is the default timeout for downloading remote media
+const DefaultFetchTimeout = 30 * time.Second
+
+// allowedRemoteTypes lists the content type prefixes accepted from remote URLs
+var allowedRemoteTypes = []string{"image/", "font/", "video/", "application/font-", "application/x-font-"}
+
+// Fetched is a downloaded remote media file
+type Fetched struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+}
+
+// Fetcher downloads remote media over HTTP(S)
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewFetcher creates a new Fetcher with the given timeout and size limit.
+// Unless allowPrivate is set, connections to loopback, private, shared
+// (carrier-grade NAT), link-local, unspecified and multicast addresses are
+// refused. The check runs on the resolved address of every connection, so
+// it also covers redirects and DNS names pointing at internal hosts. Proxies
+// from the environment are not used while the check is on, since it would
+// only see the proxy's address.
+func NewFetcher(timeout time.Duration, maxBytes int64, allowPrivate bool) *Fetcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   rejectPrivateAddress,
+		}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &Fetcher{
+		client:   &http.Client{Timeout: timeout, Transport: transport},
+		maxBytes: maxBytes,
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// net.IP.IsPrivate does not cover
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// rejectPrivateAddress is a net.Dialer Control hook refusing connections to
+// addresses that are not publicly routable
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", address, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %s", address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s (set HTML_IMAGE_CREATOR_ALLOW_PRIVATE_URLS=true to allow)", ip)
+	}
+	return nil
+}
+
+// Fetch downloads rawURL, enforcing the size limit and content type checks
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Fetched, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q: only http and https are allowed", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("URL is missing a host")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "html-image-creator/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: unexpected status %s", rawURL, resp.Status)
+	}
+	if f.maxBytes > 0 && resp.ContentLength > f.maxBytes {
+		return nil, fmt.Errorf("remote file is %d bytes, exceeding maximum size of %d bytes", resp.ContentLength, f.maxBytes)
+	}
+
+	reader := io.Reader(resp.Body)
+	if f.maxBytes > 0 {
+		reader = io.LimitReader(resp.Body, f.maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if f.maxBytes > 0 && int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("remote file exceeds maximum size of %d bytes", f.maxBytes)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("remote file is empty")
+	}
+
+	contentType := SniffContentType(data)
+	if contentType == "application/octet-stream" || strings.HasPrefix(contentType, "text/") {
+		if declared, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && declared != "" {
+			contentType = declared
+		}
+	}
+	if !isAllowedRemoteType(contentType) {
+		return nil, fmt.Errorf("remote file has unsupported content type %s", contentType)
+	}
+
+	filename := path.Base(u.Path)
+	if filename == "." || filename == "/" {
+		filename = ""
+	}
+
+	return &Fetched{
+		Data:        data,
+		ContentType: contentType,
+		Filename:    filename,
+	}, nil
+}
+
+func isAllowedRemoteType(contentType string) bool {
+	for _, prefix := range allowedRemoteTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
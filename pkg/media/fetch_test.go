@@ -0,0 +1,79 @@
+package media
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRejectPrivateAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		blocked bool
+	}{
+		{"127.0.0.1:80", true},
+		{"127.8.9.10:443", true},
+		{"[::1]:80", true},
+		{"10.0.0.1:80", true},
+		{"172.16.5.4:80", true},
+		{"192.168.1.1:80", true},
+		{"[fd00::1]:80", true},
+		{"169.254.169.254:80", true},
+		{"[fe80::1]:80", true},
+		{"100.64.0.1:80", true},
+		{"100.127.255.254:80", true},
+		{"0.0.0.0:80", true},
+		{"[::]:80", true},
+		{"224.0.0.1:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"[::ffff:10.1.2.3]:80", true},
+		{"[::ffff:169.254.169.254]:80", true},
+		{"[::ffff:100.64.0.1]:80", true},
+		{"93.184.216.34:443", false},
+		{"100.63.255.255:80", false},
+		{"100.128.0.0:80", false},
+		{"[2606:2800:220:1::1]:443", false},
+		{"[::ffff:93.184.216.34]:443", false},
+	}
+	for _, tt := range tests {
+		err := rejectPrivateAddress("tcp", tt.address, nil)
+		if tt.blocked && err == nil {
+			t.Errorf("%s was allowed, want it refused", tt.address)
+		}
+		if !tt.blocked && err != nil {
+			t.Errorf("%s was refused: %v", tt.address, err)
+		}
+	}
+}
+
+func TestRejectPrivateAddressInvalid(t *testing.T) {
+	for _, address := range []string{"127.0.0.1", "example.com:80", ""} {
+		if err := rejectPrivateAddress("tcp", address, nil); err == nil {
+			t.Errorf("%q was allowed, want an error", address)
+		}
+	}
+}
+
+func TestFetchPrivateAddress(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(png)
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher(5*time.Second, 1024, false).Fetch(context.Background(), server.URL+"/a.png")
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("Fetch from %s: got %v, want the address refused", server.URL, err)
+	}
+
+	fetched, err := NewFetcher(5*time.Second, 1024, true).Fetch(context.Background(), server.URL+"/a.png")
+	if err != nil {
+		t.Fatalf("Fetch with private addresses allowed: %v", err)
+	}
+	if fetched.ContentType != "image/png" || fetched.Filename != "a.png" {
+		t.Errorf("Fetch = %s %s, want image/png a.png", fetched.ContentType, fetched.Filename)
+	}
+}
@@ -0,0 +1,182 @@
+package media
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+// DefaultAllowedTypes lists the content types accepted for import by default
+var DefaultAllowedTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/svg+xml",
+	"image/bmp",
+	"font/woff",
+	"font/woff2",
+	"font/ttf",
+	"font/otf",
+}
+
+// DefaultMaxPixels is the default limit on the pixel count of raster images.
+// Decoding allocates four bytes per pixel, so a small file claiming huge
+// dimensions is refused before it is decoded.
+const DefaultMaxPixels = 50_000_000
+
+// Policy controls which media files may be imported into a post
+type Policy struct {
+	MaxBytes     int64    // Maximum file size in bytes (0 means unlimited)
+	MaxPixels    int64    // Maximum width times height of raster images (0 means unlimited)
+	AllowedTypes []string // Allowed sniffed content types (empty means DefaultAllowedTypes)
+}
+
+// DefaultPolicy returns the policy used when none is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxBytes:     DefaultMaxBytes,
+		MaxPixels:    DefaultMaxPixels,
+		AllowedTypes: DefaultAllowedTypes,
+	}
+}
+
+// Info describes a validated media file
+type Info struct {
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+}
+
+// Allows reports whether the policy accepts the content type
+func (p Policy) Allows(contentType string) bool {
+	allowed := p.AllowedTypes
+	if len(allowed) == 0 {
+		allowed = DefaultAllowedTypes
+	}
+	for _, t := range allowed {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Inspect validates data against the policy and probes image dimensions.
+// Raster images are fully decoded so truncated or corrupt files are rejected,
+// once their header shows they are within the pixel limit.
+func (p Policy) Inspect(data []byte) (*Info, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("media file is empty")
+	}
+	if p.MaxBytes > 0 && int64(len(data)) > p.MaxBytes {
+		return nil, fmt.Errorf("media file is %d bytes, exceeding maximum size of %d bytes", len(data), p.MaxBytes)
+	}
+
+	contentType := SniffContentType(data)
+	if !p.Allows(contentType) {
+		return nil, fmt.Errorf("media type %s is not allowed", contentType)
+	}
+
+	info := &Info{
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	switch {
+	case contentType == "image/svg+xml":
+		width, height, err := probeSVG(data)
+		if err != nil {
+			return nil, err
+		}
+		info.Width, info.Height = width, height
+	case strings.HasPrefix(contentType, "image/"):
+		img, _, err := decodeLimited(data, p.MaxPixels)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s image: %w", contentType, err)
+		}
+		bounds := img.Bounds()
+		info.Width, info.Height = bounds.Dx(), bounds.Dy()
+		// Browsers honour EXIF orientation, so report the displayed size
+		if ReadOrientation(data) >= 5 {
+			info.Width, info.Height = info.Height, info.Width
+		}
+	}
+
+	return info, nil
+}
+
+// decodeLimited decodes a raster image after checking the dimensions in its
+// header against maxPixels (0 means unlimited)
+func decodeLimited(data []byte, maxPixels int64) (image.Image, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if maxPixels > 0 && int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, "", fmt.Errorf("image is %dx%d, exceeding the maximum of %d pixels", config.Width, config.Height, maxPixels)
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+// probeSVG checks that data has an <svg> root element and reads its size
+// from the width/height attributes, falling back to the viewBox
+func probeSVG(data []byte) (int, int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid SVG: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return 0, 0, fmt.Errorf("invalid SVG: root element is <%s>", start.Name.Local)
+		}
+
+		var width, height float64
+		var viewBox string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				width = parseSVGLength(attr.Value)
+			case "height":
+				height = parseSVGLength(attr.Value)
+			case "viewBox":
+				viewBox = attr.Value
+			}
+		}
+		if (width == 0 || height == 0) && viewBox != "" {
+			fields := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ' ' || r == ',' })
+			if len(fields) == 4 {
+				width, _ = strconv.ParseFloat(fields[2], 64)
+				height, _ = strconv.ParseFloat(fields[3], 64)
+			}
+		}
+		return int(width + 0.5), int(height + 0.5), nil
+	}
+}
+
+// parseSVGLength parses absolute SVG lengths such as "120" or "120px".
+// Relative units like "100%" yield 0.
+func parseSVGLength(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
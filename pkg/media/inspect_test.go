@@ -0,0 +1,114 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// pngHeader returns a PNG signature and IHDR chunk claiming width x height,
+// with no image data after it
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // Bit depth
+	ihdr[9] = 6 // RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+// gifHeader returns a GIF header claiming width x height
+func gifHeader(width, height uint16) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a")
+	binary.Write(&buf, binary.LittleEndian, width)
+	binary.Write(&buf, binary.LittleEndian, height)
+	buf.Write([]byte{0, 0, 0, ';'})
+	return buf.Bytes()
+}
+
+func smallPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInspectPixelLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"png 50000x50000", pngHeader(50000, 50000)},
+		{"png 1x100000000", pngHeader(1, 100_000_000)},
+		{"gif 65535x65535", gifHeader(65535, 65535)},
+	}
+	policy := DefaultPolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.Inspect(tt.data)
+			if err == nil || !strings.Contains(err.Error(), "exceeding the maximum of 50000000 pixels") {
+				t.Fatalf("Inspect: got %v, want the pixel limit error", err)
+			}
+		})
+	}
+}
+
+func TestInspectWithinPixelLimit(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.MaxPixels = 200
+	info, err := policy.Inspect(smallPNG(t, 20, 10))
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if info.Width != 20 || info.Height != 10 {
+		t.Errorf("Inspect = %dx%d, want 20x10", info.Width, info.Height)
+	}
+
+	if _, err := policy.Inspect(smallPNG(t, 21, 10)); err == nil {
+		t.Error("Inspect of a 21x10 image with a 200 pixel limit succeeded")
+	}
+}
+
+func TestInspectUnlimitedPixels(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.MaxPixels = 0
+	// Without a limit the header is trusted and decoding fails on the missing data
+	_, err := policy.Inspect(pngHeader(50000, 50000))
+	if err == nil || strings.Contains(err.Error(), "pixels") {
+		t.Fatalf("Inspect: got %v, want a decoding error", err)
+	}
+}
+
+func TestTransformPixelLimit(t *testing.T) {
+	transform := &Transform{MaxDimension: 100, MaxPixels: DefaultMaxPixels}
+	_, _, err := transform.Apply(pngHeader(50000, 50000))
+	if err == nil || !strings.Contains(err.Error(), "exceeding the maximum of 50000000 pixels") {
+		t.Fatalf("Apply: got %v, want the pixel limit error", err)
+	}
+
+	data, contentType, err := transform.Apply(smallPNG(t, 400, 200))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/png" || config.Width != 100 || config.Height != 50 {
+		t.Errorf("Apply = %s %dx%d, want image/png 100x50", contentType, config.Width, config.Height)
+	}
+}
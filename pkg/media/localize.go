@@ -0,0 +1,73 @@
+package media
+
+import (
+	"regexp"
+)
+
+var (
+	// imgSrcPattern matches remote src attributes on <img> tags
+	imgSrcPattern = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)(["']?)(https?://[^"'\s>]+)(["']?)`)
+	// cssURLPattern matches remote CSS url() references
+	cssURLPattern = regexp.MustCompile(`(?i)url\(\s*(["']?)(https?://[^"')\s]+)(["']?)\s*\)`)
+)
+
+// FindRemoteURLs returns the unique remote URLs referenced by <img src> and
+// CSS url() in htmlContent, in order of first appearance
+func FindRemoteURLs(htmlContent string) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	add := func(u string) {
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+
+	for _, m := range imgSrcPattern.FindAllStringSubmatch(htmlContent, -1) {
+		add(m[3])
+	}
+	for _, m := range cssURLPattern.FindAllStringSubmatch(htmlContent, -1) {
+		add(m[2])
+	}
+	return urls
+}
+
+// ReplaceRemoteURLs rewrites <img src> and CSS url() references found in
+// replacements to their local paths. URLs without a replacement are kept.
+func ReplaceRemoteURLs(htmlContent string, replacements map[string]string) string {
+	htmlContent = imgSrcPattern.ReplaceAllStringFunc(htmlContent, func(match string) string {
+		m := imgSrcPattern.FindStringSubmatch(match)
+		local, ok := replacements[m[3]]
+		if !ok {
+			return match
+		}
+		return m[1] + m[2] + local + m[4]
+	})
+
+	return cssURLPattern.ReplaceAllStringFunc(htmlContent, func(match string) string {
+		m := cssURLPattern.FindStringSubmatch(match)
+		local, ok := replacements[m[2]]
+		if !ok {
+			return match
+		}
+		return "url(" + m[1] + local + m[3] + ")"
+	})
+}
+
+// localRefPattern matches src/href attributes and CSS url() references to
+// files in a post's media folder
+var localRefPattern = regexp.MustCompile(`(?i)(?:\b(?:src|href)\s*=\s*["']?|url\(\s*["']?)(?:\./)?(media/[^"'\s>)?#]+)`)
+
+// FindMediaRefs returns the unique media/ paths referenced by src, href and
+// CSS url() in htmlContent, in order of first appearance
+func FindMediaRefs(htmlContent string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, m := range localRefPattern.FindAllStringSubmatch(htmlContent, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			refs = append(refs, m[1])
+		}
+	}
+	return refs
+}
@@ -0,0 +1,174 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxBytes is the default size limit for a single imported media file
+const DefaultMaxBytes = 20 * 1024 * 1024
+
+// extensions maps sniffed content types to the file extension used when
+// the caller does not provide a filename
+var extensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/bmp":     ".bmp",
+	"image/x-icon":  ".ico",
+	"video/mp4":     ".mp4",
+	"video/webm":    ".webm",
+	"font/woff":     ".woff",
+	"font/woff2":    ".woff2",
+	"font/ttf":      ".ttf",
+	"font/otf":      ".otf",
+}
+
+// DecodeBase64 decodes base64 data, accepting standard and URL-safe alphabets
+// with or without padding. Whitespace is ignored.
+func DecodeBase64(s string, maxBytes int64) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return nil, fmt.Errorf("data is empty")
+	}
+
+	if maxBytes > 0 && int64(base64.StdEncoding.DecodedLen(len(s))) > maxBytes+2 {
+		return nil, fmt.Errorf("data exceeds maximum size of %d bytes", maxBytes)
+	}
+
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	}
+	var lastErr error
+	for _, enc := range encodings {
+		data, err := enc.DecodeString(s)
+		if err == nil {
+			if maxBytes > 0 && int64(len(data)) > maxBytes {
+				return nil, fmt.Errorf("data exceeds maximum size of %d bytes", maxBytes)
+			}
+			return data, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("invalid base64 data: %w", lastErr)
+}
+
+// ParseDataURI decodes an RFC 2397 data URI and returns its payload and
+// declared media type
+func ParseDataURI(uri string, maxBytes int64) ([]byte, string, error) {
+	if !strings.HasPrefix(strings.ToLower(uri), "data:") {
+		return nil, "", fmt.Errorf("data URI must start with 'data:'")
+	}
+	comma := strings.Index(uri, ",")
+	if comma == -1 {
+		return nil, "", fmt.Errorf("data URI is missing ',' separator")
+	}
+
+	header := uri[len("data:"):comma]
+	payload := uri[comma+1:]
+
+	isBase64 := false
+	if strings.HasSuffix(strings.ToLower(header), ";base64") {
+		isBase64 = true
+		header = header[:len(header)-len(";base64")]
+	}
+
+	mimeType := ""
+	if header != "" {
+		parsed, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid data URI media type: %w", err)
+		}
+		mimeType = parsed
+	}
+
+	if isBase64 {
+		data, err := DecodeBase64(payload, maxBytes)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, mimeType, nil
+	}
+
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid data URI payload: %w", err)
+	}
+	if maxBytes > 0 && int64(len(decoded)) > maxBytes {
+		return nil, "", fmt.Errorf("data exceeds maximum size of %d bytes", maxBytes)
+	}
+	return []byte(decoded), mimeType, nil
+}
+
+// SniffContentType detects the content type of data from its leading bytes.
+// SVG is recognised explicitly since net/http reports it as text.
+func SniffContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if strings.HasPrefix(contentType, "text/") {
+		head := data
+		if len(head) > 1024 {
+			head = head[:1024]
+		}
+		if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+	if idx := strings.Index(contentType, ";"); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	return contentType
+}
+
+// ExtensionFor returns the preferred file extension for a content type
+func ExtensionFor(contentType string) string {
+	if ext, ok := extensions[contentType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// ResolveFilename returns a safe base filename for imported data. An empty
+// name is derived from the content hash, and a missing extension is filled
+// in from the content type.
+func ResolveFilename(filename, contentType string, data []byte) (string, error) {
+	filename = strings.TrimSpace(filename)
+	if filename != "" {
+		filename = filepath.Base(filepath.Clean(strings.ReplaceAll(filename, "\\", "/")))
+		if filename == "." || filename == ".." || filename == "/" || strings.HasPrefix(filename, ".") {
+			return "", fmt.Errorf("invalid filename: %s", filename)
+		}
+	}
+
+	if filename == "" {
+		sum := sha256.Sum256(data)
+		filename = "media-" + hex.EncodeToString(sum[:])[:12]
+	}
+
+	if filepath.Ext(filename) == "" {
+		filename += ExtensionFor(contentType)
+	}
+
+	return filename, nil
+}
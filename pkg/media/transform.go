@@ -0,0 +1,258 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// DefaultJPEGQuality is the JPEG quality used when none is specified
+const DefaultJPEGQuality = 90
+
+// CropBox is a crop rectangle in pixels, relative to the oriented image
+type CropBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Transform describes optional processing applied to an image on import.
+// Steps run in order: orientation, crop, resize, encode.
+type Transform struct {
+	MaxDimension int      // Downscale so the longest side is at most this many pixels
+	Crop         *CropBox // Explicit crop rectangle
+	AspectRatio  string   // Center crop to a ratio such as "16:9" or "1.5"
+	AutoOrient   bool     // Apply EXIF orientation even if nothing else changes
+	Format       string   // Output format: png, jpeg or gif (defaults to the source format)
+	Quality      int      // JPEG quality 1-100
+	MaxPixels    int64    // Refuse to decode images with more pixels than this (0 means unlimited)
+}
+
+// IsZero reports whether the transform requests no processing
+func (t *Transform) IsZero() bool {
+	return t == nil || (t.MaxDimension == 0 && t.Crop == nil && t.AspectRatio == "" &&
+		!t.AutoOrient && t.Format == "" && t.Quality == 0)
+}
+
+// Validate checks the transform parameters
+func (t *Transform) Validate() error {
+	if t.MaxDimension < 0 {
+		return fmt.Errorf("max_dimension must be positive")
+	}
+	if t.Crop != nil && t.AspectRatio != "" {
+		return fmt.Errorf("crop and aspect_ratio cannot be combined")
+	}
+	if t.Crop != nil && (t.Crop.X < 0 || t.Crop.Y < 0 || t.Crop.Width <= 0 || t.Crop.Height <= 0) {
+		return fmt.Errorf("crop must have a non-negative origin and positive width and height")
+	}
+	if t.AspectRatio != "" {
+		if _, err := parseAspectRatio(t.AspectRatio); err != nil {
+			return err
+		}
+	}
+	if t.Format != "" && normalizeFormat(t.Format) == "" {
+		return fmt.Errorf("unsupported output format %q: use png, jpeg or gif", t.Format)
+	}
+	if t.Quality < 0 || t.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// Apply decodes a raster image, applies the transform and re-encodes it.
+// Re-encoding drops EXIF metadata, so the EXIF orientation is always baked
+// into the pixels. It returns the new data and its content type.
+func (t *Transform) Apply(data []byte) ([]byte, string, error) {
+	if err := t.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	decoded, sourceFormat, err := decodeLimited(data, t.MaxPixels)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot transform image: %w", err)
+	}
+	if sourceFormat == "gif" {
+		if all, err := gif.DecodeAll(bytes.NewReader(data)); err == nil && len(all.Image) > 1 {
+			return nil, "", fmt.Errorf("cannot transform animated GIFs")
+		}
+	}
+
+	img := applyOrientation(toRGBA(decoded), ReadOrientation(data))
+
+	if t.Crop != nil {
+		img, err = cropImage(img, image.Rect(t.Crop.X, t.Crop.Y, t.Crop.X+t.Crop.Width, t.Crop.Y+t.Crop.Height))
+		if err != nil {
+			return nil, "", err
+		}
+	} else if t.AspectRatio != "" {
+		ratio, _ := parseAspectRatio(t.AspectRatio)
+		img, err = cropImage(img, aspectRect(img.Bounds(), ratio))
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if t.MaxDimension > 0 {
+		img = fitWithin(img, t.MaxDimension)
+	}
+
+	format := normalizeFormat(t.Format)
+	if format == "" {
+		format = normalizeFormat(sourceFormat)
+	}
+	if format == "" {
+		format = "png"
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		quality := t.Quality
+		if quality == 0 {
+			quality = DefaultJPEGQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+
+	return buf.Bytes(), "image/" + format, nil
+}
+
+// normalizeFormat maps format names to the encoders supported by Apply
+func normalizeFormat(format string) string {
+	switch strings.ToLower(format) {
+	case "png":
+		return "png"
+	case "jpeg", "jpg":
+		return "jpeg"
+	case "gif":
+		return "gif"
+	}
+	return ""
+}
+
+// parseAspectRatio parses "W:H" or a decimal ratio
+func parseAspectRatio(value string) (float64, error) {
+	var ratio float64
+	if w, h, ok := strings.Cut(value, ":"); ok {
+		width, errW := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		height, errH := strconv.ParseFloat(strings.TrimSpace(h), 64)
+		if errW != nil || errH != nil || height == 0 {
+			return 0, fmt.Errorf("invalid aspect_ratio %q", value)
+		}
+		ratio = width / height
+	} else {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid aspect_ratio %q", value)
+		}
+		ratio = parsed
+	}
+	if ratio <= 0 {
+		return 0, fmt.Errorf("aspect_ratio must be positive")
+	}
+	return ratio, nil
+}
+
+// aspectRect returns the largest centered rectangle within bounds with the given ratio
+func aspectRect(bounds image.Rectangle, ratio float64) image.Rectangle {
+	width, height := bounds.Dx(), bounds.Dy()
+	if float64(width)/float64(height) > ratio {
+		newWidth := int(float64(height)*ratio + 0.5)
+		x := bounds.Min.X + (width-newWidth)/2
+		return image.Rect(x, bounds.Min.Y, x+newWidth, bounds.Max.Y)
+	}
+	newHeight := int(float64(width)/ratio + 0.5)
+	y := bounds.Min.Y + (height-newHeight)/2
+	return image.Rect(bounds.Min.X, y, bounds.Max.X, y+newHeight)
+}
+
+func cropImage(img *image.RGBA, rect image.Rectangle) (*image.RGBA, error) {
+	rect = rect.Add(img.Bounds().Min)
+	if !rect.In(img.Bounds()) || rect.Empty() {
+		return nil, fmt.Errorf("crop %v is outside the %dx%d image", rect, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst, nil
+}
+
+// fitWithin downscales img so neither side exceeds maxDimension
+func fitWithin(img *image.RGBA, maxDimension int) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width <= maxDimension && height <= maxDimension {
+		return img
+	}
+
+	scale := float64(maxDimension) / float64(max(width, height))
+	newWidth := max(1, int(float64(width)*scale+0.5))
+	newHeight := max(1, int(float64(height)*scale+0.5))
+
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	return dst
+}
+
+// applyOrientation rotates and flips img so it displays upright for the given EXIF orientation
+func applyOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for dy := 0; dy < dstH; dy++ {
+		for dx := 0; dx < dstW; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			si := sy*img.Stride + sx*4
+			di := dy*dst.Stride + dx*4
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
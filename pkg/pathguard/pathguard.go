@@ -0,0 +1,99 @@
+package pathguard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolve returns the absolute, cleaned form of path with symlinks resolved.
+// Paths that don't exist yet are resolved through their deepest existing
+// ancestor, so output files can be checked before they are written.
+func Resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %s: %w", path, err)
+	}
+
+	var missing []string
+	current := abs
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to resolve path %s: %w", path, err)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		missing = append(missing, filepath.Base(current))
+		current = parent
+	}
+}
+
+// Within reports whether path is root itself or lies inside root once both
+// are resolved
+func Within(root, path string) (bool, error) {
+	resolvedRoot, err := Resolve(root)
+	if err != nil {
+		return false, err
+	}
+	resolvedPath, err := Resolve(path)
+	if err != nil {
+		return false, err
+	}
+	return isWithin(resolvedRoot, resolvedPath), nil
+}
+
+// Join joins elems onto root and verifies the result stays inside root
+func Join(root string, elems ...string) (string, error) {
+	joined := filepath.Join(append([]string{root}, elems...)...)
+	ok, err := Within(root, joined)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("path %s escapes %s", joined, root)
+	}
+	return joined, nil
+}
+
+// CheckAllowed resolves path and verifies it lies inside one of roots.
+// An empty roots list allows any path.
+func CheckAllowed(path string, roots []string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path must be absolute: %s", path)
+	}
+	resolved, err := Resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if len(roots) == 0 {
+		return resolved, nil
+	}
+
+	for _, root := range roots {
+		resolvedRoot, err := Resolve(root)
+		if err != nil {
+			continue
+		}
+		if isWithin(resolvedRoot, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("path %s is outside the allowed directories", path)
+}
+
+func isWithin(root, path string) bool {
+	if path == root {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
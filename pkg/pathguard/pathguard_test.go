@@ -0,0 +1,154 @@
+package pathguard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setup creates an allowed root holding a file, a directory and symlinks
+// pointing inside and outside of it, next to a directory outside the root
+func setup(t *testing.T) (root, outside string) {
+	t.Helper()
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = filepath.Join(base, "root")
+	outside = filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "media"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{filepath.Join(root, "media", "a.png"), filepath.Join(outside, "secret.txt")} {
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "escape"):       outside,
+		filepath.Join(root, "escape.txt"):   filepath.Join(outside, "secret.txt"),
+		filepath.Join(root, "inside"):       filepath.Join(root, "media"),
+		filepath.Join(outside, "into-root"): root,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, outside
+}
+
+func TestCheckAllowed(t *testing.T) {
+	root, outside := setup(t)
+	tests := []struct {
+		name string
+		path string
+		want string // Resolved path, or "" when the path must be refused
+	}{
+		{"root itself", root, root},
+		{"file in root", filepath.Join(root, "media", "a.png"), filepath.Join(root, "media", "a.png")},
+		{"missing file in root", filepath.Join(root, "new", "out.png"), filepath.Join(root, "new", "out.png")},
+		{"dot dot inside root", filepath.Join(root, "media") + "/../media/a.png", filepath.Join(root, "media", "a.png")},
+		{"dot dot out of root", root + "/../outside/secret.txt", ""},
+		{"dot dot past filesystem root", root + "/../../../../../../etc/passwd", ""},
+		{"absolute path outside", filepath.Join(outside, "secret.txt"), ""},
+		{"sibling with root as prefix", root + "-other/file.png", ""},
+		{"symlinked dir escaping", filepath.Join(root, "escape", "secret.txt"), ""},
+		{"symlinked file escaping", filepath.Join(root, "escape.txt"), ""},
+		{"missing file below escaping symlink", filepath.Join(root, "escape", "new.png"), ""},
+		{"symlink staying inside", filepath.Join(root, "inside", "a.png"), filepath.Join(root, "media", "a.png")},
+		{"symlink from outside into root", filepath.Join(outside, "into-root", "media", "a.png"), filepath.Join(root, "media", "a.png")},
+		{"relative path", "media/a.png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckAllowed(tt.path, []string{root})
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("CheckAllowed(%s) = %s, want an error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckAllowed(%s): %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("CheckAllowed(%s) = %s, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAllowedNoRoots(t *testing.T) {
+	_, outside := setup(t)
+	path := filepath.Join(outside, "secret.txt")
+	if got, err := CheckAllowed(path, nil); err != nil || got != path {
+		t.Errorf("CheckAllowed(%s, nil) = %s, %v; want any absolute path allowed", path, got, err)
+	}
+	if _, err := CheckAllowed("secret.txt", nil); err == nil {
+		t.Error("CheckAllowed of a relative path with no roots succeeded")
+	}
+}
+
+func TestCheckAllowedSeveralRoots(t *testing.T) {
+	root, outside := setup(t)
+	path := filepath.Join(outside, "secret.txt")
+	if _, err := CheckAllowed(path, []string{root, outside}); err != nil {
+		t.Errorf("CheckAllowed(%s) with both roots: %v", path, err)
+	}
+	if _, err := CheckAllowed(path, []string{filepath.Join(root, "missing"), root}); err == nil {
+		t.Errorf("CheckAllowed(%s) outside both roots succeeded", path)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	root, _ := setup(t)
+	tests := []struct {
+		elems []string
+		ok    bool
+	}{
+		{[]string{"media", "a.png"}, true},
+		{[]string{"post-1a2b", "index.html"}, true},
+		{[]string{"..", "outside", "secret.txt"}, false},
+		{[]string{"media", "..", "..", "outside"}, false},
+		{[]string{"escape", "secret.txt"}, false},
+		{[]string{"inside", "a.png"}, true},
+	}
+	for _, tt := range tests {
+		_, err := Join(root, tt.elems...)
+		if tt.ok && err != nil {
+			t.Errorf("Join(%v): %v", tt.elems, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("Join(%v) succeeded, want it refused", tt.elems)
+		}
+	}
+}
+
+func TestWithin(t *testing.T) {
+	root, outside := setup(t)
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{root, true},
+		{root + "/", true},
+		{filepath.Join(root, "media"), true},
+		{filepath.Join(outside, "into-root"), true},
+		{filepath.Join(root, "escape"), false},
+		{outside, false},
+		{root + "-other", false},
+		{filepath.Dir(root), false},
+	}
+	for _, tt := range tests {
+		got, err := Within(root, tt.path)
+		if err != nil {
+			t.Fatalf("Within(%s): %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("Within(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
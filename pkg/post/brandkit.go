@@ -0,0 +1,372 @@
+package post
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"html_image_creator/pkg/media"
+
+	"github.com/gosimple/slug"
+)
+
+// BrandStyleID is the id of the style element holding a brand kit's CSS
+// custom properties in a post's HTML
+const BrandStyleID = "brand-kit"
+
+// BrandLogoPrefix starts the filenames of logos stored in a brand kit
+const BrandLogoPrefix = "brand-"
+
+// BrandLogoDir is the directory under media/ holding the logos of a post's
+// brand kit. Each logo is stored as media/brand/<name>, without an extension,
+// so its path stays the same when the logo changes format. Only files whose
+// manifest entry names a brand logo are replaced or removed by ApplyBrandKit.
+const BrandLogoDir = "brand"
+
+// HistoryBrandKit is the history action recorded when a brand kit is applied or removed
+const HistoryBrandKit = "brand_kit"
+
+var (
+	brandTokenPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	brandStylePattern = regexp.MustCompile(`(?s)<style id="` + BrandStyleID + `">.*?</style>\n?`)
+	headTagPattern    = regexp.MustCompile(`(?i)<head(\s[^>]*)?>`)
+)
+
+// CreateBrandKit creates a named brand kit. Its ID is derived from the name.
+func (s *Service) CreateBrandKit(name string, update BrandKitUpdate) (*BrandKit, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("brand kit name cannot be empty")
+	}
+	id := slug.Make(name)
+	if id == "" {
+		return nil, fmt.Errorf("brand kit name %q has no usable characters", name)
+	}
+
+	kits, err := s.storage.ReadBrandKits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read brand kits: %w", err)
+	}
+	for _, k := range kits {
+		if k.ID == id {
+			return nil, fmt.Errorf("brand kit %s already exists", id)
+		}
+	}
+
+	now := time.Now()
+	kit := &BrandKit{ID: id, Name: name, CreatedAt: now, UpdatedAt: now}
+	update.Name = nil
+	logos, removed, err := s.applyBrandKitUpdate(kit, update)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.WriteBrandKit(kit, logos, removed); err != nil {
+		return nil, fmt.Errorf("failed to create brand kit: %w", err)
+	}
+	return kit, nil
+}
+
+// UpdateBrandKit edits a brand kit and re-applies it to every post using it.
+// It returns the kit and the IDs of the updated posts.
+func (s *Service) UpdateBrandKit(kitID string, update BrandKitUpdate) (*BrandKit, []string, error) {
+	if update.Name != nil && strings.TrimSpace(*update.Name) == "" {
+		return nil, nil, fmt.Errorf("brand kit name cannot be empty")
+	}
+
+	kit, err := s.GetBrandKit(kitID)
+	if err != nil {
+		return nil, nil, err
+	}
+	logos, removed, err := s.applyBrandKitUpdate(kit, update)
+	if err != nil {
+		return nil, nil, err
+	}
+	kit.UpdatedAt = time.Now()
+	if err := s.storage.WriteBrandKit(kit, logos, removed); err != nil {
+		return nil, nil, fmt.Errorf("failed to update brand kit: %w", err)
+	}
+
+	posts, err := s.storage.ListPosts()
+	if err != nil {
+		return kit, nil, fmt.Errorf("brand kit saved but failed to list posts: %w", err)
+	}
+	synced := []string{}
+	for _, info := range posts {
+		p, err := s.storage.GetPost(info.ID)
+		if err != nil || p.BrandKit != kit.ID {
+			continue
+		}
+		if err := s.brandPost(p, kit); err != nil {
+			return kit, synced, fmt.Errorf("brand kit saved but failed to update post %s: %w", p.ID, err)
+		}
+		synced = append(synced, p.ID)
+	}
+	return kit, synced, nil
+}
+
+// GetBrandKit returns the brand kit with the given ID
+func (s *Service) GetBrandKit(kitID string) (*BrandKit, error) {
+	kits, err := s.storage.ReadBrandKits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read brand kits: %w", err)
+	}
+	for _, k := range kits {
+		if k.ID == kitID {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("brand kit %s does not exist", kitID)
+}
+
+// ListBrandKits returns all brand kits
+func (s *Service) ListBrandKits() ([]*BrandKit, error) {
+	kits, err := s.storage.ReadBrandKits()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list brand kits: %w", err)
+	}
+	return kits, nil
+}
+
+// ApplyBrandKit assigns a brand kit to a post: the kit's tokens are written
+// to a :root style block at the top of the HTML and its logos are copied to
+// media/brand/<name>. An empty kitID removes the style block and logos.
+func (s *Service) ApplyBrandKit(postID, kitID string) (*ImagePost, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	var kit *BrandKit
+	if kitID != "" {
+		if kit, err = s.GetBrandKit(kitID); err != nil {
+			return nil, err
+		}
+	}
+
+	p.History = append(p.History, &HistoryEntry{
+		Action:  HistoryBrandKit,
+		At:      time.Now(),
+		Details: map[string]interface{}{"from": p.BrandKit, "to": kitID},
+	})
+	if err := s.brandPost(p, kit); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// BrandCSS returns the :root rule declaring a kit's tokens as CSS custom
+// properties: --color-*, --font-*, --spacing-* and --logo-* (a url()).
+func BrandCSS(kit *BrandKit) string {
+	var b strings.Builder
+	b.WriteString(":root {\n")
+	for _, group := range []struct {
+		prefix string
+		tokens map[string]string
+	}{
+		{"--color-", kit.Palette},
+		{"--font-", kit.Typography},
+		{"--spacing-", kit.Spacing},
+	} {
+		names := make([]string, 0, len(group.tokens))
+		for name := range group.tokens {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "  %s%s: %s;\n", group.prefix, name, group.tokens[name])
+		}
+	}
+	for _, logo := range kit.Logos {
+		fmt.Fprintf(&b, "  --logo-%s: url(\"%s\");\n", logo.Name, BrandLogoPath(logo.Name))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// BrandLogoPath returns the path, relative to the post directory, of the
+// brand kit logo with the given name
+func BrandLogoPath(name string) string {
+	return path.Join("media", BrandLogoDir, name)
+}
+
+// brandPost brings a post in line with kit, or removes its branding when kit
+// is nil, and saves it
+func (s *Service) brandPost(p *ImagePost, kit *BrandKit) error {
+	wanted := make(map[string]bool)
+	if kit != nil {
+		for _, logo := range kit.Logos {
+			data, err := s.storage.ReadBrandLogo(kit.ID, logo.Filename)
+			if err != nil {
+				return fmt.Errorf("failed to read logo %s: %w", logo.Name, err)
+			}
+			info := &MediaInfo{
+				BrandLogo:   logo.Name,
+				ContentType: logo.ContentType,
+				Size:        logo.Size,
+				Width:       logo.Width,
+				Height:      logo.Height,
+				AddedAt:     time.Now(),
+			}
+			if err := s.storage.WriteMediaFile(p.ID, info, data); err != nil {
+				return fmt.Errorf("failed to copy logo %s: %w", logo.Name, err)
+			}
+			wanted[logo.Name] = true
+		}
+	}
+
+	existing, err := s.storage.ListMedia(p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list media: %w", err)
+	}
+	for _, info := range existing {
+		if info.BrandLogo != "" && !wanted[info.BrandLogo] {
+			if err := s.storage.DeleteMediaFile(p.ID, info.Filename); err != nil {
+				return fmt.Errorf("failed to remove old logo %s: %w", info.Filename, err)
+			}
+		}
+	}
+
+	p.HTMLContent = applyBrandStyle(p.HTMLContent, kit)
+	p.BrandKit = ""
+	if kit != nil {
+		p.BrandKit = kit.ID
+	}
+	p.UpdatedAt = time.Now()
+	if err := s.storage.UpdatePost(p); err != nil {
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+	return nil
+}
+
+// applyBrandStyle replaces the brand style block of htmlContent with one for
+// kit, or removes it when kit is nil. A new block goes right after <head>, or
+// at the very start, so the post's own styles can override the tokens.
+func applyBrandStyle(htmlContent string, kit *BrandKit) string {
+	htmlContent = brandStylePattern.ReplaceAllString(htmlContent, "")
+	if kit == nil {
+		return htmlContent
+	}
+
+	return prependToHead(htmlContent, `<style id="`+BrandStyleID+`">`+"\n"+BrandCSS(kit)+"</style>\n")
+}
+
+// prependToHead inserts block right after <head>, or at the very start of
+// htmlContent when it has no head
+func prependToHead(htmlContent, block string) string {
+	if loc := headTagPattern.FindStringIndex(htmlContent); loc != nil {
+		return htmlContent[:loc[1]] + "\n" + block + strings.TrimPrefix(htmlContent[loc[1]:], "\n")
+	}
+	return block + htmlContent
+}
+
+// applyBrandKitUpdate validates update and applies it to kit. It returns the
+// logo files to write, keyed by filename, and the filenames to delete.
+func (s *Service) applyBrandKitUpdate(kit *BrandKit, update BrandKitUpdate) (map[string][]byte, []string, error) {
+	if update.Name != nil {
+		kit.Name = *update.Name
+	}
+	if update.Description != nil {
+		kit.Description = *update.Description
+	}
+
+	var err error
+	if kit.Palette, err = mergeBrandTokens("palette", kit.Palette, update.Palette); err != nil {
+		return nil, nil, err
+	}
+	if kit.Typography, err = mergeBrandTokens("typography", kit.Typography, update.Typography); err != nil {
+		return nil, nil, err
+	}
+	if kit.Spacing, err = mergeBrandTokens("spacing", kit.Spacing, update.Spacing); err != nil {
+		return nil, nil, err
+	}
+
+	logos := make(map[string][]byte)
+	var removed []string
+	names := make([]string, 0, len(update.Logos))
+	for name := range update.Logos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !brandTokenPattern.MatchString(name) {
+			return nil, nil, fmt.Errorf("logo name %q must use lowercase letters, digits and hyphens", name)
+		}
+
+		// Drop the current logo of that name; a replacement may have another extension
+		kept := kit.Logos[:0]
+		for _, logo := range kit.Logos {
+			if logo.Name == name {
+				removed = append(removed, logo.Filename)
+			} else {
+				kept = append(kept, logo)
+			}
+		}
+		kit.Logos = kept
+
+		sourcePath := update.Logos[name]
+		if sourcePath == "" {
+			continue
+		}
+		data, err := s.readMediaSource(sourcePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("logo %s: %w", name, err)
+		}
+		probed, err := s.mediaPolicy.Inspect(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid logo file %s: %w", sourcePath, err)
+		}
+		logo := &BrandLogo{
+			Name:        name,
+			Filename:    BrandLogoPrefix + name + media.ExtensionFor(probed.ContentType),
+			ContentType: probed.ContentType,
+			Size:        probed.Size,
+			Width:       probed.Width,
+			Height:      probed.Height,
+		}
+		kit.Logos = append(kit.Logos, logo)
+		logos[logo.Filename] = data
+	}
+	sort.Slice(kit.Logos, func(i, j int) bool { return kit.Logos[i].Name < kit.Logos[j].Name })
+
+	// A logo replaced by one of the same type keeps its filename
+	kept := removed[:0]
+	for _, filename := range removed {
+		if _, ok := logos[filename]; !ok {
+			kept = append(kept, filename)
+		}
+	}
+	return logos, kept, nil
+}
+
+// mergeBrandTokens applies token edits to current. Names must be valid CSS
+// identifier parts and values may not break out of the declaration.
+func mergeBrandTokens(group string, current, edits map[string]string) (map[string]string, error) {
+	if len(edits) == 0 {
+		return current, nil
+	}
+	merged := make(map[string]string, len(current)+len(edits))
+	for name, value := range current {
+		merged[name] = value
+	}
+	for name, value := range edits {
+		if !brandTokenPattern.MatchString(name) {
+			return nil, fmt.Errorf("%s name %q must use lowercase letters, digits and hyphens", group, name)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			delete(merged, name)
+			continue
+		}
+		if strings.ContainsAny(value, ";{}<>\r\n") {
+			return nil, fmt.Errorf("%s value of %s must not contain ; { } < > or line breaks", group, name)
+		}
+		merged[name] = value
+	}
+	return merged, nil
+}
@@ -0,0 +1,77 @@
+package post
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"html_image_creator/pkg/layout"
+)
+
+// ClonePost copies a post's HTML and media into a new post called name.
+// Width and height of zero keep the source dimensions. When rescale is set
+// and the dimensions change, px lengths in the HTML's CSS are scaled by the
+// largest uniform factor that fits the old canvas into the new one.
+func (s *Service) ClonePost(postID, name string, width, height int, rescale bool) (*ImagePost, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("post name cannot be empty")
+	}
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("width and height must be positive integers")
+	}
+
+	source, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if width == 0 {
+		width = source.Width
+	}
+	if height == 0 {
+		height = source.Height
+	}
+
+	files, err := s.storage.ReadPostFiles(postID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read post files: %w", err)
+	}
+
+	now := time.Now()
+	for _, f := range files {
+		switch f.Path {
+		case "index.html":
+			if rescale {
+				factor := layout.ProportionalFactor(source.Width, source.Height, width, height)
+				f.Data = []byte(layout.ScalePx(string(f.Data), factor))
+			}
+		case "metadata.json":
+			// Decode into the stored metadata so fields this code doesn't set are carried over
+			var metadata Metadata
+			if err := json.Unmarshal(f.Data, &metadata); err != nil {
+				return nil, fmt.Errorf("invalid metadata.json: %w", err)
+			}
+			metadata.Name = name
+			metadata.Width = width
+			metadata.Height = height
+			metadata.CreatedAt = now
+			metadata.UpdatedAt = now
+			metadata.History = nil
+			metadata.Exports = nil
+			data, err := json.MarshalIndent(&metadata, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			f.Data = data
+		}
+	}
+
+	cloneID := GeneratePostID(name, s.storage.PostExists)
+	if err := s.storage.WritePostFiles(cloneID, files); err != nil {
+		return nil, fmt.Errorf("failed to write post files: %w", err)
+	}
+
+	return s.GetPost(cloneID)
+}
@@ -0,0 +1,335 @@
+package post
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ComponentTag is the element that includes a component in a post's HTML
+const ComponentTag = "x-component"
+
+// maxComponentDepth limits nested components, catching components that include themselves
+const maxComponentDepth = 10
+
+var (
+	componentNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	componentParamPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// RegisterComponent creates or replaces a component. The HTML is an
+// html/template that may reference the declared parameters and .content, the
+// inner HTML of the <x-component> tag; values are escaped for their context.
+func (s *Service) RegisterComponent(c *Component) (*Component, error) {
+	if _, err := parseComponent(c); err != nil {
+		return nil, err
+	}
+
+	components, err := s.storage.ReadComponents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read components: %w", err)
+	}
+	now := time.Now()
+	c.CreatedAt = now
+	for _, existing := range components {
+		if existing.Name == c.Name {
+			c.CreatedAt = existing.CreatedAt
+		}
+	}
+	c.UpdatedAt = now
+
+	if err := s.storage.WriteComponent(c); err != nil {
+		return nil, fmt.Errorf("failed to register component: %w", err)
+	}
+	return c, nil
+}
+
+// ListComponents returns all components sorted by name
+func (s *Service) ListComponents() ([]*Component, error) {
+	components, err := s.storage.ReadComponents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list components: %w", err)
+	}
+	return components, nil
+}
+
+// PreviewComponent renders a component with params and inner content and
+// returns the HTML with the component's style block
+func (s *Service) PreviewComponent(name string, params map[string]string, content string) (string, error) {
+	e, err := s.newComponentExpander()
+	if err != nil {
+		return "", err
+	}
+	attrs := map[string]string{"name": name}
+	for key, value := range params {
+		if key == "name" {
+			return "", fmt.Errorf("name is not a parameter")
+		}
+		attrs[key] = value
+	}
+	rendered, err := e.render(attrs, content, 0)
+	if err != nil {
+		return "", err
+	}
+	return e.withStyles(rendered), nil
+}
+
+// expandComponents replaces the <x-component> tags of htmlContent with the
+// rendered components and adds their styles. HTML without components is
+// returned unchanged.
+func (s *Service) expandComponents(htmlContent string) (string, error) {
+	if !strings.Contains(strings.ToLower(htmlContent), "<"+ComponentTag) {
+		return htmlContent, nil
+	}
+	e, err := s.newComponentExpander()
+	if err != nil {
+		return "", err
+	}
+	expanded, err := e.expand(htmlContent, 0)
+	if err != nil {
+		return "", err
+	}
+	return e.withStyles(expanded), nil
+}
+
+// componentExpander renders the components of one HTML document
+type componentExpander struct {
+	components map[string]*Component
+	templates  map[string]*template.Template
+	used       map[string]bool
+}
+
+func (s *Service) newComponentExpander() (*componentExpander, error) {
+	components, err := s.storage.ReadComponents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read components: %w", err)
+	}
+	e := &componentExpander{
+		components: make(map[string]*Component, len(components)),
+		templates:  make(map[string]*template.Template),
+		used:       make(map[string]bool),
+	}
+	for _, c := range components {
+		e.components[c.Name] = c
+	}
+	return e, nil
+}
+
+// expand renders every <x-component> element of src. Everything else is
+// copied byte for byte.
+func (e *componentExpander) expand(src string, depth int) (string, error) {
+	if depth > maxComponentDepth {
+		return "", fmt.Errorf("components are nested more than %d levels deep; does a component include itself?", maxComponentDepth)
+	}
+
+	var out strings.Builder
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				return out.String(), nil
+			}
+			return "", fmt.Errorf("failed to parse HTML: %w", z.Err())
+		}
+		raw := string(z.Raw())
+		tok := z.Token()
+		if tok.Data != ComponentTag {
+			out.WriteString(raw)
+			continue
+		}
+
+		switch tt {
+		case html.EndTagToken:
+			return "", fmt.Errorf("unexpected </%s> without an opening tag", ComponentTag)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			attrs := make(map[string]string, len(tok.Attr))
+			for _, a := range tok.Attr {
+				attrs[a.Key] = a.Val
+			}
+			var content string
+			if tt == html.StartTagToken {
+				var err error
+				if content, err = componentContent(z); err != nil {
+					return "", err
+				}
+			}
+			rendered, err := e.render(attrs, content, depth)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(rendered)
+		default:
+			out.WriteString(raw)
+		}
+	}
+}
+
+// componentContent returns the raw inner HTML of the <x-component> element
+// whose start tag was just read, consuming its end tag
+func componentContent(z *html.Tokenizer) (string, error) {
+	var content strings.Builder
+	open := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return "", fmt.Errorf("<%s> is missing its </%s>", ComponentTag, ComponentTag)
+		}
+		raw := string(z.Raw())
+		if name, _ := z.TagName(); string(name) == ComponentTag {
+			switch tt {
+			case html.StartTagToken:
+				open++
+			case html.EndTagToken:
+				if open--; open == 0 {
+					return content.String(), nil
+				}
+			}
+		}
+		content.WriteString(raw)
+	}
+}
+
+// render expands one component from its tag attributes and inner content
+func (e *componentExpander) render(attrs map[string]string, content string, depth int) (string, error) {
+	name := attrs["name"]
+	if name == "" {
+		return "", fmt.Errorf("<%s> requires a name attribute", ComponentTag)
+	}
+	c, ok := e.components[name]
+	if !ok {
+		return "", fmt.Errorf("unknown component %q", name)
+	}
+
+	declared := make(map[string]bool, len(c.Params))
+	for _, p := range c.Params {
+		declared[p.Name] = true
+	}
+	var unknown []string
+	for key := range attrs {
+		if key != "name" && !declared[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("component %s does not accept parameters: %s", name, strings.Join(unknown, ", "))
+	}
+
+	data := make(map[string]interface{}, len(c.Params)+1)
+	var missing []string
+	for _, p := range c.Params {
+		value, ok := attrs[p.Name]
+		if !ok {
+			if p.Required {
+				missing = append(missing, p.Name)
+			}
+			value = p.Default
+		}
+		data[p.Name] = value
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("component %s is missing required parameters: %s", name, strings.Join(missing, ", "))
+	}
+
+	inner, err := e.expand(content, depth+1)
+	if err != nil {
+		return "", err
+	}
+	// Inner content is the post's own markup, inserted as is
+	data["content"] = template.HTML(inner)
+
+	t, ok := e.templates[name]
+	if !ok {
+		if t, err = parseComponent(c); err != nil {
+			return "", err
+		}
+		e.templates[name] = t
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render component %s: %w", name, err)
+	}
+	e.used[name] = true
+
+	// A component's HTML may include other components
+	return e.expand(buf.String(), depth+1)
+}
+
+// withStyles adds a style block for each component used that has CSS,
+// replacing blocks left by an earlier expansion
+func (e *componentExpander) withStyles(htmlContent string) string {
+	names := make([]string, 0, len(e.used))
+	for name := range e.used {
+		if e.components[name].CSS != "" {
+			names = append(names, name)
+		}
+	}
+	// Prepending in reverse keeps the blocks in name order
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, name := range names {
+		id := "component-" + name
+		existing := regexp.MustCompile(`(?s)<style id="` + regexp.QuoteMeta(id) + `">.*?</style>\n?`)
+		htmlContent = existing.ReplaceAllString(htmlContent, "")
+		htmlContent = prependToHead(htmlContent, `<style id="`+id+`">`+"\n"+strings.TrimSpace(e.components[name].CSS)+"\n</style>\n")
+	}
+	return htmlContent
+}
+
+// parseComponent validates a component and parses its HTML
+func parseComponent(c *Component) (*template.Template, error) {
+	if !componentNamePattern.MatchString(c.Name) {
+		return nil, fmt.Errorf("component name %q must use lowercase letters, digits and hyphens", c.Name)
+	}
+	if strings.TrimSpace(c.HTML) == "" {
+		return nil, fmt.Errorf("component HTML cannot be empty")
+	}
+	if strings.Contains(strings.ToLower(c.CSS), "</style") {
+		return nil, fmt.Errorf("component CSS must not contain </style>")
+	}
+
+	declared := map[string]bool{"content": true}
+	for _, p := range c.Params {
+		switch {
+		case !componentParamPattern.MatchString(p.Name):
+			return nil, fmt.Errorf("parameter name %q must use lowercase letters, digits and underscores", p.Name)
+		case p.Name == "name" || p.Name == "content":
+			return nil, fmt.Errorf("parameter name %q is reserved", p.Name)
+		case declared[p.Name]:
+			return nil, fmt.Errorf("duplicate parameter %q", p.Name)
+		}
+		declared[p.Name] = true
+	}
+
+	t, err := template.New(c.Name).Parse(c.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("invalid component HTML: %w", err)
+	}
+	var undeclared []string
+	for name := range templateFields(t.Tree.Root) {
+		if !declared[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return nil, fmt.Errorf("component references undeclared parameters: %s", strings.Join(undeclared, ", "))
+	}
+
+	// Escaping problems only surface on execution, so try it with empty values
+	data := map[string]interface{}{"content": template.HTML("")}
+	for _, p := range c.Params {
+		data[p.Name] = ""
+	}
+	if err := t.Execute(io.Discard, data); err != nil {
+		return nil, fmt.Errorf("invalid component HTML: %w", err)
+	}
+	return t, nil
+}
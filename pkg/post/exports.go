@@ -0,0 +1,99 @@
+package post
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// MaxExportRecords is the number of most recent exports kept per post
+const MaxExportRecords = 50
+
+// HashHTML returns the content hash recorded for rendered HTML
+func HashHTML(html string) string {
+	sum := sha256.Sum256([]byte(html))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// HashMedia returns a digest of a post's media files computed from their
+// manifest entries, so it changes whenever a file is added, replaced or
+// removed without reading the files. Entries recorded before content hashes
+// were kept contribute their size and time added instead.
+func HashMedia(files []*MediaInfo) string {
+	sorted := make([]*MediaInfo, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Filename < sorted[j].Filename })
+
+	h := sha256.New()
+	for _, info := range sorted {
+		content := info.SHA256
+		if content == "" {
+			content = fmt.Sprintf("%d@%d", info.Size, info.AddedAt.UnixNano())
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", info.Filename, content)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// LatestExport returns the most recent export of the post, or nil if it has never been exported
+func (p *ImagePost) LatestExport() *ExportRecord {
+	if len(p.Exports) == 0 {
+		return nil
+	}
+	return p.Exports[len(p.Exports)-1]
+}
+
+// ExportStale reports whether the post would now render differently from
+// its latest export: its HTML, canvas size or media, given as the HashMedia
+// digest mediaDigest, changed since. Metadata edits don't make an export
+// stale. Exports recorded without a media digest fall back to comparing
+// UpdatedAt. Posts that were never exported are not considered stale.
+func (p *ImagePost) ExportStale(mediaDigest string) bool {
+	latest := p.LatestExport()
+	if latest == nil {
+		return false
+	}
+	if latest.HTMLHash != HashHTML(p.HTMLContent) {
+		return true
+	}
+	if latest.Width != 0 && (latest.Width != p.Width || latest.Height != p.Height) {
+		return true
+	}
+	if latest.MediaDigest == "" {
+		return p.UpdatedAt.After(latest.ExportedAt)
+	}
+	return latest.MediaDigest != mediaDigest
+}
+
+// MediaDigest returns the HashMedia digest of a post's current media
+func (s *Service) MediaDigest(postID string) (string, error) {
+	files, err := s.ListMedia(postID)
+	if err != nil {
+		return "", err
+	}
+	return HashMedia(files), nil
+}
+
+// RecordExport appends an export to the post's export history. UpdatedAt is
+// left unchanged so the new export is not itself reported as stale.
+func (s *Service) RecordExport(postID string, record *ExportRecord) error {
+	if !ValidatePostID(postID) {
+		return fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return fmt.Errorf("failed to get post: %w", err)
+	}
+
+	p.Exports = append(p.Exports, record)
+	if len(p.Exports) > MaxExportRecords {
+		p.Exports = p.Exports[len(p.Exports)-MaxExportRecords:]
+	}
+
+	if err := s.storage.UpdatePost(p); err != nil {
+		return fmt.Errorf("failed to record export: %w", err)
+	}
+	return nil
+}
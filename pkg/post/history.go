@@ -0,0 +1,45 @@
+package post
+
+import "fmt"
+
+// HistoryCheckout is the history action recorded by CheckoutRevision
+const HistoryCheckout = "checkout"
+
+// PostLog returns up to limit recorded revisions of a post, newest first. An
+// empty postID returns the revisions of the whole storage.
+func (s *Service) PostLog(postID string, limit int) ([]*Revision, error) {
+	if postID != "" && !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	revisions, err := s.storage.PostLog(postID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read post log: %w", err)
+	}
+	return revisions, nil
+}
+
+// CheckoutRevision restores a post to its state at a recorded revision. A
+// post deleted since the revision is recreated.
+func (s *Service) CheckoutRevision(postID, revision string) (*ImagePost, string, error) {
+	if !ValidatePostID(postID) {
+		return nil, "", fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if revision == "" {
+		return nil, "", fmt.Errorf("revision cannot be empty")
+	}
+
+	hash, err := s.storage.CheckoutRevision(postID, revision)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to check out revision: %w", err)
+	}
+
+	p, err := s.GetPost(postID)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, hash, nil
+}
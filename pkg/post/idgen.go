@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/gosimple/slug"
@@ -14,13 +15,18 @@ const (
 	SuffixLength  = 4
 )
 
+// postIDPattern matches GeneratePostID output: a lowercase slug of at most
+// MaxSlugLength characters followed by a 4 or 8 character hex suffix.
+// Dots, slashes and other path characters can never appear.
+var postIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}-[0-9a-f]{4}([0-9a-f]{4})?$`)
+
 // GeneratePostID creates a unique post ID from a name
 // Format: slugified-name-abc1
 func GeneratePostID(name string, existsFunc func(string) bool) string {
 	slugified := slug.Make(name)
 
 	if len(slugified) > MaxSlugLength {
-		slugified = slugified[:MaxSlugLength]
+		slugified = strings.TrimRight(slugified[:MaxSlugLength], "-_")
 	}
 
 	if slugified == "" {
@@ -47,16 +53,7 @@ func generateRandomSuffix() string {
 	return hex.EncodeToString(bytes)[:SuffixLength]
 }
 
-// ValidatePostID checks if a post ID is valid
+// ValidatePostID checks if a post ID matches the format produced by GeneratePostID
 func ValidatePostID(id string) bool {
-	if id == "" {
-		return false
-	}
-	if !strings.Contains(id, "-") {
-		return false
-	}
-	if len(id) > MaxSlugLength+SuffixLength+1 {
-		return false
-	}
-	return true
+	return postIDPattern.MatchString(id)
 }
@@ -0,0 +1,77 @@
+package post
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePostID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"post-1a2b", true},
+		{"my-first-post-0f9e", true},
+		{"a-0000", true},
+		{"snake_case-abcd", true},
+		{"x-1a2b3c4d", true},
+		{"123-abcd", true},
+		{strings.Repeat("a", 30) + "-abcd", true},
+
+		{"", false},
+		{"post", false},
+		{"-abcd", false},
+		{"post-", false},
+		{"post-1a2", false},
+		{"post-1a2b3", false},
+		{"post-1a2b3c4d5", false},
+		{"post-1A2B", false},
+		{"post-wxyz", false},
+		{"Post-1a2b", false},
+		{"_post-1a2b", false},
+		{strings.Repeat("a", 31) + "-abcd", false},
+		{"../post-1a2b", false},
+		{"..-abcd", false},
+		{"post/x-1a2b", false},
+		{"post\\x-1a2b", false},
+		{"post.x-1a2b", false},
+		{".trash-1a2b", false},
+		{"/etc/passwd-1a2b", false},
+		{"post-1a2b\n", false},
+		{"post 1-1a2b", false},
+		{"café-1a2b", false},
+		{"post-1a2b/index.html", false},
+	}
+	for _, tt := range tests {
+		if got := ValidatePostID(tt.id); got != tt.valid {
+			t.Errorf("ValidatePostID(%q) = %v, want %v", tt.id, got, tt.valid)
+		}
+	}
+}
+
+func TestGeneratePostIDIsValid(t *testing.T) {
+	names := []string{
+		"Summer Sale",
+		"",
+		"../../etc/passwd",
+		"Ünïcödé Nämé",
+		"!!!",
+		"a/b\\c.d",
+		strings.Repeat("very long name ", 10),
+	}
+	never := func(string) bool { return false }
+	for _, name := range names {
+		id := GeneratePostID(name, never)
+		if !ValidatePostID(id) {
+			t.Errorf("GeneratePostID(%q) = %q, which ValidatePostID rejects", name, id)
+		}
+	}
+}
+
+func TestGeneratePostIDLongSuffix(t *testing.T) {
+	always := func(string) bool { return true }
+	id := GeneratePostID("Taken", always)
+	if !ValidatePostID(id) || len(id) != len("taken-")+2*SuffixLength {
+		t.Errorf("GeneratePostID with every short ID taken = %q, want a valid ID with an 8 character suffix", id)
+	}
+}
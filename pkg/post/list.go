@@ -0,0 +1,213 @@
+package post
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	nonTextBlockPattern = regexp.MustCompile(`(?is)<(script|style|head)\b[^>]*>.*?</(script|style|head)\s*>`)
+	tagPattern          = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// listCursor is the decoded form of PostPage.NextCursor. It records the
+// sort position of the last post returned so pages stay stable when posts
+// are added or removed between requests.
+type listCursor struct {
+	SortBy    string    `json:"s"`
+	Ascending bool      `json:"a"`
+	ID        string    `json:"id"`
+	Name      string    `json:"n,omitempty"`
+	Time      time.Time `json:"t"`
+}
+
+// ListPosts returns the posts matching opts, sorted and paginated
+func (s *Service) ListPosts(opts ListOptions) (*PostPage, error) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = SortUpdated
+	}
+	if sortBy != SortCreated && sortBy != SortUpdated && sortBy != SortName {
+		return nil, fmt.Errorf("invalid sort field %q: use %s, %s or %s", sortBy, SortCreated, SortUpdated, SortName)
+	}
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("limit cannot be negative")
+	}
+
+	var after *PostInfo
+	if opts.Cursor != "" {
+		cursor, err := decodeListCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.SortBy != sortBy || cursor.Ascending != opts.Ascending {
+			return nil, fmt.Errorf("cursor was created with a different sort order")
+		}
+		after = &PostInfo{ID: cursor.ID, Name: cursor.Name, CreatedAt: cursor.Time, UpdatedAt: cursor.Time}
+	}
+
+	posts, err := s.storage.ListPosts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
+	}
+
+	terms := strings.Fields(strings.ToLower(opts.Query))
+	tags := make([]string, 0, len(opts.Tags))
+	for _, tag := range opts.Tags {
+		tags = append(tags, strings.ToLower(strings.TrimSpace(tag)))
+	}
+
+	var matched []*PostInfo
+	for _, p := range posts {
+		if !opts.matches(p, tags) {
+			continue
+		}
+		if len(terms) > 0 && !s.matchesQuery(p, terms) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+
+	less := func(a, b *PostInfo) bool {
+		c := comparePosts(a, b, sortBy)
+		if opts.Ascending {
+			return c < 0
+		}
+		return c > 0
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	page := &PostPage{Total: len(matched)}
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool { return less(after, matched[i]) })
+	}
+	end := len(matched)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+		page.NextCursor = encodeListCursor(matched[end-1], sortBy, opts.Ascending)
+	}
+	page.Posts = matched[start:end]
+
+	return page, nil
+}
+
+// matches reports whether a post satisfies the metadata filters in opts.
+// tags must already be normalized.
+func (o ListOptions) matches(p *PostInfo, tags []string) bool {
+	if o.Collection != "" && p.Collection != o.Collection {
+		return false
+	}
+	if o.Width > 0 && p.Width != o.Width {
+		return false
+	}
+	if o.Height > 0 && p.Height != o.Height {
+		return false
+	}
+	if !o.CreatedAfter.IsZero() && p.CreatedAt.Before(o.CreatedAfter) {
+		return false
+	}
+	if !o.CreatedBefore.IsZero() && !p.CreatedAt.Before(o.CreatedBefore) {
+		return false
+	}
+	if !o.UpdatedAfter.IsZero() && p.UpdatedAt.Before(o.UpdatedAfter) {
+		return false
+	}
+	if !o.UpdatedBefore.IsZero() && !p.UpdatedAt.Before(o.UpdatedBefore) {
+		return false
+	}
+	for _, tag := range tags {
+		found := false
+		for _, t := range p.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesQuery reports whether every search term appears in the post's
+// name or in the visible text of its HTML
+func (s *Service) matchesQuery(p *PostInfo, terms []string) bool {
+	name := strings.ToLower(p.Name)
+	var text string
+	loaded := false
+	for _, term := range terms {
+		if strings.Contains(name, term) {
+			continue
+		}
+		if !loaded {
+			full, err := s.storage.GetPost(p.ID)
+			if err != nil {
+				return false
+			}
+			text = strings.ToLower(htmlText(full.HTMLContent))
+			loaded = true
+		}
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// htmlText returns the visible text of an HTML document with whitespace collapsed
+func htmlText(content string) string {
+	content = nonTextBlockPattern.ReplaceAllString(content, " ")
+	content = tagPattern.ReplaceAllString(content, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(content)), " ")
+}
+
+// comparePosts orders posts by the sort field, breaking ties by ID
+func comparePosts(a, b *PostInfo, sortBy string) int {
+	var c int
+	switch sortBy {
+	case SortName:
+		c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	case SortCreated:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	default:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+func encodeListCursor(last *PostInfo, sortBy string, ascending bool) string {
+	cursor := listCursor{SortBy: sortBy, Ascending: ascending, ID: last.ID}
+	switch sortBy {
+	case SortName:
+		cursor.Name = last.Name
+	case SortCreated:
+		cursor.Time = last.CreatedAt
+	default:
+		cursor.Time = last.UpdatedAt
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(s string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
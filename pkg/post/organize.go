@@ -0,0 +1,197 @@
+package post
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+)
+
+// MaxTagLength is the maximum length of a tag in characters
+const MaxTagLength = 50
+
+// NormalizeTag trims and lowercases a tag, rejecting empty or overlong tags
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tags cannot be empty")
+	}
+	if len([]rune(tag)) > MaxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+	}
+	return tag, nil
+}
+
+// TagPost adds and removes tags on a post. Tags are stored lowercased and sorted.
+func (s *Service) TagPost(postID string, add, remove []string) (*ImagePost, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	tags := make(map[string]bool, len(p.Tags))
+	for _, tag := range p.Tags {
+		tags[tag] = true
+	}
+	for _, tag := range remove {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		delete(tags, normalized)
+	}
+	for _, tag := range add {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		tags[normalized] = true
+	}
+
+	p.Tags = make([]string, 0, len(tags))
+	for tag := range tags {
+		p.Tags = append(p.Tags, tag)
+	}
+	sort.Strings(p.Tags)
+	p.UpdatedAt = time.Now()
+
+	if err := s.storage.UpdatePost(p); err != nil {
+		return nil, fmt.Errorf("failed to update post tags: %w", err)
+	}
+
+	return p, nil
+}
+
+// CreateCollection creates a named collection. Its ID is derived from the name.
+func (s *Service) CreateCollection(name, description string) (*Collection, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+	id := slug.Make(name)
+	if id == "" {
+		return nil, fmt.Errorf("collection name %q has no usable characters", name)
+	}
+
+	collections, err := s.storage.ReadCollections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections: %w", err)
+	}
+	for _, c := range collections {
+		if c.ID == id {
+			return nil, fmt.Errorf("collection %s already exists", id)
+		}
+	}
+
+	collection := &Collection{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.storage.WriteCollections(append(collections, collection)); err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	return collection, nil
+}
+
+// ListCollections returns all collections
+func (s *Service) ListCollections() ([]*Collection, error) {
+	collections, err := s.storage.ReadCollections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	return collections, nil
+}
+
+// DeleteCollection deletes a collection and removes its posts from it. The
+// posts themselves are kept.
+func (s *Service) DeleteCollection(collectionID string) error {
+	collections, err := s.storage.ReadCollections()
+	if err != nil {
+		return fmt.Errorf("failed to read collections: %w", err)
+	}
+
+	remaining := make([]*Collection, 0, len(collections))
+	for _, c := range collections {
+		if c.ID != collectionID {
+			remaining = append(remaining, c)
+		}
+	}
+	if len(remaining) == len(collections) {
+		return fmt.Errorf("collection %s does not exist", collectionID)
+	}
+
+	page, err := s.ListPosts(ListOptions{Collection: collectionID})
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(page.Posts))
+	for i, p := range page.Posts {
+		ids[i] = p.ID
+	}
+	if err := s.MoveToCollection(ids, ""); err != nil {
+		return err
+	}
+
+	if err := s.storage.WriteCollections(remaining); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// MoveToCollection moves posts into the collection with collectionID, or out
+// of any collection when collectionID is empty
+func (s *Service) MoveToCollection(postIDs []string, collectionID string) error {
+	if collectionID != "" {
+		if _, err := s.getCollection(collectionID); err != nil {
+			return err
+		}
+	}
+
+	posts := make([]*ImagePost, len(postIDs))
+	for i, postID := range postIDs {
+		if !ValidatePostID(postID) {
+			return fmt.Errorf("invalid post ID: %s", postID)
+		}
+		p, err := s.storage.GetPost(postID)
+		if err != nil {
+			return fmt.Errorf("failed to get post: %w", err)
+		}
+		posts[i] = p
+	}
+
+	now := time.Now()
+	for _, p := range posts {
+		if p.Collection == collectionID {
+			continue
+		}
+		p.Collection = collectionID
+		p.UpdatedAt = now
+		if err := s.storage.UpdatePost(p); err != nil {
+			return fmt.Errorf("failed to move post %s: %w", p.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// getCollection returns the collection with the given ID
+func (s *Service) getCollection(collectionID string) (*Collection, error) {
+	collections, err := s.storage.ReadCollections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections: %w", err)
+	}
+	for _, c := range collections {
+		if c.ID == collectionID {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("collection %s does not exist", collectionID)
+}
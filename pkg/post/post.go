@@ -2,12 +2,21 @@ package post
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"html_image_creator/pkg/htmledit"
+	"html_image_creator/pkg/media"
 )
 
 // Service provides image post operations
 type Service struct {
-	storage StorageInterface
+	storage     StorageInterface
+	mediaPolicy media.Policy
 }
 
 // StorageInterface defines the storage operations needed by the service
@@ -17,20 +26,43 @@ type StorageInterface interface {
 	UpdatePost(post *ImagePost) error
 	GetPost(postID string) (*ImagePost, error)
 	ListPosts() ([]*PostInfo, error)
-	CopyMediaFile(postID, sourcePath string) (string, error)
+	WriteMediaFile(postID string, info *MediaInfo, data []byte) error
+	ListMedia(postID string) ([]*MediaInfo, error)
 	DeletePost(postID string) error
-	GetPostPath(postID string) string
-	GetHTMLPath(postID string) string
+	TrashPost(postID string) error
+	ListTrash() ([]*TrashedPost, error)
+	RestorePost(postID string) error
+	PurgeTrash(olderThan time.Duration) ([]string, error)
+	ReadPostFiles(postID string, includeVersions bool) ([]*PostFile, error)
+	WritePostFiles(postID string, files []*PostFile) error
+	MaterializePost(postID string) (string, func(), error)
+	PostLocation(postID string) string
+	ReadCollections() ([]*Collection, error)
+	WriteCollections(collections []*Collection) error
+	MigrateAll(dryRun bool) (*MigrationReport, error)
+	CheckStorage(repair string) (*StorageCheckReport, error)
+	GarbageCollect(policy RetentionPolicy, dryRun bool) (*GCReport, error)
+	PostLog(postID string, limit int) ([]*Revision, error)
+	CheckoutRevision(postID, revision string) (string, error)
+	Usage(postID string, largestMedia int) (*StorageUsage, error)
+	ReadBrandKits() ([]*BrandKit, error)
+	ReadBrandLogo(kitID, filename string) ([]byte, error)
+	WriteBrandKit(kit *BrandKit, logos map[string][]byte, removed []string) error
+	DeleteMediaFile(postID, filename string) error
+	ReadComponents() ([]*Component, error)
+	WriteComponent(c *Component) error
 }
 
-// NewService creates a new post service
-func NewService(storage StorageInterface) *Service {
+// NewService creates a new post service that validates imported media against mediaPolicy
+func NewService(storage StorageInterface, mediaPolicy media.Policy) *Service {
 	return &Service{
-		storage: storage,
+		storage:     storage,
+		mediaPolicy: mediaPolicy,
 	}
 }
 
-// CreatePost creates a new image post with fixed canvas dimensions
+// CreatePost creates a new image post with fixed canvas dimensions.
+// <x-component> tags in the HTML are expanded before it is stored.
 func (s *Service) CreatePost(name, htmlContent string, width, height int) (*ImagePost, error) {
 	if name == "" {
 		return nil, fmt.Errorf("post name cannot be empty")
@@ -41,6 +73,10 @@ func (s *Service) CreatePost(name, htmlContent string, width, height int) (*Imag
 	if width <= 0 || height <= 0 {
 		return nil, fmt.Errorf("width and height must be positive integers")
 	}
+	htmlContent, err := s.expandComponents(htmlContent)
+	if err != nil {
+		return nil, err
+	}
 
 	postID := GeneratePostID(name, s.storage.PostExists)
 
@@ -62,7 +98,8 @@ func (s *Service) CreatePost(name, htmlContent string, width, height int) (*Imag
 	return p, nil
 }
 
-// UpdatePost updates an existing post's HTML content (dimensions are immutable)
+// UpdatePost updates an existing post's HTML content (use ResizePost to
+// change dimensions). <x-component> tags are expanded as in CreatePost.
 func (s *Service) UpdatePost(postID, htmlContent string) (*ImagePost, error) {
 	if !ValidatePostID(postID) {
 		return nil, fmt.Errorf("invalid post ID: %s", postID)
@@ -76,6 +113,16 @@ func (s *Service) UpdatePost(postID, htmlContent string) (*ImagePost, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
+	if htmlContent, err = s.expandComponents(htmlContent); err != nil {
+		return nil, err
+	}
+
+	// Branded posts keep their brand style block even if the new HTML drops it
+	if p.BrandKit != "" {
+		if kit, err := s.GetBrandKit(p.BrandKit); err == nil {
+			htmlContent = applyBrandStyle(htmlContent, kit)
+		}
+	}
 
 	p.HTMLContent = htmlContent
 	p.UpdatedAt = time.Now()
@@ -87,6 +134,81 @@ func (s *Service) UpdatePost(postID, htmlContent string) (*ImagePost, error) {
 	return p, nil
 }
 
+// EditPost applies selector-based edits to a post's stored HTML. The
+// operations are applied together: if one fails, the post is left unchanged.
+// The returned edit holds the result of each operation and any change
+// re-rendering made outside the edited elements.
+func (s *Service) EditPost(postID string, ops []*htmledit.Operation) (*ImagePost, *htmledit.Edit, error) {
+	if !ValidatePostID(postID) {
+		return nil, nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	edit, err := htmledit.Apply(p.HTMLContent, ops)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err = s.UpdatePost(postID, edit.HTML)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, edit, nil
+}
+
+// UpdateMetadata applies metadata edits to a post without changing its ID or HTML
+func (s *Service) UpdateMetadata(postID string, update MetadataUpdate) (*ImagePost, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if update.Name != nil && *update.Name == "" {
+		return nil, fmt.Errorf("post name cannot be empty")
+	}
+	for key := range update.CustomFields {
+		if key == "" {
+			return nil, fmt.Errorf("custom field names cannot be empty")
+		}
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	if update.Name != nil {
+		p.Name = *update.Name
+	}
+	if update.Description != nil {
+		p.Description = *update.Description
+	}
+	if update.Caption != nil {
+		p.Caption = *update.Caption
+	}
+	if update.AltText != nil {
+		p.AltText = *update.AltText
+	}
+	for key, value := range update.CustomFields {
+		if value == nil || *value == "" {
+			delete(p.CustomFields, key)
+			continue
+		}
+		if p.CustomFields == nil {
+			p.CustomFields = make(map[string]string)
+		}
+		p.CustomFields[key] = *value
+	}
+	p.UpdatedAt = time.Now()
+
+	if err := s.storage.UpdatePost(p); err != nil {
+		return nil, fmt.Errorf("failed to update post metadata: %w", err)
+	}
+
+	return p, nil
+}
+
 // GetPost retrieves a post by ID
 func (s *Service) GetPost(postID string) (*ImagePost, error) {
 	if !ValidatePostID(postID) {
@@ -101,30 +223,110 @@ func (s *Service) GetPost(postID string) (*ImagePost, error) {
 	return p, nil
 }
 
-// ListPosts returns all posts
-func (s *Service) ListPosts() ([]*PostInfo, error) {
-	posts, err := s.storage.ListPosts()
+// AddMedia validates a media file from disk, applies the optional transform and adds it to a post
+func (s *Service) AddMedia(postID, sourcePath string, transform *media.Transform) (*MediaInfo, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if sourcePath == "" {
+		return nil, fmt.Errorf("source path cannot be empty")
+	}
+
+	data, err := s.readMediaSource(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.AddMediaData(postID, filepath.Base(sourcePath), data, transform)
+}
+
+// readMediaSource reads a media file from disk, enforcing the policy's size limit
+func (s *Service) readMediaSource(sourcePath string) ([]byte, error) {
+	srcFile, err := os.Open(sourcePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list posts: %w", err)
+		return nil, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer srcFile.Close()
+
+	stat, err := srcFile.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat source file: %w", err)
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, fmt.Errorf("source path is not a regular file: %s", sourcePath)
+	}
+	if s.mediaPolicy.MaxBytes > 0 && stat.Size() > s.mediaPolicy.MaxBytes {
+		return nil, fmt.Errorf("media file is %d bytes, exceeding maximum size of %d bytes", stat.Size(), s.mediaPolicy.MaxBytes)
 	}
-	return posts, nil
+
+	data, err := io.ReadAll(srcFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+	return data, nil
 }
 
-// AddMedia adds a media file to a post and returns the relative path
-func (s *Service) AddMedia(postID, sourcePath string) (string, error) {
+// AddMediaData validates in-memory media data, applies the optional transform
+// and writes it to a post under filename. The extension of filename is
+// updated when the transform changes the image format.
+func (s *Service) AddMediaData(postID, filename string, data []byte, transform *media.Transform) (*MediaInfo, error) {
 	if !ValidatePostID(postID) {
-		return "", fmt.Errorf("invalid post ID: %s", postID)
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
 	}
-	if sourcePath == "" {
-		return "", fmt.Errorf("source path cannot be empty")
+	if filename == "" {
+		return nil, fmt.Errorf("filename cannot be empty")
 	}
 
-	relativePath, err := s.storage.CopyMediaFile(postID, sourcePath)
+	probed, err := s.mediaPolicy.Inspect(data)
 	if err != nil {
-		return "", fmt.Errorf("failed to add media: %w", err)
+		return nil, fmt.Errorf("invalid media file %s: %w", filename, err)
+	}
+
+	if !transform.IsZero() {
+		if probed.Width == 0 || probed.ContentType == "image/svg+xml" {
+			return nil, fmt.Errorf("transforms are only supported for raster images, got %s", probed.ContentType)
+		}
+		limited := *transform
+		limited.MaxPixels = s.mediaPolicy.MaxPixels
+		transformed, contentType, err := limited.Apply(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform %s: %w", filename, err)
+		}
+		if contentType != probed.ContentType {
+			filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + media.ExtensionFor(contentType)
+		}
+		data = transformed
+		if probed, err = s.mediaPolicy.Inspect(data); err != nil {
+			return nil, fmt.Errorf("invalid transformed media file %s: %w", filename, err)
+		}
+	}
+
+	info := &MediaInfo{
+		Filename:    filename,
+		ContentType: probed.ContentType,
+		Size:        probed.Size,
+		Width:       probed.Width,
+		Height:      probed.Height,
+		AddedAt:     time.Now(),
+	}
+	if err := s.storage.WriteMediaFile(postID, info, data); err != nil {
+		return nil, fmt.Errorf("failed to add media: %w", err)
 	}
 
-	return relativePath, nil
+	return info, nil
+}
+
+// ListMedia returns the media manifest entries for a post
+func (s *Service) ListMedia(postID string) ([]*MediaInfo, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	files, err := s.storage.ListMedia(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media: %w", err)
+	}
+	return files, nil
 }
 
 // DeletePost deletes a post
@@ -140,12 +342,131 @@ func (s *Service) DeletePost(postID string) error {
 	return nil
 }
 
-// GetPostPath returns the absolute path to the post directory
-func (s *Service) GetPostPath(postID string) string {
-	return s.storage.GetPostPath(postID)
+// TrashPost moves a post into the trash so it can be restored later
+func (s *Service) TrashPost(postID string) error {
+	if !ValidatePostID(postID) {
+		return fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	if err := s.storage.TrashPost(postID); err != nil {
+		return fmt.Errorf("failed to trash post: %w", err)
+	}
+
+	return nil
+}
+
+// ListTrash returns all trashed posts
+func (s *Service) ListTrash() ([]*TrashedPost, error) {
+	trashed, err := s.storage.ListTrash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	return trashed, nil
+}
+
+// RestorePost moves a trashed post back into the post list
+func (s *Service) RestorePost(postID string) error {
+	if !ValidatePostID(postID) {
+		return fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	if err := s.storage.RestorePost(postID); err != nil {
+		return fmt.Errorf("failed to restore post: %w", err)
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently deletes trashed posts deleted more than olderThan
+// ago (all of them when olderThan is zero) and returns their IDs
+func (s *Service) EmptyTrash(olderThan time.Duration) ([]string, error) {
+	if olderThan < 0 {
+		return nil, fmt.Errorf("age cannot be negative")
+	}
+
+	purged, err := s.storage.PurgeTrash(olderThan)
+	if err != nil {
+		return purged, fmt.Errorf("failed to empty trash: %w", err)
+	}
+	return purged, nil
+}
+
+// MaterializePost returns a local directory holding the post's files for
+// rendering, and a cleanup function to call once rendering is done
+func (s *Service) MaterializePost(postID string) (string, func(), error) {
+	if !ValidatePostID(postID) {
+		return "", nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	dir, cleanup, err := s.storage.MaterializePost(postID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to materialize post: %w", err)
+	}
+	return dir, cleanup, nil
+}
+
+// PostLocation returns where the post's HTML is stored, as a file path or URL
+func (s *Service) PostLocation(postID string) string {
+	return s.storage.PostLocation(postID)
+}
+
+// MigrateStorage upgrades every post's metadata to the current schema
+// version, or only reports pending migrations when dryRun is set
+func (s *Service) MigrateStorage(dryRun bool) (*MigrationReport, error) {
+	report, err := s.storage.MigrateAll(dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate storage: %w", err)
+	}
+	return report, nil
+}
+
+// CheckStorage scans storage for broken posts and applies the repair mode
+func (s *Service) CheckStorage(repair string) (*StorageCheckReport, error) {
+	report, err := s.storage.CheckStorage(repair)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check storage: %w", err)
+	}
+	return report, nil
+}
+
+// GarbageCollect applies the retention policy, deleting old posts and
+// surplus versions, or only reports what would be deleted when dryRun is set
+func (s *Service) GarbageCollect(policy RetentionPolicy, dryRun bool) (*GCReport, error) {
+	if policy.MaxAgeDays < 0 || policy.KeepVersions < 0 {
+		return nil, fmt.Errorf("retention values must not be negative")
+	}
+	if policy == (RetentionPolicy{}) {
+		return nil, fmt.Errorf("no retention policy: set max_age_days or keep_versions")
+	}
+
+	report, err := s.storage.GarbageCollect(policy, dryRun)
+	if err != nil {
+		return report, fmt.Errorf("failed to collect garbage: %w", err)
+	}
+	return report, nil
 }
 
-// GetHTMLPath returns the absolute path to the HTML file
-func (s *Service) GetHTMLPath(postID string) string {
-	return s.storage.GetHTMLPath(postID)
+// StorageUsage reports bytes used per post, largest first, with workspace
+// totals. limit caps the number of posts listed (0 lists all) without
+// affecting the totals; largestMedia is the number of media files to list.
+func (s *Service) StorageUsage(postID string, limit, largestMedia int) (*StorageUsage, error) {
+	if postID != "" && !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if limit < 0 || largestMedia < 0 {
+		return nil, fmt.Errorf("limit and largest_media must not be negative")
+	}
+
+	usage, err := s.storage.Usage(postID, largestMedia)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure storage: %w", err)
+	}
+
+	sort.SliceStable(usage.Posts, func(i, j int) bool {
+		return usage.Posts[i].TotalBytes > usage.Posts[j].TotalBytes
+	})
+	if limit > 0 && len(usage.Posts) > limit {
+		usage.Posts = usage.Posts[:limit]
+	}
+	return usage, nil
 }
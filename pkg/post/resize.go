@@ -0,0 +1,76 @@
+package post
+
+import (
+	"fmt"
+	"time"
+
+	"html_image_creator/pkg/layout"
+)
+
+// Resize modes for ResizePost
+const (
+	ResizeKeep    = "keep"    // Change the canvas only, leaving the layout as-is
+	ResizeScale   = "scale"   // Scale the whole layout uniformly with a CSS transform wrapper
+	ResizeRewrite = "rewrite" // Rewrite px lengths in the CSS by the uniform factor
+)
+
+// HistoryResize is the history action recorded by ResizePost
+const HistoryResize = "resize"
+
+// ResizePost changes a post's canvas dimensions, adapting the layout
+// according to mode, and records the change in the post's history
+func (s *Service) ResizePost(postID string, width, height int, mode string) (*ImagePost, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("width and height must be positive integers")
+	}
+	if mode == "" {
+		mode = ResizeKeep
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if p.Width == width && p.Height == height {
+		return nil, fmt.Errorf("post %s is already %dx%d", postID, width, height)
+	}
+
+	switch mode {
+	case ResizeKeep:
+	case ResizeScale:
+		p.HTMLContent = layout.WrapScaled(p.HTMLContent, p.Width, p.Height, width, height)
+	case ResizeRewrite:
+		if layout.IsScaleWrapped(p.HTMLContent) {
+			return nil, fmt.Errorf("post %s was previously resized with mode %s; use %s again", postID, ResizeScale, ResizeScale)
+		}
+		factor := layout.ProportionalFactor(p.Width, p.Height, width, height)
+		p.HTMLContent = layout.ScalePx(p.HTMLContent, factor)
+	default:
+		return nil, fmt.Errorf("invalid resize mode %q: use %s, %s or %s", mode, ResizeKeep, ResizeScale, ResizeRewrite)
+	}
+
+	now := time.Now()
+	p.History = append(p.History, &HistoryEntry{
+		Action: HistoryResize,
+		At:     now,
+		Details: map[string]interface{}{
+			"from_width":  p.Width,
+			"from_height": p.Height,
+			"to_width":    width,
+			"to_height":   height,
+			"mode":        mode,
+		},
+	})
+	p.Width = width
+	p.Height = height
+	p.UpdatedAt = now
+
+	if err := s.storage.UpdatePost(p); err != nil {
+		return nil, fmt.Errorf("failed to resize post: %w", err)
+	}
+
+	return p, nil
+}
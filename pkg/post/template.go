@@ -0,0 +1,611 @@
+package post
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// Template variable types
+const (
+	VarString  = "string"  // Text, escaped for the context it is inserted in
+	VarHTML    = "html"    // Markup inserted verbatim
+	VarCSS     = "css"     // CSS value for <style> blocks and style attributes
+	VarNumber  = "number"  // JSON number
+	VarBoolean = "boolean" // For {{if .flag}}
+	VarList    = "list"    // List of strings, each escaped like string, for {{range .items}}
+)
+
+// HistoryFromTemplate is the history action recorded on posts created by InstantiateTemplate
+const HistoryFromTemplate = "from_template"
+
+var (
+	templateVarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// cssValuePattern admits values like rgb(0, 0, 0), var(--x) or
+	// calc(100% - 4px) but nothing that ends a declaration, rule, string or
+	// <style> element, so a css value can't add styles or markup
+	cssValuePattern    = regexp.MustCompile(`^[A-Za-z0-9#%., +*/()_-]*$`)
+	cssForbiddenTokens = []string{"/*", "*/", "url(", "expression("}
+)
+
+// TemplateVariable declares a placeholder of a template post
+type TemplateVariable struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+// TemplateSpec marks a post as a template. Its HTML is a Go html/template
+// whose placeholders ({{.name}}) are the declared variables. Values are
+// escaped for their context, so text placed in a <style> block, a URL or an
+// attribute cannot break out of it. In CSS, string values are limited to
+// keywords, #hex colors and lengths and anything else renders as ZgotmplZ;
+// css variables carry other values, such as rgb() or var(), after checking
+// they stay a single value.
+type TemplateSpec struct {
+	Variables []*TemplateVariable `json:"variables"`
+}
+
+// DefineTemplate declares the variables of a template post, or turns the
+// template back into a plain post when spec is nil. The HTML must parse as a
+// template and may only reference declared variables.
+func (s *Service) DefineTemplate(postID string, spec *TemplateSpec) (*ImagePost, error) {
+	if !ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+
+	p, err := s.storage.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	if spec != nil {
+		if err := validateTemplateSpec(spec); err != nil {
+			return nil, err
+		}
+		if _, err := parseTemplate(p.HTMLContent, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Template = spec
+	p.UpdatedAt = time.Now()
+	if err := s.storage.UpdatePost(p); err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	return p, nil
+}
+
+// RenderTemplate fills a template post's placeholders with values and
+// returns the template and the rendered HTML. Values must match the declared
+// variables: unknown names, missing required variables and wrong types are
+// rejected.
+func (s *Service) RenderTemplate(templateID string, values map[string]interface{}) (*ImagePost, string, error) {
+	tmpl, t, err := s.loadTemplate(templateID)
+	if err != nil {
+		return nil, "", err
+	}
+	rendered, err := executeTemplate(t, tmpl.Template, values)
+	if err != nil {
+		return nil, "", err
+	}
+	return tmpl, rendered, nil
+}
+
+// loadTemplate reads a template post and parses its HTML
+func (s *Service) loadTemplate(templateID string) (*ImagePost, *template.Template, error) {
+	if !ValidatePostID(templateID) {
+		return nil, nil, fmt.Errorf("invalid post ID: %s", templateID)
+	}
+
+	tmpl, err := s.storage.GetPost(templateID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if tmpl.Template == nil {
+		return nil, nil, fmt.Errorf("post %s is not a template", templateID)
+	}
+
+	t, err := parseTemplate(tmpl.HTMLContent, tmpl.Template)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tmpl, t, nil
+}
+
+// executeTemplate validates values against spec and renders t with them
+func executeTemplate(t *template.Template, spec *TemplateSpec, values map[string]interface{}) (string, error) {
+	data, err := templateData(spec, values)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// InstantiateTemplate creates a new post named name from a template post,
+// copying its media and canvas size
+func (s *Service) InstantiateTemplate(templateID, name string, values map[string]interface{}) (*ImagePost, error) {
+	if name == "" {
+		return nil, fmt.Errorf("post name cannot be empty")
+	}
+
+	_, rendered, err := s.RenderTemplate(templateID, values)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.storage.ReadPostFiles(templateID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template files: %w", err)
+	}
+
+	now := time.Now()
+	for _, f := range files {
+		switch f.Path {
+		case "index.html":
+			f.Data = []byte(rendered)
+		case "metadata.json":
+			// Decode into the stored metadata so fields this code doesn't set are carried over
+			var metadata Metadata
+			if err := json.Unmarshal(f.Data, &metadata); err != nil {
+				return nil, fmt.Errorf("invalid metadata.json: %w", err)
+			}
+			metadata.Name = name
+			metadata.CreatedAt = now
+			metadata.UpdatedAt = now
+			metadata.Template = nil
+			metadata.Exports = nil
+			metadata.History = []*HistoryEntry{{
+				Action:  HistoryFromTemplate,
+				At:      now,
+				Details: map[string]interface{}{"template_id": templateID, "values": values},
+			}}
+			data, err := json.MarshalIndent(&metadata, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			f.Data = data
+		}
+	}
+
+	postID := GeneratePostID(name, s.storage.PostExists)
+	if err := s.storage.WritePostFiles(postID, files); err != nil {
+		return nil, fmt.Errorf("failed to write post files: %w", err)
+	}
+
+	return s.GetPost(postID)
+}
+
+// MaterializeTemplate renders a template into a scratch directory with its
+// media, for exporting without creating a post. mediaPaths is as for
+// PreparedTemplate.Materialize. The returned cleanup function removes the
+// directory.
+func (s *Service) MaterializeTemplate(templateID string, values map[string]interface{}, mediaPaths map[string]string) (*ImagePost, string, func(), error) {
+	prepared, err := s.PrepareTemplate(templateID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	dir, err := prepared.Materialize(values, mediaPaths)
+	if err != nil {
+		prepared.Close()
+		return nil, "", nil, err
+	}
+	return prepared.Post, dir, prepared.Close, nil
+}
+
+// PreparedTemplate is a template read, parsed and copied into a scratch
+// directory once, for rendering many sets of values such as the rows of a
+// batch. Each Materialize call only writes index.html and its own media.
+type PreparedTemplate struct {
+	Post *ImagePost
+
+	svc   *Service
+	t     *template.Template
+	dir   string
+	own   map[string]bool // Files copied from the template
+	extra []string        // Media written by the last Materialize call
+}
+
+// PrepareTemplate reads and parses a template post and copies its files,
+// except index.html, into a scratch directory. Close removes the directory.
+func (s *Service) PrepareTemplate(templateID string) (*PreparedTemplate, error) {
+	tmpl, t, err := s.loadTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.storage.ReadPostFiles(templateID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template files: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "html-image-template-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	prepared := &PreparedTemplate{Post: tmpl, svc: s, t: t, dir: dir, own: make(map[string]bool)}
+	for _, f := range files {
+		prepared.own[f.Path] = true
+		if f.Path == "index.html" {
+			continue
+		}
+		if err := prepared.writeFile(f.Path, f.Data); err != nil {
+			prepared.Close()
+			return nil, err
+		}
+	}
+	return prepared, nil
+}
+
+// Materialize renders values into the scratch directory and returns the
+// directory. mediaPaths maps variables to media files on disk: each file is
+// validated, copied into the media folder and the variable is set to its
+// media/ path. Media copied by the previous call are removed first.
+func (p *PreparedTemplate) Materialize(values map[string]interface{}, mediaPaths map[string]string) (string, error) {
+	for _, rel := range p.extra {
+		os.Remove(filepath.Join(p.dir, filepath.FromSlash(rel)))
+	}
+	p.extra = nil
+
+	extra := make(map[string][]byte)
+	if len(mediaPaths) > 0 {
+		merged := make(map[string]interface{}, len(values)+len(mediaPaths))
+		for name, value := range values {
+			merged[name] = value
+		}
+		for name, sourcePath := range mediaPaths {
+			data, err := p.svc.readMediaSource(sourcePath)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", name, err)
+			}
+			if _, err := p.svc.mediaPolicy.Inspect(data); err != nil {
+				return "", fmt.Errorf("%s: invalid media file %s: %w", name, sourcePath, err)
+			}
+			// Prefix with the variable so files never replace the template's own media
+			rel := "media/" + name + "-" + filepath.Base(sourcePath)
+			if p.own[rel] {
+				return "", fmt.Errorf("%s: media file %s would replace a file of the template", name, rel)
+			}
+			extra[rel] = data
+			merged[name] = rel
+		}
+		values = merged
+	}
+
+	rendered, err := executeTemplate(p.t, p.Post.Template, values)
+	if err != nil {
+		return "", err
+	}
+	if err := p.writeFile("index.html", []byte(rendered)); err != nil {
+		return "", err
+	}
+	for rel, data := range extra {
+		p.extra = append(p.extra, rel)
+		if err := p.writeFile(rel, data); err != nil {
+			return "", err
+		}
+	}
+	return p.dir, nil
+}
+
+// Close removes the scratch directory
+func (p *PreparedTemplate) Close() {
+	os.RemoveAll(p.dir)
+}
+
+// writeFile writes a file below the scratch directory
+func (p *PreparedTemplate) writeFile(rel string, data []byte) error {
+	path := filepath.Join(p.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", rel, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	return nil
+}
+
+// CoerceValues converts text values, such as CSV cells, to the types of the
+// declared variables. Lists are split on "|". Empty text is dropped so that
+// defaults apply. Values of other types and undeclared names are returned
+// unchanged for RenderTemplate to validate.
+func (spec *TemplateSpec) CoerceValues(values map[string]interface{}) (map[string]interface{}, error) {
+	types := make(map[string]string)
+	for _, v := range spec.Variables {
+		types[v.Name] = v.Type
+	}
+
+	coerced := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		text, ok := value.(string)
+		if !ok {
+			coerced[name] = value
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		switch types[name] {
+		case VarNumber:
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number, got %q", name, text)
+			}
+			coerced[name] = n
+		case VarBoolean:
+			b, err := strconv.ParseBool(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a boolean, got %q", name, text)
+			}
+			coerced[name] = b
+		case VarList:
+			var items []interface{}
+			for _, item := range strings.Split(text, "|") {
+				items = append(items, strings.TrimSpace(item))
+			}
+			coerced[name] = items
+		default:
+			coerced[name] = value
+		}
+	}
+	return coerced, nil
+}
+
+// validateTemplateSpec checks variable names, types and defaults
+func validateTemplateSpec(spec *TemplateSpec) error {
+	seen := make(map[string]bool)
+	for _, v := range spec.Variables {
+		if !templateVarPattern.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name %q: use letters, digits and '_', not starting with a digit", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %s is declared twice", v.Name)
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case "":
+			v.Type = VarString
+		case VarString, VarHTML, VarCSS, VarNumber, VarBoolean, VarList:
+		default:
+			return fmt.Errorf("variable %s has invalid type %q: use %s, %s, %s, %s, %s or %s", v.Name, v.Type, VarString, VarHTML, VarCSS, VarNumber, VarBoolean, VarList)
+		}
+		if v.Default != nil {
+			if _, err := convertTemplateValue(v, v.Default); err != nil {
+				return fmt.Errorf("invalid default: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseTemplate parses a template's HTML and verifies that it only
+// references declared variables and can be escaped
+func parseTemplate(htmlContent string, spec *TemplateSpec) (*template.Template, error) {
+	t, err := template.New("index.html").Option("missingkey=error").Parse(htmlContent)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template HTML: %w", err)
+	}
+
+	declared := make(map[string]bool)
+	for _, v := range spec.Variables {
+		declared[v.Name] = true
+	}
+	var undeclared []string
+	for name := range templateFields(t.Tree.Root) {
+		if !declared[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return nil, fmt.Errorf("template references undeclared variables: %s", strings.Join(undeclared, ", "))
+	}
+
+	// Escaping problems only surface on execution, so try it with zero values
+	data := make(map[string]interface{}, len(spec.Variables))
+	for _, v := range spec.Variables {
+		data[v.Name], _ = convertTemplateValue(v, nil)
+	}
+	if err := t.Execute(io.Discard, data); err != nil {
+		return nil, fmt.Errorf("invalid template HTML: %w", err)
+	}
+	return t, nil
+}
+
+// templateFields returns the top-level variable names referenced by a
+// template. Fields inside {{range}} and {{with}} bodies refer to the current
+// element and are skipped; $.name references are included.
+func templateFields(root parse.Node) map[string]bool {
+	fields := make(map[string]bool)
+	var walk func(n parse.Node, top bool)
+	walk = func(n parse.Node, top bool) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, top)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, top)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg, top)
+				}
+			}
+		case *parse.FieldNode:
+			if top {
+				fields[n.Ident[0]] = true
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				fields[n.Ident[1]] = true
+			}
+		case *parse.ChainNode:
+			walk(n.Node, top)
+		case *parse.IfNode:
+			walk(n.Pipe, top)
+			walk(n.List, top)
+			walk(n.ElseList, top)
+		case *parse.RangeNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *parse.WithNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *parse.TemplateNode:
+			walk(n.Pipe, top)
+		}
+	}
+	walk(root, true)
+	return fields
+}
+
+// templateData validates values against the spec and returns the data the
+// template is executed with, with defaults applied
+func templateData(spec *TemplateSpec, values map[string]interface{}) (map[string]interface{}, error) {
+	var problems []string
+
+	declared := make(map[string]bool)
+	for _, v := range spec.Variables {
+		declared[v.Name] = true
+	}
+	var extra []string
+	for name := range values {
+		if !declared[name] {
+			extra = append(extra, name)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		problems = append(problems, "unknown variables: "+strings.Join(extra, ", "))
+	}
+
+	data := make(map[string]interface{})
+	var missing []string
+	for _, v := range spec.Variables {
+		value, ok := values[v.Name]
+		if !ok || value == nil {
+			if v.Required {
+				missing = append(missing, v.Name)
+				continue
+			}
+			value = v.Default
+		}
+		converted, err := convertTemplateValue(v, value)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		data[v.Name] = converted
+	}
+	if len(missing) > 0 {
+		problems = append(problems, "missing required variables: "+strings.Join(missing, ", "))
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid template values: %s", strings.Join(problems, "; "))
+	}
+	return data, nil
+}
+
+// convertTemplateValue checks a value's type and prepares it for insertion.
+// A nil value yields the type's zero value.
+func convertTemplateValue(v *TemplateVariable, value interface{}) (interface{}, error) {
+	switch v.Type {
+	case VarNumber:
+		if value == nil {
+			return 0.0, nil
+		}
+		n, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number", v.Name)
+		}
+		return n, nil
+	case VarBoolean:
+		if value == nil {
+			return false, nil
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a boolean", v.Name)
+		}
+		return b, nil
+	case VarList:
+		if value == nil {
+			return []string{}, nil
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of strings", v.Name)
+		}
+		list := make([]string, len(items))
+		for i, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", v.Name)
+			}
+			list[i] = s
+		}
+		return list, nil
+	default:
+		if value == nil {
+			return "", nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", v.Name)
+		}
+		switch v.Type {
+		case VarHTML:
+			// Declared as markup by the template author
+			return template.HTML(s), nil
+		case VarCSS:
+			if err := checkCSSValue(s); err != nil {
+				return nil, fmt.Errorf("%s %w", v.Name, err)
+			}
+			return template.CSS(s), nil
+		}
+		return s, nil
+	}
+}
+
+// checkCSSValue rejects CSS values that could do more than set one property
+func checkCSSValue(value string) error {
+	if !cssValuePattern.MatchString(value) {
+		return fmt.Errorf("must be a CSS value of letters, digits, spaces and #%%.,+-*/()_ only")
+	}
+	lower := strings.ToLower(value)
+	for _, token := range cssForbiddenTokens {
+		if strings.Contains(lower, token) {
+			return fmt.Errorf("must not contain %s", token)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package post
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplateSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		vars []*TemplateVariable
+		err  string
+	}{
+		{"valid", []*TemplateVariable{{Name: "title"}, {Name: "_n2", Type: VarNumber, Default: 2.0}}, ""},
+		{"every type", []*TemplateVariable{
+			{Name: "s", Type: VarString}, {Name: "h", Type: VarHTML}, {Name: "c", Type: VarCSS},
+			{Name: "n", Type: VarNumber}, {Name: "b", Type: VarBoolean}, {Name: "l", Type: VarList},
+		}, ""},
+		{"name starting with a digit", []*TemplateVariable{{Name: "1st"}}, "invalid variable name"},
+		{"name with a dash", []*TemplateVariable{{Name: "sub-title"}}, "invalid variable name"},
+		{"empty name", []*TemplateVariable{{Name: ""}}, "invalid variable name"},
+		{"declared twice", []*TemplateVariable{{Name: "a"}, {Name: "a"}}, "declared twice"},
+		{"unknown type", []*TemplateVariable{{Name: "a", Type: "date"}}, "invalid type"},
+		{"default of the wrong type", []*TemplateVariable{{Name: "a", Type: VarNumber, Default: "2"}}, "a must be a number"},
+		{"list default with a number", []*TemplateVariable{{Name: "a", Type: VarList, Default: []interface{}{"x", 1.0}}}, "a must be a list of strings"},
+		{"unsafe css default", []*TemplateVariable{{Name: "a", Type: VarCSS, Default: "red; x: y"}}, "a must be a CSS value"},
+	}
+
+	for _, tt := range tests {
+		err := validateTemplateSpec(&TemplateSpec{Variables: tt.vars})
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+
+	spec := &TemplateSpec{Variables: []*TemplateVariable{{Name: "a"}}}
+	if err := validateTemplateSpec(spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Variables[0].Type != VarString {
+		t.Errorf("type defaulted to %q, want %q", spec.Variables[0].Type, VarString)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	spec := &TemplateSpec{Variables: []*TemplateVariable{
+		{Name: "title", Type: VarString},
+		{Name: "items", Type: VarList},
+		{Name: "show", Type: VarBoolean},
+	}}
+	tests := []struct {
+		name string
+		html string
+		err  string
+	}{
+		{"declared variables", `<h1>{{.title}}</h1>{{if .show}}<ul>{{range .items}}<li>{{.}}</li>{{end}}</ul>{{end}}`, ""},
+		{"$ inside range", `{{range .items}}<p>{{.}} of {{$.title}}</p>{{end}}`, ""},
+		{"undeclared variables", `<h1>{{.heading}}</h1><p>{{.body}}</p>`, "undeclared variables: body, heading"},
+		{"undeclared $ inside range", `{{range .items}}{{$.other}}{{end}}`, "undeclared variables: other"},
+		{"syntax error", `<h1>{{.title</h1>`, "invalid template HTML"},
+		{"ambiguous context", `<a href="{{if .show}}x{{else}}"{{end}}">x</a>`, "invalid template HTML"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseTemplate(tt.html, spec)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestTemplateData(t *testing.T) {
+	spec := &TemplateSpec{Variables: []*TemplateVariable{
+		{Name: "title", Required: true},
+		{Name: "count", Type: VarNumber, Default: 3.0},
+		{Name: "show", Type: VarBoolean},
+		{Name: "items", Type: VarList},
+		{Name: "color", Type: VarCSS, Default: "red"},
+	}}
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		err    string
+	}{
+		{"required only", map[string]interface{}{"title": "T"}, ""},
+		{"all values", map[string]interface{}{"title": "T", "count": 1.0, "show": true, "items": []interface{}{"a"}, "color": "rgb(0, 0, 0)"}, ""},
+		{"missing required", map[string]interface{}{"count": 1.0}, "missing required variables: title"},
+		{"null required", map[string]interface{}{"title": nil}, "missing required variables: title"},
+		{"unknown", map[string]interface{}{"title": "T", "subtitle": "S", "extra": 1.0}, "unknown variables: extra, subtitle"},
+		{"string for number", map[string]interface{}{"title": "T", "count": "1"}, "count must be a number"},
+		{"number for string", map[string]interface{}{"title": 1.0}, "title must be a string"},
+		{"string for boolean", map[string]interface{}{"title": "T", "show": "yes"}, "show must be a boolean"},
+		{"string for list", map[string]interface{}{"title": "T", "items": "a"}, "items must be a list of strings"},
+		{"several problems", map[string]interface{}{"count": "x", "other": 1.0}, "unknown variables: other; count must be a number; missing required variables: title"},
+	}
+
+	for _, tt := range tests {
+		data, err := templateData(spec, tt.values)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(data) != len(spec.Variables) {
+			t.Errorf("%s: data has %d values, want one per variable", tt.name, len(data))
+		}
+	}
+
+	data, err := templateData(spec, map[string]interface{}{"title": "T"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["count"] != 3.0 || data["show"] != false {
+		t.Errorf("defaults not applied: count %v, show %v", data["count"], data["show"])
+	}
+}
+
+func TestCheckCSSValue(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{"red", true},
+		{"#1a2b3c", true},
+		{"rgb(10, 20, 30)", true},
+		{"rgba(0, 0, 0, .5)", true},
+		{"hsl(120 50% 50% / 80%)", true},
+		{"var(--color-primary)", true},
+		{"calc(100% - 4px)", true},
+		{"1px solid #000", true},
+		{"Open Sans, sans-serif", true},
+		{"", true},
+
+		{"red; background: blue", false},
+		{"red}body{display:none", false},
+		{"</style><script>alert(1)</script>", false},
+		{`"Open Sans"`, false},
+		{"'a'", false},
+		{`\3c`, false},
+		{"url(a.png)", false},
+		{"URL(a.png)", false},
+		{"expression(alert(1))", false},
+		{"red /* x */", false},
+		{"red !important", false},
+		{"a:b", false},
+		{"red\ncolor", false},
+	}
+	for _, tt := range tests {
+		if err := checkCSSValue(tt.value); (err == nil) != tt.ok {
+			t.Errorf("checkCSSValue(%q) = %v, want ok = %v", tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestExecuteTemplateEscaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		typ   string
+		value interface{}
+		want  string
+	}{
+		{"text", `<p>{{.v}}</p>`, VarString, `<b>"Tom" & 'Jerry'</b>`, `<p>&lt;b&gt;&#34;Tom&#34; &amp; &#39;Jerry&#39;&lt;/b&gt;</p>`},
+		{"attribute", `<p title="{{.v}}">x</p>`, VarString, `a" onclick="x`, `<p title="a&#34; onclick=&#34;x">x</p>`},
+		{"unquoted attribute", `<p title={{.v}}>x</p>`, VarString, `a b`, `<p title=a&#32;b>x</p>`},
+		{"url", `<a href="{{.v}}">x</a>`, VarString, `javascript:alert(1)`, `<a href="#ZgotmplZ">x</a>`},
+		{"url query", `<a href="/s?q={{.v}}">x</a>`, VarString, `a&b c`, `<a href="/s?q=a%26b%20c">x</a>`},
+		{"script", `<script>var v = {{.v}};</script>`, VarString, `</script>`, `<script>var v = "\u003c/script\u003e";</script>`},
+		{"html", `<div>{{.v}}</div>`, VarHTML, `<b>bold</b>`, `<div><b>bold</b></div>`},
+		{"list", `<ul>{{range .v}}<li>{{.}}</li>{{end}}</ul>`, VarList, []interface{}{"a", "<b>"}, `<ul><li>a</li><li>&lt;b&gt;</li></ul>`},
+		{"number", `<p>{{.v}}</p>`, VarNumber, 2.5, `<p>2.5</p>`},
+
+		// A string in CSS takes keywords, colors and lengths only
+		{"string keyword in style", `<style>h1{color:{{.v}}}</style>`, VarString, "red", `<style>h1{color:red}</style>`},
+		{"string hex color in style attribute", `<p style="color: {{.v}}">x</p>`, VarString, "#ff0000", `<p style="color: #ff0000">x</p>`},
+		{"string function in style", `<style>h1{color:{{.v}}}</style>`, VarString, "rgb(1, 2, 3)", `<style>h1{color:ZgotmplZ}</style>`},
+		{"string declaration in style attribute", `<p style="color: {{.v}}">x</p>`, VarString, "red; top: 0", `<p style="color: ZgotmplZ">x</p>`},
+		{"string inside a CSS string", `<style>h1{font-family:"{{.v}}"}</style>`, VarString, `a"}`, `<style>h1{font-family:"a\22\7d "}</style>`},
+
+		// css variables carry other values
+		{"css function in style", `<style>h1{color:{{.v}}}</style>`, VarCSS, "rgb(1, 2, 3)", `<style>h1{color:rgb(1, 2, 3)}</style>`},
+		{"css var in style attribute", `<p style="color: {{.v}}">x</p>`, VarCSS, "var(--color-primary)", `<p style="color: var(--color-primary)">x</p>`},
+		{"css calc in style attribute", `<p style="width: {{.v}}">x</p>`, VarCSS, "calc(100% - 4px)", `<p style="width: calc(100% - 4px)">x</p>`},
+		{"css as text", `<p>{{.v}}</p>`, VarCSS, "calc(1px + 2px)", `<p>calc(1px &#43; 2px)</p>`},
+	}
+
+	for _, tt := range tests {
+		spec := &TemplateSpec{Variables: []*TemplateVariable{{Name: "v", Type: tt.typ}}}
+		tmpl, err := parseTemplate(tt.html, spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got, err := executeTemplate(tmpl, spec, map[string]interface{}{"v": tt.value})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteTemplateRejectsUnsafeCSS(t *testing.T) {
+	spec := &TemplateSpec{Variables: []*TemplateVariable{{Name: "v", Type: VarCSS}}}
+	tmpl, err := parseTemplate(`<p style="color: {{.v}}">x</p>`, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"red; position: fixed", `red" onclick="x`, "red}</style><script>"} {
+		if _, err := executeTemplate(tmpl, spec, map[string]interface{}{"v": value}); err == nil || !strings.Contains(err.Error(), "v must be a CSS value") {
+			t.Errorf("%q: got error %v, want a CSS value error", value, err)
+		}
+	}
+}
+
+func TestCoerceValues(t *testing.T) {
+	spec := &TemplateSpec{Variables: []*TemplateVariable{
+		{Name: "s"}, {Name: "n", Type: VarNumber}, {Name: "b", Type: VarBoolean}, {Name: "l", Type: VarList}, {Name: "c", Type: VarCSS},
+	}}
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+		want  map[string]interface{}
+		err   string
+	}{
+		{"text cells", map[string]interface{}{"s": " hi ", "n": "2.5", "b": "true", "c": "rgb(0, 0, 0)"},
+			map[string]interface{}{"s": " hi ", "n": 2.5, "b": true, "c": "rgb(0, 0, 0)"}, ""},
+		{"empty cells use defaults", map[string]interface{}{"s": "", "n": "  "}, map[string]interface{}{}, ""},
+		{"typed values pass through", map[string]interface{}{"n": 3.0, "x": "kept"}, map[string]interface{}{"n": 3.0, "x": "kept"}, ""},
+		{"bad number", map[string]interface{}{"n": "two"}, nil, "n must be a number"},
+		{"bad boolean", map[string]interface{}{"b": "maybe"}, nil, "b must be a boolean"},
+	}
+
+	for _, tt := range tests {
+		got, err := spec.CoerceValues(tt.input)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %#v, want %#v", tt.name, k, got[k], v)
+			}
+		}
+	}
+
+	got, err := spec.CoerceValues(map[string]interface{}{"l": "a | b|c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ := got["l"].([]interface{})
+	if len(items) != 3 || items[0] != "a" || items[1] != "b" || items[2] != "c" {
+		t.Errorf("list split into %#v, want a, b, c", got["l"])
+	}
+}
@@ -0,0 +1,129 @@
+package post
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Conflict strategies for importing a post whose ID is already taken
+const (
+	ConflictError     = "error"
+	ConflictRename    = "rename"
+	ConflictOverwrite = "overwrite"
+)
+
+// ImportOptions controls how imported post files are stored
+type ImportOptions struct {
+	PreserveID bool   // Keep the original post ID instead of generating a new one
+	OnConflict string // What to do when a preserved ID exists: error, rename or overwrite
+	Name       string // Optional new name for the imported post
+}
+
+// ExportFiles returns a post and all of its files for bundling
+func (s *Service) ExportFiles(postID string, includeVersions bool) (*ImagePost, []*PostFile, error) {
+	p, err := s.GetPost(postID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files, err := s.storage.ReadPostFiles(postID, includeVersions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read post files: %w", err)
+	}
+
+	return p, files, nil
+}
+
+// ImportFiles stores post files exported from sourceID as a post, choosing
+// the new ID according to opts. Media files are re-validated against the
+// media policy since bundles may come from untrusted sources.
+func (s *Service) ImportFiles(sourceID string, files []*PostFile, opts ImportOptions) (*ImagePost, error) {
+	var metadataFile *PostFile
+	hasHTML := false
+	for _, f := range files {
+		if !isPostFilePath(f.Path) {
+			return nil, fmt.Errorf("unexpected file in post: %s", f.Path)
+		}
+		switch {
+		case f.Path == "index.html":
+			hasHTML = true
+		case f.Path == "metadata.json":
+			metadataFile = f
+		case strings.HasPrefix(f.Path, "media/"):
+			if _, err := s.mediaPolicy.Inspect(f.Data); err != nil {
+				return nil, fmt.Errorf("invalid media file %s: %w", f.Path, err)
+			}
+		}
+	}
+	if !hasHTML || metadataFile == nil {
+		return nil, fmt.Errorf("post files must include index.html and metadata.json")
+	}
+
+	var metadata Metadata
+	if err := json.Unmarshal(metadataFile.Data, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata.json: %w", err)
+	}
+	if metadata.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("metadata.json schema version %d is newer than supported version %d", metadata.SchemaVersion, SchemaVersion)
+	}
+	if metadata.Width <= 0 || metadata.Height <= 0 {
+		return nil, fmt.Errorf("metadata.json must have positive width and height")
+	}
+	if opts.Name != "" && opts.Name != metadata.Name {
+		metadata.Name = opts.Name
+		data, err := json.MarshalIndent(&metadata, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		metadataFile.Data = data
+	}
+	if metadata.Name == "" {
+		return nil, fmt.Errorf("metadata.json must have a name")
+	}
+
+	onConflict := opts.OnConflict
+	if onConflict == "" {
+		onConflict = ConflictError
+	}
+	if onConflict != ConflictError && onConflict != ConflictRename && onConflict != ConflictOverwrite {
+		return nil, fmt.Errorf("invalid conflict strategy %q: use %s, %s or %s", onConflict, ConflictError, ConflictRename, ConflictOverwrite)
+	}
+
+	postID := GeneratePostID(metadata.Name, s.storage.PostExists)
+	if opts.PreserveID {
+		if !ValidatePostID(sourceID) {
+			return nil, fmt.Errorf("invalid post ID: %s", sourceID)
+		}
+		switch {
+		case !s.storage.PostExists(sourceID), onConflict == ConflictOverwrite:
+			postID = sourceID
+		case onConflict == ConflictError:
+			return nil, fmt.Errorf("post %s already exists", sourceID)
+		}
+		// ConflictRename keeps the generated ID
+	}
+
+	if err := s.storage.WritePostFiles(postID, files); err != nil {
+		return nil, fmt.Errorf("failed to write post files: %w", err)
+	}
+
+	return s.GetPost(postID)
+}
+
+// isPostFilePath reports whether p is a file that may appear in a post directory
+func isPostFilePath(p string) bool {
+	switch p {
+	case "index.html", "metadata.json", "media.json":
+		return true
+	}
+	if path.Clean(p) != p {
+		return false
+	}
+	dir, name := path.Split(p)
+	if name == "" || strings.HasPrefix(name, ".") {
+		return false
+	}
+	return dir == "media/" || dir == "versions/"
+}
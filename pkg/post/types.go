@@ -4,31 +4,345 @@ import "time"
 
 // ImagePost represents an HTML image post with fixed canvas dimensions
 type ImagePost struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	HTMLContent string          `json:"html_content"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	History     []*HistoryEntry `json:"history,omitempty"`
+	Exports     []*ExportRecord `json:"exports,omitempty"`
+	PostDetails
+}
+
+// SchemaVersion is the metadata.json schema version written by this build.
+// metadata.json files without a version are version 1.
+const SchemaVersion = 2
+
+// Metadata represents post metadata stored in metadata.json
+type Metadata struct {
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	Name          string          `json:"name"`
+	Width         int             `json:"width"`
+	Height        int             `json:"height"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	History       []*HistoryEntry `json:"history,omitempty"`
+	Exports       []*ExportRecord `json:"exports,omitempty"`
+	PostDetails
+}
+
+// ExportRecord records a rendered export of a post
+type ExportRecord struct {
+	OutputPath  string    `json:"output_path"`
+	Format      string    `json:"format"`
+	Scale       float64   `json:"scale"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"` // Canvas size the export was rendered at
+	Height      int       `json:"height,omitempty"`
+	HTMLHash    string    `json:"html_hash"`
+	MediaDigest string    `json:"media_digest,omitempty"` // HashMedia of the post's media at export time
+	ExportedAt  time.Time `json:"exported_at"`
+}
+
+// PostDetails holds the descriptive and organizing metadata a user can edit after creation
+type PostDetails struct {
+	Description  string            `json:"description,omitempty"`
+	Caption      string            `json:"caption,omitempty"`
+	AltText      string            `json:"alt_text,omitempty"`
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	Collection   string            `json:"collection,omitempty"`
+	Template     *TemplateSpec     `json:"template,omitempty"`  // Set on template posts
+	BrandKit     string            `json:"brand_kit,omitempty"` // ID of the applied brand kit
+}
+
+// Collection is a named group of posts, such as a campaign or client
+type Collection struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
-	HTMLContent string    `json:"html_content"`
-	Width       int       `json:"width"`
-	Height      int       `json:"height"`
+	Description string    `json:"description,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
 }
 
-// Metadata represents post metadata stored in metadata.json
-type Metadata struct {
+// BrandKit holds the design tokens and logos shared by a brand's posts
+type BrandKit struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Palette     map[string]string `json:"palette,omitempty"`    // Color name to CSS color
+	Typography  map[string]string `json:"typography,omitempty"` // Role to CSS font-family
+	Spacing     map[string]string `json:"spacing,omitempty"`    // Token to CSS length
+	Logos       []*BrandLogo      `json:"logos,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// BrandLogo is a logo file of a brand kit. Posts using the kit reference it
+// as media/Filename.
+type BrandLogo struct {
+	Name        string `json:"name"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+// BrandKitUpdate lists brand kit edits; nil fields are left unchanged. Tokens
+// and logos set to an empty string are removed. Logos map names to source
+// file paths.
+type BrandKitUpdate struct {
+	Name        *string
+	Description *string
+	Palette     map[string]string
+	Typography  map[string]string
+	Spacing     map[string]string
+	Logos       map[string]string
+}
+
+// Component is a reusable HTML/CSS snippet. Posts include it with
+// <x-component name="..." param="...">, which is expanded before storage.
+type Component struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	HTML        string            `json:"html"`          // html/template over the parameters and .content
+	CSS         string            `json:"css,omitempty"` // Added once to every post using the component
+	Params      []*ComponentParam `json:"params,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// ComponentParam declares a parameter of a component, passed as an attribute
+// of its <x-component> tag
+type ComponentParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Sort fields for ListOptions
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortName    = "name"
+)
+
+// ListOptions filters, sorts and paginates the posts returned by ListPosts.
+// Zero values disable the corresponding filter.
+type ListOptions struct {
+	Query         string    // Words that must all appear in the name or HTML text
+	Tags          []string  // Only posts with all of these tags
+	Collection    string    // Only posts in the collection with this ID
+	Width         int       // Only posts with this canvas width
+	Height        int       // Only posts with this canvas height
+	CreatedAfter  time.Time // Only posts created at or after this time
+	CreatedBefore time.Time // Only posts created before this time
+	UpdatedAfter  time.Time // Only posts updated at or after this time
+	UpdatedBefore time.Time // Only posts updated before this time
+	SortBy        string    // created, updated (default) or name
+	Ascending     bool      // Sort oldest or A-Z first instead of newest or Z-A first
+	Limit         int       // Maximum number of posts to return; 0 returns all
+	Cursor        string    // NextCursor from a previous page
+}
+
+// PostPage is one page of ListPosts results
+type PostPage struct {
+	Posts      []*PostInfo
+	Total      int    // Number of posts matching the filters across all pages
+	NextCursor string // Cursor for the next page, empty on the last page
+}
+
+// MetadataUpdate lists metadata edits; nil fields are left unchanged. A
+// custom field set to nil or an empty string is removed.
+type MetadataUpdate struct {
+	Name         *string
+	Description  *string
+	Caption      *string
+	AltText      *string
+	CustomFields map[string]*string
+}
+
+// HistoryEntry records a structural change to a post, such as a canvas resize
+type HistoryEntry struct {
+	Action  string                 `json:"action"`
+	At      time.Time              `json:"at"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// PostInfo is a lightweight post summary for listing
+type PostInfo struct {
+	ID        string    `json:"id"`
 	Name      string    `json:"name"`
 	Width     int       `json:"width"`
 	Height    int       `json:"height"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	FilePath  string    `json:"file_path"`
+	PostDetails
 }
 
-// PostInfo is a lightweight post summary for listing
-type PostInfo struct {
+// MediaInfo describes a validated media file in a post's media folder
+type MediaInfo struct {
+	Filename    string    `json:"filename"`
+	Path        string    `json:"path"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"`     // Hex digest of the file contents
+	BrandLogo   string    `json:"brand_logo,omitempty"` // Set on copies of brand kit logos to the logo name
+	AddedAt     time.Time `json:"added_at"`
+}
+
+// MediaManifest is the per-post media index stored in media.json
+type MediaManifest struct {
+	Files []*MediaInfo `json:"files"`
+}
+
+// TrashedPost is a soft-deleted post that can be restored or purged
+type TrashedPost struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
 	Width     int       `json:"width"`
 	Height    int       `json:"height"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	FilePath  string    `json:"file_path"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// VersionInfo describes a saved snapshot of a post's previous HTML
+type VersionInfo struct {
+	ID      string    `json:"id"`
+	SavedAt time.Time `json:"saved_at"`
+	Size    int64     `json:"size"`
+}
+
+// PostFile is a file belonging to a post, addressed by its slash separated
+// path relative to the post directory (e.g. "media/photo.png")
+type PostFile struct {
+	Path string
+	Data []byte
+}
+
+// MigrationResult describes the schema migrations applied, or pending in a
+// dry run, for one post
+type MigrationResult struct {
+	PostID      string   `json:"post_id"`
+	FromVersion int      `json:"from_version"`
+	ToVersion   int      `json:"to_version"`
+	Steps       []string `json:"steps,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// MigrationReport summarizes a bulk schema migration
+type MigrationReport struct {
+	DryRun         bool               `json:"dry_run"`
+	CurrentVersion int                `json:"current_version"`
+	Migrated       int                `json:"migrated"`
+	UpToDate       int                `json:"up_to_date"`
+	Failed         int                `json:"failed"`
+	Posts          []*MigrationResult `json:"posts"`
+}
+
+// Storage check repair modes
+const (
+	RepairNone       = ""           // Only report problems
+	RepairRegenerate = "regenerate" // Rebuild metadata and manifests from defaults and the files present
+	RepairQuarantine = "quarantine" // Move broken directories aside for manual inspection
+	RepairDelete     = "delete"     // Delete broken directories
+)
+
+// StorageIssue is a problem found by a storage check
+type StorageIssue struct {
+	Kind        string   `json:"kind"`
+	Path        string   `json:"path"`
+	PostID      string   `json:"post_id,omitempty"`
+	Message     string   `json:"message"`
+	Repairs     []string `json:"repairs,omitempty"`
+	Repaired    string   `json:"repaired,omitempty"`
+	RepairError string   `json:"repair_error,omitempty"`
+}
+
+// StorageCheckReport summarizes a storage check
+type StorageCheckReport struct {
+	Repair       string          `json:"repair,omitempty"`
+	CheckedPosts int             `json:"checked_posts"`
+	Issues       []*StorageIssue `json:"issues"`
+	Repaired     int             `json:"repaired"`
+}
+
+// RetentionPolicy selects what garbage collection removes. Zero values disable a rule.
+type RetentionPolicy struct {
+	MaxAgeDays   int `json:"max_age_days,omitempty"`  // Delete posts not updated in this many days
+	KeepVersions int `json:"keep_versions,omitempty"` // Keep only the newest versions of each post
+}
+
+// Garbage collection actions
+const (
+	GCDeletePost    = "delete_post"
+	GCDeleteVersion = "delete_version"
+)
+
+// GCAction is one deletion made, or planned in a dry run, by garbage collection
+type GCAction struct {
+	Action     string `json:"action"`
+	PostID     string `json:"post_id"`
+	VersionID  string `json:"version_id,omitempty"`
+	Collection string `json:"collection,omitempty"` // Collection a deleted post was removed from
+	Bytes      int64  `json:"bytes"`
+}
+
+// GCReport summarizes a garbage collection run
+type GCReport struct {
+	DryRun          bool            `json:"dry_run"`
+	Policy          RetentionPolicy `json:"policy"`
+	DeletedPosts    int             `json:"deleted_posts"`
+	DeletedVersions int             `json:"deleted_versions"`
+	FreedBytes      int64           `json:"freed_bytes"`
+	Actions         []*GCAction     `json:"actions"`
+}
+
+// Revision is a recorded change in git-backed storage
+type Revision struct {
+	Hash    string    `json:"hash"`
+	Message string    `json:"message"`
+	Author  string    `json:"author"`
+	At      time.Time `json:"at"`
+}
+
+// UsageBreakdown splits a post's or a workspace's bytes by kind
+type UsageBreakdown struct {
+	HTMLBytes     int64 `json:"html_bytes"`
+	MediaBytes    int64 `json:"media_bytes"`
+	VersionBytes  int64 `json:"version_bytes"`
+	MetadataBytes int64 `json:"metadata_bytes"`
+	ExportBytes   int64 `json:"export_bytes"` // Recorded exports; these live outside storage
+	TotalBytes    int64 `json:"total_bytes"`  // Stored bytes, excluding exports
+}
+
+// PostUsage reports the bytes used by one post
+type PostUsage struct {
+	PostID      string `json:"post_id"`
+	Name        string `json:"name"`
+	ExportCount int    `json:"export_count"`
+	UsageBreakdown
+}
+
+// MediaUsage reports the size of one media file
+type MediaUsage struct {
+	PostID string `json:"post_id"`
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// StorageUsage reports the bytes used by the posts in storage
+type StorageUsage struct {
+	PostCount    int            `json:"post_count"`
+	Posts        []*PostUsage   `json:"posts"`
+	Totals       UsageBreakdown `json:"totals"`
+	TrashBytes   int64          `json:"trash_bytes"`
+	LargestMedia []*MediaUsage  `json:"largest_media"`
 }
@@ -0,0 +1,97 @@
+package screenshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Output formats supported by TakeScreenshot
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+const (
+	// DefaultScale is the device scale factor used for high-res output
+	DefaultScale = 2.0
+	// MaxScale limits the device scale factor to keep renders a reasonable size
+	MaxScale = 4.0
+	// DefaultQuality is the compression quality for jpeg and webp output
+	DefaultQuality = 90
+)
+
+// Options controls the encoding of a screenshot. Zero values select PNG at
+// DefaultScale.
+type Options struct {
+	Format  string  // png, jpeg or webp
+	Scale   float64 // Device scale factor; output pixels are canvas size times Scale
+	Quality int     // 1-100, for jpeg and webp only
+}
+
+// WithDefaults returns o with zero fields replaced by their defaults
+func (o Options) WithDefaults() Options {
+	if o.Format == "" {
+		o.Format = FormatPNG
+	}
+	if o.Scale == 0 {
+		o.Scale = DefaultScale
+	}
+	if o.Quality == 0 && o.Format != FormatPNG {
+		o.Quality = DefaultQuality
+	}
+	return o
+}
+
+// Validate checks that the options are supported
+func (o Options) Validate() error {
+	switch o.Format {
+	case "", FormatPNG, FormatJPEG, FormatWebP:
+	default:
+		return fmt.Errorf("unsupported format %q: use %s, %s or %s", o.Format, FormatPNG, FormatJPEG, FormatWebP)
+	}
+	if o.Scale < 0 || o.Scale > MaxScale {
+		return fmt.Errorf("scale must be between 0 and %g", MaxScale)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// FormatFromPath picks the output format from a file extension, defaulting to png
+func FormatFromPath(path string) string {
+	if format, ok := FormatOfExtension(path); ok {
+		return format
+	}
+	return FormatPNG
+}
+
+// FormatOfExtension returns the output format written to files with path's
+// extension. ok is false when the extension isn't one of an output format,
+// such as in a name template ending in "{{.slug}}".
+func FormatOfExtension(path string) (format string, ok bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return FormatPNG, true
+	case ".jpg", ".jpeg":
+		return FormatJPEG, true
+	case ".webp":
+		return FormatWebP, true
+	default:
+		return "", false
+	}
+}
+
+// Extension returns the file extension, with its dot, written for a format
+func Extension(format string) string {
+	switch format {
+	case FormatJPEG:
+		return ".jpg"
+	case FormatWebP:
+		return ".webp"
+	default:
+		return ".png"
+	}
+}
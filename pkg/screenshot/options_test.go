@@ -0,0 +1,27 @@
+package screenshot
+
+import "testing"
+
+func TestFormatOfExtension(t *testing.T) {
+	tests := []struct {
+		path   string
+		format string
+		ok     bool
+	}{
+		{"out.png", FormatPNG, true},
+		{"out.JPG", FormatJPEG, true},
+		{"out.jpeg", FormatJPEG, true},
+		{"dir/out.webp", FormatWebP, true},
+		{"{{.slug}}", "", false},
+		{"{{.title}}-{{.row}}", "", false},
+		{"out.gif", "", false},
+		{"out", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		format, ok := FormatOfExtension(tt.path)
+		if format != tt.format || ok != tt.ok {
+			t.Errorf("FormatOfExtension(%q) = %q, %v, want %q, %v", tt.path, format, ok, tt.format, tt.ok)
+		}
+	}
+}
@@ -3,13 +3,17 @@ package screenshot
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"html_image_creator/pkg/media"
+
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
@@ -27,8 +31,64 @@ func NewScreenshotter() *Screenshotter {
 	}
 }
 
-// TakeScreenshot renders an HTML post at exact dimensions and saves as PNG
-func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, outputPath string) error {
+// TakeScreenshot renders an HTML post at exact dimensions and saves it in
+// the format and scale given by opts, using a browser launched for this call
+func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, outputPath string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	session, err := s.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	return session.TakeScreenshot(postDir, width, height, outputPath, opts)
+}
+
+// Session is a headless Chrome instance shared by several screenshots
+type Session struct {
+	launcher *launcher.Launcher
+	browser  *rod.Browser
+	timeout  time.Duration
+}
+
+// NewSession launches headless Chrome for a series of screenshots. The
+// caller must Close the session.
+func (s *Screenshotter) NewSession() (*Session, error) {
+	l := launcher.New().Headless(true)
+	if chromePath, _ := launcher.LookPath(); chromePath != "" {
+		l = l.Bin(chromePath)
+	}
+	controlURL, err := l.Launch()
+	if err != nil {
+		return nil, fmt.Errorf("chrome not available: %w", err)
+	}
+
+	browser := rod.New().ControlURL(controlURL)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		return nil, fmt.Errorf("chrome not available: %w", err)
+	}
+	return &Session{launcher: l, browser: browser, timeout: s.chromeTimeout}, nil
+}
+
+// Close shuts down the session's browser and removes its profile directory
+func (s *Session) Close() error {
+	err := s.browser.Close()
+	s.launcher.Cleanup()
+	return err
+}
+
+// TakeScreenshot renders an HTML post at exact dimensions in a new tab of
+// the session's browser and saves it in the format and scale given by opts
+func (s *Session) TakeScreenshot(postDir string, width, height int, outputPath string, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	opts = opts.WithDefaults()
+
 	// Read and prepare HTML with CSS reset
 	htmlPath := filepath.Join(postDir, "index.html")
 	htmlBytes, err := os.ReadFile(htmlPath)
@@ -52,31 +112,14 @@ func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, output
 	}
 	port := listener.Addr().(*net.TCPAddr).Port
 
-	fileServer := http.FileServer(http.Dir(postDir))
-	httpServer := &http.Server{Handler: fileServer}
+	httpServer := &http.Server{Handler: sniffingFileServer(postDir)}
 	go httpServer.Serve(listener)
 	defer httpServer.Close()
 
-	// Launch headless Chrome
-	ctx, cancel := context.WithTimeout(context.Background(), s.chromeTimeout)
+	// Each screenshot gets its own tab and time limit
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
-
-	chromePath, _ := launcher.LookPath()
-
-	var controlURL string
-	if chromePath != "" {
-		l := launcher.New().Bin(chromePath).Headless(true)
-		controlURL = l.MustLaunch()
-	} else {
-		l := launcher.New().Headless(true)
-		controlURL = l.MustLaunch()
-	}
-
-	browser := rod.New().ControlURL(controlURL).Context(ctx)
-	if err := browser.Connect(); err != nil {
-		return fmt.Errorf("chrome not available: %w", err)
-	}
-	defer browser.MustClose()
+	browser := s.browser.Context(ctx)
 
 	// Create page and set viewport to exact canvas dimensions
 	pageURL := fmt.Sprintf("http://127.0.0.1:%d/temp_screenshot.html", port)
@@ -84,12 +127,13 @@ func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, output
 	if err != nil {
 		return fmt.Errorf("failed to create page: %w", err)
 	}
+	defer page.Close()
 
-	// Set exact viewport dimensions with 2x scale for high-res output
+	// Set exact viewport dimensions, scaled for high-res output
 	err = page.SetViewport(&proto.EmulationSetDeviceMetricsOverride{
 		Width:             width,
 		Height:            height,
-		DeviceScaleFactor: 2,
+		DeviceScaleFactor: opts.Scale,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to set viewport: %w", err)
@@ -108,8 +152,8 @@ func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, output
 	}
 
 	// Take screenshot of the viewport
-	screenshotData, err := page.Screenshot(true, &proto.PageCaptureScreenshot{
-		Format: proto.PageCaptureScreenshotFormatPng,
+	req := &proto.PageCaptureScreenshot{
+		Format: proto.PageCaptureScreenshotFormat(opts.Format),
 		Clip: &proto.PageViewport{
 			X:      0,
 			Y:      0,
@@ -117,7 +161,11 @@ func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, output
 			Height: float64(height),
 			Scale:  1,
 		},
-	})
+	}
+	if opts.Format != FormatPNG {
+		req.Quality = &opts.Quality
+	}
+	screenshotData, err := page.Screenshot(true, req)
 	if err != nil {
 		return fmt.Errorf("failed to take screenshot: %w", err)
 	}
@@ -128,7 +176,7 @@ func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, output
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Write PNG to output path
+	// Write image to output path
 	if err := os.WriteFile(outputPath, screenshotData, 0644); err != nil {
 		return fmt.Errorf("failed to write screenshot: %w", err)
 	}
@@ -136,6 +184,27 @@ func (s *Screenshotter) TakeScreenshot(postDir string, width, height int, output
 	return nil
 }
 
+// sniffingFileServer serves dir like http.FileServer, but detects the type of
+// files without an extension, such as brand kit logos, from their contents so
+// that SVG is served as an image rather than text
+func sniffingFileServer(dir string) http.Handler {
+	root := http.Dir(dir)
+	fileServer := http.FileServer(root)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Ext(r.URL.Path) == "" {
+			if f, err := root.Open(r.URL.Path); err == nil {
+				head := make([]byte, 1024)
+				n, _ := io.ReadFull(f, head)
+				f.Close()
+				if n > 0 {
+					w.Header().Set("Content-Type", media.SniffContentType(head[:n]))
+				}
+			}
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
 // injectCSSReset injects a CSS reset to ensure accurate viewport rendering
 func injectCSSReset(htmlContent string) string {
 	cssReset := `<style>html,body{margin:0;padding:0;overflow:hidden;}</style>`
@@ -0,0 +1,53 @@
+package screenshot
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSniffingFileServer(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"temp_screenshot.html": "<p>post</p>",
+		"media/brand/logo":     `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"></svg>`,
+		"media/brand/mark":     "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"media/notes.txt":      "<svg> in a text file",
+		"media/photo.svg":      `<svg xmlns="http://www.w3.org/2000/svg"></svg>`,
+		"media/brand/unknown":  "plain words",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/media/brand/logo", "image/svg+xml"},
+		{"/media/brand/mark", "image/png"},
+		{"/media/brand/unknown", "text/plain"},
+		{"/media/notes.txt", "text/plain; charset=utf-8"},
+		{"/media/photo.svg", "image/svg+xml"},
+		{"/temp_screenshot.html", "text/html; charset=utf-8"},
+	}
+	server := sniffingFileServer(dir)
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != 200 {
+			t.Errorf("%s: status %d", tt.path, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: Content-Type %q, want %q", tt.path, got, tt.contentType)
+		}
+	}
+}
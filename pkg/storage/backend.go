@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by backends when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend is an object store holding post files. Keys are slash separated
+// paths relative to the store root, e.g. "my-post-a1b2/index.html".
+type Backend interface {
+	// Read returns the contents of key, or an error wrapping ErrNotFound
+	Read(key string) ([]byte, error)
+	// Write creates or replaces key
+	Write(key string, data []byte) error
+	// Delete removes key; deleting a missing key is not an error
+	Delete(key string) error
+	// DeletePrefix removes every object under prefix
+	DeletePrefix(prefix string) error
+	// Stat returns object info for key, or an error wrapping ErrNotFound
+	Stat(key string) (*ObjectInfo, error)
+	// List returns every object under prefix, recursively
+	List(prefix string) ([]ObjectInfo, error)
+	// ListDirs returns the names of the immediate sub-directories of prefix
+	ListDirs(prefix string) ([]string, error)
+	// Location returns a human readable location for key, such as a file path or URL
+	Location(key string) string
+}
+
+// LocalPather is implemented by backends whose objects are plain files on
+// the local filesystem, so they can be rendered in place
+type LocalPather interface {
+	LocalPath(key string) (string, error)
+}
+
+// Mover is implemented by backends that can move a prefix more cheaply than
+// copying each object
+type Mover interface {
+	Move(srcPrefix, dstPrefix string) error
+}
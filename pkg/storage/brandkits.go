@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"html_image_creator/pkg/post"
+	"path"
+	"sort"
+)
+
+// brandKitsDir is the root-level directory holding brand kits, one
+// sub-directory per kit with its kit.json and logo files
+const brandKitsDir = ".brandkits"
+
+// brandKitKey returns the key of a file of a brand kit
+func brandKitKey(kitID, filename string) (string, error) {
+	if kitID == "" || kitID != path.Base(kitID) || filename != path.Base(filename) || filename == "." || filename == ".." {
+		return "", fmt.Errorf("invalid brand kit file %s/%s", kitID, filename)
+	}
+	return path.Join(brandKitsDir, kitID, filename), nil
+}
+
+// ReadBrandKits returns all brand kits sorted by ID
+func (s *Storage) ReadBrandKits() ([]*post.BrandKit, error) {
+	dirs, err := s.backend.ListDirs(brandKitsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read brand kits directory: %w", err)
+	}
+	sort.Strings(dirs)
+
+	var kits []*post.BrandKit
+	for _, dir := range dirs {
+		key, err := brandKitKey(dir, "kit.json")
+		if err != nil {
+			continue
+		}
+		data, err := s.backend.Read(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read brand kit %s: %w", dir, err)
+		}
+		var kit post.BrandKit
+		if err := json.Unmarshal(data, &kit); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal brand kit %s: %w", dir, err)
+		}
+		kits = append(kits, &kit)
+	}
+	return kits, nil
+}
+
+// ReadBrandLogo returns the contents of a logo file of a brand kit
+func (s *Storage) ReadBrandLogo(kitID, filename string) ([]byte, error) {
+	key, err := brandKitKey(kitID, filename)
+	if err != nil {
+		return nil, err
+	}
+	return s.backend.Read(key)
+}
+
+// WriteBrandKit writes a brand kit's kit.json along with new logo files,
+// keyed by filename, and deletes the removed logo files
+func (s *Storage) WriteBrandKit(kit *post.BrandKit, logos map[string][]byte, removed []string) error {
+	var touched []string
+	for filename, data := range logos {
+		key, err := brandKitKey(kit.ID, filename)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.Write(key, data); err != nil {
+			return fmt.Errorf("failed to write logo %s: %w", filename, err)
+		}
+		touched = append(touched, key)
+	}
+	for _, filename := range removed {
+		key, err := brandKitKey(kit.ID, filename)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete logo %s: %w", filename, err)
+		}
+		touched = append(touched, key)
+	}
+
+	data, err := json.MarshalIndent(kit, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal brand kit: %w", err)
+	}
+	key, err := brandKitKey(kit.ID, "kit.json")
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Write(key, data); err != nil {
+		return fmt.Errorf("failed to write brand kit file: %w", err)
+	}
+	return s.commit(append(touched, key), "Save brand kit %s", kit.ID)
+}
+
+// DeleteMediaFile removes a media file from a post and its media manifest
+func (s *Storage) DeleteMediaFile(postID, filename string) error {
+	key, err := postKey(postID, "media", filename)
+	if err != nil {
+		return err
+	}
+	if err := s.deleteObject(key); err != nil {
+		return fmt.Errorf("failed to delete media file: %w", err)
+	}
+	if err := s.dropManifestEntry(postID, filename); err != nil {
+		return fmt.Errorf("failed to update media manifest: %w", err)
+	}
+	return s.commit([]string{key, mediaManifestKey(postID)}, "Remove media %s from post %s", filename, postID)
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html_image_creator/pkg/post"
+)
+
+// collectionsKey is the root-level file listing all collections
+const collectionsKey = "collections.json"
+
+// collectionsFile is the structure of collections.json
+type collectionsFile struct {
+	Collections []*post.Collection `json:"collections"`
+}
+
+// ReadCollections returns all collections, or none if none have been created
+func (s *Storage) ReadCollections() ([]*post.Collection, error) {
+	data, err := s.backend.Read(collectionsKey)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections file: %w", err)
+	}
+
+	var file collectionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal collections: %w", err)
+	}
+	return file.Collections, nil
+}
+
+// WriteCollections replaces the list of collections
+func (s *Storage) WriteCollections(collections []*post.Collection) error {
+	data, err := json.MarshalIndent(collectionsFile{Collections: collections}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal collections: %w", err)
+	}
+	if err := s.backend.Write(collectionsKey, data); err != nil {
+		return fmt.Errorf("failed to write collections file: %w", err)
+	}
+	return s.commit([]string{collectionsKey}, "Update collections")
+}
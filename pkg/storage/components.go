@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"html_image_creator/pkg/post"
+	"path"
+	"sort"
+	"strings"
+)
+
+// componentsDir is the root-level directory holding one <name>.json file per component
+const componentsDir = ".components"
+
+// ReadComponents returns all components sorted by name
+func (s *Storage) ReadComponents() ([]*post.Component, error) {
+	objects, err := s.backend.List(componentsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list components: %w", err)
+	}
+
+	var components []*post.Component
+	for _, obj := range objects {
+		if path.Dir(obj.Key) != componentsDir || !strings.HasSuffix(obj.Key, ".json") {
+			continue
+		}
+		data, err := s.backend.Read(obj.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read component %s: %w", obj.Key, err)
+		}
+		var c post.Component
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal component %s: %w", obj.Key, err)
+		}
+		components = append(components, &c)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components, nil
+}
+
+// WriteComponent creates or replaces a component
+func (s *Storage) WriteComponent(c *post.Component) error {
+	if c.Name == "" || c.Name != path.Base(c.Name) || strings.HasPrefix(c.Name, ".") {
+		return fmt.Errorf("invalid component name: %s", c.Name)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal component: %w", err)
+	}
+	key := path.Join(componentsDir, c.Name+".json")
+	if err := s.backend.Write(key, data); err != nil {
+		return fmt.Errorf("failed to write component file: %w", err)
+	}
+	return s.commit([]string{key}, "Register component %s", c.Name)
+}
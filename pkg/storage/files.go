@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/post"
+	"path"
+	"strings"
+)
+
+// stagingDir holds post files while WritePostFiles writes them, so a failed
+// write never leaves a half-replaced post. Like the trash, its name can never
+// collide with a post ID.
+const stagingDir = ".staging"
+
+// transientFiles are written into post directories while rendering and never
+// belong to the post itself
+var transientFiles = map[string]bool{
+	"temp_screenshot.html": true,
+}
+
+// ReadPostFiles returns every file of a post with paths relative to the post
+// directory. Version snapshots are only included when includeVersions is set.
+func (s *Storage) ReadPostFiles(postID string, includeVersions bool) ([]*post.PostFile, error) {
+	if !s.PostExists(postID) {
+		return nil, fmt.Errorf("post %s does not exist", postID)
+	}
+
+	objects, err := s.backend.List(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list post files: %w", err)
+	}
+
+	var files []*post.PostFile
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Key, postID+"/")
+		if transientFiles[rel] {
+			continue
+		}
+		if !includeVersions && strings.HasPrefix(rel, "versions/") {
+			continue
+		}
+		data, err := s.backend.Read(obj.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", obj.Key, err)
+		}
+		files = append(files, &post.PostFile{Path: rel, Data: data})
+	}
+
+	return files, nil
+}
+
+// WritePostFiles replaces the contents of a post directory with files
+func (s *Storage) WritePostFiles(postID string, files []*post.PostFile) error {
+	if _, err := postKey(postID); err != nil {
+		return err
+	}
+
+	existing, err := s.backend.List(postID)
+	if err != nil {
+		return fmt.Errorf("failed to list post directory: %w", err)
+	}
+	var growth int64
+	for _, obj := range existing {
+		growth -= obj.Size
+	}
+	for _, f := range files {
+		growth += int64(len(f.Data))
+	}
+	if err := s.checkQuota(postID, growth, !s.PostExists(postID)); err != nil {
+		return err
+	}
+
+	// Check every path before anything is written
+	hasHTML := false
+	for _, f := range files {
+		if _, err := postKey(postID, f.Path); err != nil {
+			return err
+		}
+		hasHTML = hasHTML || f.Path == "index.html"
+	}
+	if !hasHTML {
+		return fmt.Errorf("post files are missing index.html")
+	}
+
+	staged := path.Join(stagingDir, postID)
+	if err := s.backend.DeletePrefix(staged); err != nil {
+		return fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	for _, f := range files {
+		if err := s.backend.Write(path.Join(staged, f.Path), f.Data); err != nil {
+			s.backend.DeletePrefix(staged)
+			return fmt.Errorf("failed to write %s: %w", f.Path, err)
+		}
+	}
+
+	err = s.swapPrefix(staged, postID)
+	s.remeasureUsage(postID)
+	if err != nil {
+		s.backend.DeletePrefix(staged)
+		return err
+	}
+
+	return s.commit([]string{postID}, "Write files of post %s", postID)
+}
+
+// swapPrefix replaces the objects under dst with those under src. The old
+// objects are set aside first and put back if the move fails.
+func (s *Storage) swapPrefix(src, dst string) error {
+	existing, err := s.backend.List(dst)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dst, err)
+	}
+	if len(existing) == 0 {
+		if err := s.movePrefix(src, dst); err != nil {
+			s.backend.DeletePrefix(dst)
+			return fmt.Errorf("failed to move files into place: %w", err)
+		}
+		return nil
+	}
+
+	backup := src + ".old"
+	if err := s.backend.DeletePrefix(backup); err != nil {
+		return fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	if err := s.movePrefix(dst, backup); err != nil {
+		return fmt.Errorf("failed to set aside existing files: %w", err)
+	}
+	if err := s.movePrefix(src, dst); err != nil {
+		s.backend.DeletePrefix(dst)
+		if restoreErr := s.movePrefix(backup, dst); restoreErr != nil {
+			return fmt.Errorf("failed to move files into place: %w (previous files remain in %s: %v)", err, backup, restoreErr)
+		}
+		return fmt.Errorf("failed to move files into place: %w", err)
+	}
+	if err := s.backend.DeletePrefix(backup); err != nil {
+		return fmt.Errorf("failed to remove previous files: %w", err)
+	}
+	return nil
+}
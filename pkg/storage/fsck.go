@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/media"
+	"html_image_creator/pkg/post"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// quarantineDir is the root-level directory holding directories moved aside by CheckStorage
+const quarantineDir = ".quarantine"
+
+// regeneratedCanvasSize is the width and height given to posts whose metadata is regenerated
+const regeneratedCanvasSize = 1080
+
+// Storage issue kinds
+const (
+	IssueInvalidDir         = "invalid_dir"
+	IssueMissingHTML        = "missing_html"
+	IssueMissingMetadata    = "missing_metadata"
+	IssueCorruptMetadata    = "corrupt_metadata"
+	IssueCorruptManifest    = "corrupt_media_manifest"
+	IssueDanglingMedia      = "dangling_media"
+	IssueDanglingReference  = "dangling_reference"
+	IssueTempFile           = "temp_file"
+	IssueCorruptCollections = "corrupt_collections"
+)
+
+// fatalIssues make a directory unusable as a post; quarantine and delete act on them
+var fatalIssues = map[string]bool{
+	IssueInvalidDir:      true,
+	IssueMissingHTML:     true,
+	IssueMissingMetadata: true,
+	IssueCorruptMetadata: true,
+	IssueCorruptManifest: true,
+}
+
+// CheckStorage scans the root directory for broken posts and stray files and
+// applies the given repair mode to what it finds
+func (s *Storage) CheckStorage(repair string) (*post.StorageCheckReport, error) {
+	switch repair {
+	case post.RepairNone, post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete:
+	default:
+		return nil, fmt.Errorf("invalid repair mode %q: use %s, %s or %s", repair, post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete)
+	}
+
+	dirs, err := s.backend.ListDirs("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root directory: %w", err)
+	}
+
+	report := &post.StorageCheckReport{Repair: repair, Issues: []*post.StorageIssue{}}
+	for _, dir := range dirs {
+		// Trash, quarantine and other dot directories are managed separately
+		if strings.HasPrefix(dir, ".") {
+			continue
+		}
+
+		var issues []*post.StorageIssue
+		if !post.ValidatePostID(dir) {
+			issues = []*post.StorageIssue{{
+				Kind:    IssueInvalidDir,
+				Path:    dir,
+				Message: "directory name is not a valid post ID",
+				Repairs: []string{post.RepairQuarantine, post.RepairDelete},
+			}}
+		} else {
+			report.CheckedPosts++
+			if issues, err = s.checkPost(dir); err != nil {
+				return nil, err
+			}
+		}
+
+		if repair != post.RepairNone {
+			s.repairDir(dir, issues, repair)
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	if issue := s.checkCollections(); issue != nil {
+		if repair == post.RepairQuarantine || repair == post.RepairDelete {
+			s.repairFile(collectionsKey, issue, repair)
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	var touched []string
+	for _, issue := range report.Issues {
+		if issue.Repaired != "" {
+			report.Repaired++
+			// Regenerating rewrites other files of the post than issue.Path
+			if issue.PostID != "" {
+				touched = append(touched, issue.PostID)
+			} else {
+				touched = append(touched, issue.Path)
+			}
+		}
+	}
+	if report.Repaired > 0 {
+		s.remeasureUsage(touched...)
+		if err := s.commit(touched, "Repair storage (%s): %d issues", repair, report.Repaired); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// checkPost returns the problems with a post directory
+func (s *Storage) checkPost(postID string) ([]*post.StorageIssue, error) {
+	objects, err := s.backend.List(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", postID, err)
+	}
+	files := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		files[strings.TrimPrefix(obj.Key, postID+"/")] = true
+	}
+
+	var issues []*post.StorageIssue
+	add := func(kind, rel, message string, repairs ...string) {
+		issues = append(issues, &post.StorageIssue{
+			Kind:    kind,
+			Path:    path.Join(postID, rel),
+			PostID:  postID,
+			Message: message,
+			Repairs: repairs,
+		})
+	}
+
+	if !files["index.html"] {
+		add(IssueMissingHTML, "index.html", "post has no index.html", post.RepairQuarantine, post.RepairDelete)
+	}
+
+	if !files["metadata.json"] {
+		add(IssueMissingMetadata, "metadata.json", "post has no metadata.json", post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete)
+	} else if metadata, err := s.readRawMetadata(postID); err != nil {
+		add(IssueCorruptMetadata, "metadata.json", err.Error(), post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete)
+	} else if problem := metadataProblem(metadata); problem != "" {
+		add(IssueCorruptMetadata, "metadata.json", problem, post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete)
+	}
+
+	if files["media.json"] {
+		manifest, err := s.readMediaManifest(postID)
+		if err != nil {
+			add(IssueCorruptManifest, "media.json", err.Error(), post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete)
+		} else {
+			for _, info := range manifest.Files {
+				if !files[path.Join("media", info.Filename)] {
+					add(IssueDanglingMedia, path.Join("media", info.Filename), "media.json lists a file that does not exist", post.RepairRegenerate)
+				}
+			}
+		}
+	}
+
+	for name := range transientFiles {
+		if files[name] {
+			add(IssueTempFile, name, "leftover file from an interrupted render", post.RepairRegenerate, post.RepairQuarantine, post.RepairDelete)
+		}
+	}
+
+	if files["index.html"] {
+		html, err := s.backend.Read(htmlKey(postID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", htmlKey(postID), err)
+		}
+		for _, ref := range media.FindMediaRefs(string(html)) {
+			rel, err := url.PathUnescape(ref)
+			if err != nil {
+				rel = ref
+			}
+			if !files[path.Clean(rel)] {
+				add(IssueDanglingReference, rel, "index.html references a media file that does not exist")
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// metadataProblem describes invalid values in parsed metadata, or returns ""
+func metadataProblem(metadata *post.Metadata) string {
+	switch {
+	case schemaVersion(metadata) > post.SchemaVersion:
+		return fmt.Sprintf("schema version %d is newer than supported version %d", metadata.SchemaVersion, post.SchemaVersion)
+	case metadata.Name == "":
+		return "metadata has no name"
+	case metadata.Width <= 0 || metadata.Height <= 0:
+		return "metadata has no valid width and height"
+	}
+	return ""
+}
+
+// checkCollections reports a corrupt collections.json
+func (s *Storage) checkCollections() *post.StorageIssue {
+	if _, err := s.ReadCollections(); err != nil {
+		return &post.StorageIssue{
+			Kind:    IssueCorruptCollections,
+			Path:    collectionsKey,
+			Message: err.Error(),
+			Repairs: []string{post.RepairQuarantine, post.RepairDelete},
+		}
+	}
+	return nil
+}
+
+// repairDir applies a repair mode to the issues found in one directory.
+// Quarantine and delete act on the whole directory when it has a fatal
+// issue; temp files are removed under every mode.
+func (s *Storage) repairDir(dir string, issues []*post.StorageIssue, repair string) {
+	if repair == post.RepairQuarantine || repair == post.RepairDelete {
+		for _, issue := range issues {
+			if !fatalIssues[issue.Kind] {
+				continue
+			}
+			var err error
+			if repair == post.RepairQuarantine {
+				err = s.quarantine(dir)
+			} else {
+				err = s.backend.DeletePrefix(dir)
+			}
+			for _, issue := range issues {
+				markRepair(issue, repair, err)
+			}
+			return
+		}
+	}
+
+	for _, issue := range issues {
+		switch {
+		case issue.Kind == IssueTempFile:
+			markRepair(issue, post.RepairDelete, s.backend.Delete(issue.Path))
+		case repair != post.RepairRegenerate:
+		case issue.Kind == IssueMissingMetadata, issue.Kind == IssueCorruptMetadata:
+			markRepair(issue, repair, s.regenerateMetadata(issue.PostID))
+		case issue.Kind == IssueCorruptManifest:
+			markRepair(issue, repair, s.regenerateMediaManifest(issue.PostID))
+		case issue.Kind == IssueDanglingMedia:
+			markRepair(issue, repair, s.dropManifestEntry(issue.PostID, path.Base(issue.Path)))
+		}
+	}
+}
+
+// repairFile quarantines or deletes a single root-level file
+func (s *Storage) repairFile(key string, issue *post.StorageIssue, repair string) {
+	var err error
+	if repair == post.RepairQuarantine {
+		err = s.quarantine(key)
+	} else {
+		err = s.backend.Delete(key)
+	}
+	markRepair(issue, repair, err)
+}
+
+func markRepair(issue *post.StorageIssue, repair string, err error) {
+	if err != nil {
+		issue.RepairError = err.Error()
+		return
+	}
+	issue.Repaired = repair
+}
+
+// quarantine moves a root-level directory or file under .quarantine/,
+// suffixing the name with a timestamp if it was quarantined before
+func (s *Storage) quarantine(name string) error {
+	dst := path.Join(quarantineDir, name)
+	existing, err := s.backend.List(dst)
+	if err != nil {
+		return err
+	}
+	if _, statErr := s.backend.Stat(dst); len(existing) > 0 || statErr == nil {
+		dst += "-" + time.Now().UTC().Format("20060102T150405Z")
+	}
+
+	if _, err := s.backend.Stat(name); err == nil {
+		data, err := s.backend.Read(name)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.Write(dst, data); err != nil {
+			return err
+		}
+		return s.backend.Delete(name)
+	}
+	return s.movePrefix(name, dst)
+}
+
+// regenerateMetadata writes default metadata for a post, keeping the name,
+// dimensions and timestamps from the old metadata when they are readable
+func (s *Storage) regenerateMetadata(postID string) error {
+	metadata := &post.Metadata{}
+	if old, err := s.readRawMetadata(postID); err == nil && schemaVersion(old) <= post.SchemaVersion {
+		metadata = old
+	}
+	if metadata.Name == "" {
+		metadata.Name = postID
+	}
+	if metadata.Width <= 0 || metadata.Height <= 0 {
+		metadata.Width = regeneratedCanvasSize
+		metadata.Height = regeneratedCanvasSize
+	}
+	if metadata.CreatedAt.IsZero() || metadata.UpdatedAt.IsZero() {
+		modTime := time.Now()
+		if info, err := s.backend.Stat(htmlKey(postID)); err == nil {
+			modTime = info.ModTime
+		}
+		if metadata.CreatedAt.IsZero() {
+			metadata.CreatedAt = modTime
+		}
+		if metadata.UpdatedAt.IsZero() {
+			metadata.UpdatedAt = modTime
+		}
+	}
+	return s.writeMetadata(postID, metadata)
+}
+
+// regenerateMediaManifest rebuilds media.json from the files in media/
+func (s *Storage) regenerateMediaManifest(postID string) error {
+	if err := s.deleteObject(mediaManifestKey(postID)); err != nil {
+		return err
+	}
+	_, err := migrateMediaManifest(s, postID, nil, false)
+	return err
+}
+
+// dropManifestEntry removes a file from a post's media.json
+func (s *Storage) dropManifestEntry(postID, filename string) error {
+	manifest, err := s.readMediaManifest(postID)
+	if err != nil {
+		return err
+	}
+	kept := manifest.Files[:0]
+	for _, info := range manifest.Files {
+		if info.Filename != filename {
+			kept = append(kept, info)
+		}
+	}
+	manifest.Files = kept
+	return s.writeMediaManifest(postID, manifest)
+}
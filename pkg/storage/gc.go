@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/post"
+	"time"
+)
+
+// GarbageCollect permanently deletes posts not updated within
+// policy.MaxAgeDays and all but the newest policy.KeepVersions versions of
+// the remaining posts. Collection membership is kept in each post's
+// metadata, so a deleted post leaves its collection with it; the report
+// names the collection it left. With dryRun nothing is deleted. On failure
+// the report covers the deletions made so far.
+func (s *Storage) GarbageCollect(policy post.RetentionPolicy, dryRun bool) (*post.GCReport, error) {
+	dirs, err := s.backend.ListDirs("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root directory: %w", err)
+	}
+
+	report := &post.GCReport{DryRun: dryRun, Policy: policy, Actions: []*post.GCAction{}}
+	cutoff := time.Now().AddDate(0, 0, -policy.MaxAgeDays)
+	var touched []string
+	for _, postID := range dirs {
+		if !post.ValidatePostID(postID) || !s.PostExists(postID) {
+			continue
+		}
+
+		if policy.MaxAgeDays > 0 {
+			metadata, err := s.readMetadata(postID)
+			if err != nil {
+				continue // Broken posts are left to CheckStorage
+			}
+			if metadata.UpdatedAt.Before(cutoff) {
+				size, err := s.prefixSize(postID)
+				if err != nil {
+					return report, fmt.Errorf("failed to measure %s: %w", postID, err)
+				}
+				if !dryRun {
+					err := s.backend.DeletePrefix(postID)
+					s.remeasureUsage(postID)
+					if err != nil {
+						return report, fmt.Errorf("failed to delete %s: %w", postID, err)
+					}
+				}
+				touched = append(touched, postID)
+				report.Actions = append(report.Actions, &post.GCAction{Action: post.GCDeletePost, PostID: postID, Collection: metadata.Collection, Bytes: size})
+				report.DeletedPosts++
+				report.FreedBytes += size
+				continue
+			}
+		}
+
+		if policy.KeepVersions > 0 {
+			versions, err := s.ListVersions(postID)
+			if err != nil {
+				return report, err
+			}
+			// Versions are listed oldest first
+			for i := 0; i < len(versions)-policy.KeepVersions; i++ {
+				v := versions[i]
+				key := versionsKey(postID, v.ID+".html")
+				if !dryRun {
+					if err := s.deleteObject(key); err != nil {
+						return report, fmt.Errorf("failed to delete version %s of %s: %w", v.ID, postID, err)
+					}
+				}
+				touched = append(touched, key)
+				report.Actions = append(report.Actions, &post.GCAction{Action: post.GCDeleteVersion, PostID: postID, VersionID: v.ID, Bytes: v.Size})
+				report.DeletedVersions++
+				report.FreedBytes += v.Size
+			}
+		}
+	}
+
+	if !dryRun && len(report.Actions) > 0 {
+		if err := s.commit(touched, "Collect garbage: delete %d posts and %d versions", report.DeletedPosts, report.DeletedVersions); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"html_image_creator/pkg/post"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// gitIgnore keeps scratch files and separately managed directories out of history
+const gitIgnore = `.quarantine/
+.workspaces/
+temp_screenshot.html
+`
+
+// Historian is implemented by backends that record changes as revisions
+type Historian interface {
+	// Commit records the changes to paths, which are keys or key prefixes,
+	// with message. Nothing is recorded when they did not change.
+	Commit(paths []string, message string) error
+	// Log returns the newest revisions touching files under prefix, newest first
+	Log(prefix string, limit int) ([]*post.Revision, error)
+	// ReadRevision returns the files under prefix at revision, keyed by their
+	// path relative to prefix, and the full hash of the revision
+	ReadRevision(revision, prefix string) (string, map[string][]byte, error)
+}
+
+// GitBackend is a LocalBackend whose root directory is a git repository
+type GitBackend struct {
+	*LocalBackend
+	repo   *git.Repository
+	author object.Signature
+	mu     sync.Mutex
+}
+
+// NewGitBackend opens the git repository at rootDir, initializing it when
+// missing. Commits are attributed to authorName and authorEmail.
+func NewGitBackend(rootDir, authorName, authorEmail string) (*GitBackend, error) {
+	repo, err := git.PlainOpen(rootDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInit(rootDir, false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository %s: %w", rootDir, err)
+	}
+
+	ignorePath := filepath.Join(rootDir, ".gitignore")
+	if _, err := os.Stat(ignorePath); os.IsNotExist(err) {
+		if err := os.WriteFile(ignorePath, []byte(gitIgnore), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write .gitignore: %w", err)
+		}
+	}
+
+	b := &GitBackend{
+		LocalBackend: NewLocalBackend(rootDir),
+		repo:         repo,
+		author:       object.Signature{Name: authorName, Email: authorEmail},
+	}
+	if err := b.recordExisting(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// recordExisting commits everything in the root directory when the
+// repository has no commits yet, so posts created before history was
+// enabled are tracked. Later commits only stage the paths they touch.
+func (b *GitBackend) recordExisting() error {
+	if _, err := b.repo.Head(); !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	wt, err := b.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return fmt.Errorf("failed to stage existing files: %w", err)
+	}
+	author := b.author
+	author.When = time.Now()
+	_, err = wt.Commit("Start history", &git.CommitOptions{Author: &author})
+	if err != nil && !errors.Is(err, git.ErrEmptyCommit) {
+		return fmt.Errorf("failed to commit existing files: %w", err)
+	}
+	return nil
+}
+
+// Commit stages the files at or under each of paths and commits them.
+// Only those paths are looked at, so a commit costs the size of the change
+// rather than a status scan of the whole root directory.
+func (b *GitBackend) Commit(paths []string, message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wt, err := b.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	ignored := b.ignoreMatcher()
+
+	// Add the files that exist, then drop index entries for the ones that don't
+	present := make(map[string]bool)
+	for _, p := range paths {
+		files, err := b.filesUnder(p)
+		if err != nil {
+			return fmt.Errorf("failed to stage %s: %w", p, err)
+		}
+		for _, f := range files {
+			if ignored.Match(strings.Split(f, "/"), false) {
+				continue
+			}
+			present[f] = true
+			if err := wt.AddWithOptions(&git.AddOptions{Path: f, SkipStatus: true}); err != nil {
+				return fmt.Errorf("failed to stage %s: %w", f, err)
+			}
+		}
+	}
+
+	idx, err := b.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	kept := idx.Entries[:0]
+	for _, e := range idx.Entries {
+		if present[e.Name] || !underAny(e.Name, paths) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) != len(idx.Entries) {
+		idx.Entries = kept
+		if err := b.repo.Storer.SetIndex(idx); err != nil {
+			return fmt.Errorf("failed to update index: %w", err)
+		}
+	}
+
+	author := b.author
+	author.When = time.Now()
+	_, err = wt.Commit(message, &git.CommitOptions{Author: &author})
+	if errors.Is(err, git.ErrEmptyCommit) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// filesUnder returns the regular files at or below key, as slash separated
+// paths relative to the root directory
+func (b *GitBackend) filesUnder(key string) ([]string, error) {
+	root, err := b.LocalPath(key)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(b.rootDir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// ignoreMatcher reads the patterns of the root .gitignore
+func (b *GitBackend) ignoreMatcher() gitignore.Matcher {
+	var patterns []gitignore.Pattern
+	if data, err := os.ReadFile(filepath.Join(b.rootDir, ".gitignore")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				patterns = append(patterns, gitignore.ParsePattern(line, nil))
+			}
+		}
+	}
+	return gitignore.NewMatcher(patterns)
+}
+
+// underAny reports whether name is one of keys or lies below one of them
+func underAny(name string, keys []string) bool {
+	for _, key := range keys {
+		key = strings.Trim(key, "/")
+		if key == "" || name == key || strings.HasPrefix(name, key+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Log returns up to limit revisions touching prefix, newest first. A zero
+// limit returns all of them; an empty prefix matches every revision.
+func (b *GitBackend) Log(prefix string, limit int) ([]*post.Revision, error) {
+	opts := &git.LogOptions{Order: git.LogOrderCommitterTime}
+	if prefix != "" {
+		opts.PathFilter = func(p string) bool { return strings.HasPrefix(p, prefix+"/") }
+	}
+	iter, err := b.repo.Log(opts)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return []*post.Revision{}, nil // No commits yet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+	defer iter.Close()
+
+	revisions := []*post.Revision{}
+	for limit <= 0 || len(revisions) < limit {
+		c, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read log: %w", err)
+		}
+		revisions = append(revisions, &post.Revision{
+			Hash:    c.Hash.String(),
+			Message: strings.TrimSpace(c.Message),
+			Author:  c.Author.Name,
+			At:      c.Author.When,
+		})
+	}
+	return revisions, nil
+}
+
+// ReadRevision returns the files under prefix at revision, which may be a
+// full or abbreviated hash or any revision git understands (e.g. HEAD~2)
+func (b *GitBackend) ReadRevision(revision, prefix string) (string, map[string][]byte, error) {
+	hash, err := b.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", nil, fmt.Errorf("unknown revision %s: %w", revision, err)
+	}
+	commit, err := b.repo.CommitObject(*hash)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read revision %s: %w", revision, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read revision %s: %w", revision, err)
+	}
+	subtree, err := tree.Tree(prefix)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s does not exist at revision %s", prefix, revision)
+	}
+
+	files := make(map[string][]byte)
+	err = subtree.Files().ForEach(func(f *object.File) error {
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		files[f.Name] = data
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read files at revision %s: %w", revision, err)
+	}
+	return hash.String(), files, nil
+}
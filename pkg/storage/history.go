@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/post"
+	"strings"
+	"time"
+)
+
+// commit records the changes an operation made to paths, which are keys or
+// key prefixes, when the backend keeps history
+func (s *Storage) commit(paths []string, format string, args ...interface{}) error {
+	historian, ok := s.backend.(Historian)
+	if !ok {
+		return nil
+	}
+	if err := historian.Commit(paths, fmt.Sprintf(format, args...)); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// historian returns the backend's history or an error when it keeps none
+func (s *Storage) historian() (Historian, error) {
+	historian, ok := s.backend.(Historian)
+	if !ok {
+		return nil, fmt.Errorf("git history is not enabled for this storage")
+	}
+	return historian, nil
+}
+
+// PostLog returns up to limit revisions touching the post, newest first. An
+// empty postID returns revisions of the whole storage.
+func (s *Storage) PostLog(postID string, limit int) ([]*post.Revision, error) {
+	historian, err := s.historian()
+	if err != nil {
+		return nil, err
+	}
+	if postID != "" && !post.ValidatePostID(postID) {
+		return nil, fmt.Errorf("invalid post ID: %s", postID)
+	}
+	return historian.Log(postID, limit)
+}
+
+// CheckoutRevision restores a post's files as they were at revision and
+// returns the revision's full hash. Existing versions are kept. Posts that
+// were deleted since the revision are recreated.
+func (s *Storage) CheckoutRevision(postID, revision string) (string, error) {
+	historian, err := s.historian()
+	if err != nil {
+		return "", err
+	}
+	if _, err := postKey(postID); err != nil {
+		return "", err
+	}
+
+	hash, files, err := historian.ReadRevision(revision, postID)
+	if err != nil {
+		return "", err
+	}
+	html, ok := files["index.html"]
+	if !ok {
+		return "", fmt.Errorf("post %s has no index.html at revision %s", postID, revision)
+	}
+
+	exists := s.PostExists(postID)
+	current, err := s.backend.List(postID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list post files: %w", err)
+	}
+	var growth int64
+	for rel, data := range files {
+		if !isVersionPath(rel) {
+			growth += int64(len(data))
+		}
+	}
+	for _, obj := range current {
+		if !isVersionPath(strings.TrimPrefix(obj.Key, postID+"/")) {
+			growth -= obj.Size
+		}
+	}
+	if err := s.checkQuota(postID, growth, !exists); err != nil {
+		return "", err
+	}
+
+	for _, obj := range current {
+		if !isVersionPath(strings.TrimPrefix(obj.Key, postID+"/")) {
+			if err := s.deleteObject(obj.Key); err != nil {
+				return "", fmt.Errorf("failed to remove %s: %w", obj.Key, err)
+			}
+		}
+	}
+
+	// Write index.html last so a partially restored post never appears in listings
+	for rel, data := range files {
+		if rel == "index.html" || isVersionPath(rel) {
+			continue
+		}
+		key, err := postKey(postID, rel)
+		if err != nil {
+			return "", err
+		}
+		if err := s.writeObject(key, data); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+	}
+	if err := s.writeObject(htmlKey(postID), html); err != nil {
+		return "", fmt.Errorf("failed to write HTML file: %w", err)
+	}
+
+	metadata, err := s.readMetadata(postID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read restored metadata: %w", err)
+	}
+	now := time.Now()
+	metadata.UpdatedAt = now
+	metadata.History = append(metadata.History, &post.HistoryEntry{
+		Action:  post.HistoryCheckout,
+		At:      now,
+		Details: map[string]interface{}{"revision": hash},
+	})
+	if err := s.writeMetadata(postID, metadata); err != nil {
+		return "", fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	if err := s.commit([]string{postID}, "Check out post %s at revision %s", postID, hash[:7]); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// isVersionPath reports whether a path relative to a post is a saved version
+func isVersionPath(rel string) bool {
+	return strings.HasPrefix(rel, "versions/")
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"html_image_creator/pkg/pathguard"
+)
+
+// LocalBackend stores objects as files under a root directory
+type LocalBackend struct {
+	rootDir string
+}
+
+// NewLocalBackend creates a new LocalBackend rooted at rootDir
+func NewLocalBackend(rootDir string) *LocalBackend {
+	return &LocalBackend{
+		rootDir: rootDir,
+	}
+}
+
+// LocalPath returns the file path for key, verifying it stays inside the root directory
+func (b *LocalBackend) LocalPath(key string) (string, error) {
+	return pathguard.Join(b.rootDir, filepath.FromSlash(key))
+}
+
+// Read returns the contents of key
+func (b *LocalBackend) Read(key string) ([]byte, error) {
+	path, err := b.LocalPath(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return data, err
+}
+
+// Write creates or replaces key, creating parent directories as needed
+func (b *LocalBackend) Write(key string, data []byte) error {
+	path, err := b.LocalPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Delete removes key
+func (b *LocalBackend) Delete(key string) error {
+	path, err := b.LocalPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix removes the directory for prefix and everything in it
+func (b *LocalBackend) DeletePrefix(prefix string) error {
+	path, err := b.LocalPath(prefix)
+	if err != nil {
+		return err
+	}
+	if path == filepath.Clean(b.rootDir) {
+		return fmt.Errorf("refusing to delete the storage root")
+	}
+	return os.RemoveAll(path)
+}
+
+// Move renames the directory for srcPrefix to dstPrefix
+func (b *LocalBackend) Move(srcPrefix, dstPrefix string) error {
+	src, err := b.LocalPath(srcPrefix)
+	if err != nil {
+		return err
+	}
+	dst, err := b.LocalPath(dstPrefix)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return os.Rename(src, dst)
+}
+
+// Stat returns object info for key
+func (b *LocalBackend) Stat(key string) (*ObjectInfo, error) {
+	path, err := b.LocalPath(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// List returns every file under prefix, sorted by key
+func (b *LocalBackend) List(prefix string) ([]ObjectInfo, error) {
+	root, err := b.LocalPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []ObjectInfo
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(b.rootDir, path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:     filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// ListDirs returns the names of the sub-directories of prefix
+func (b *LocalBackend) ListDirs(prefix string) ([]string, error) {
+	path, err := b.LocalPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs, nil
+}
+
+// Location returns the absolute file path for key
+func (b *LocalBackend) Location(key string) string {
+	return filepath.Join(b.rootDir, filepath.FromSlash(key))
+}
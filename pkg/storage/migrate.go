@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"html_image_creator/pkg/media"
+	"html_image_creator/pkg/post"
+	"path"
+	"strings"
+)
+
+// migration upgrades a post's stored files to version. apply returns a short
+// description of what it changed, or an empty string if there was nothing to
+// do, and must not write anything when dryRun is set.
+type migration struct {
+	version     int
+	description string
+	apply       func(s *Storage, postID string, metadata *post.Metadata, dryRun bool) (string, error)
+}
+
+// migrations lists every schema upgrade in order. The last entry's version
+// must equal post.SchemaVersion.
+var migrations = []migration{
+	{
+		version:     2,
+		description: "index media files added before media.json existed",
+		apply:       migrateMediaManifest,
+	},
+}
+
+func init() {
+	if migrations[len(migrations)-1].version != post.SchemaVersion {
+		panic("storage: migrations do not reach post.SchemaVersion")
+	}
+}
+
+// schemaVersion returns the schema version of stored metadata
+func schemaVersion(metadata *post.Metadata) int {
+	if metadata.SchemaVersion == 0 {
+		return 1
+	}
+	return metadata.SchemaVersion
+}
+
+// migratePost applies pending migrations to a post whose raw metadata has
+// already been read, then saves the upgraded metadata unless dryRun is set
+func (s *Storage) migratePost(postID string, metadata *post.Metadata, dryRun bool) (*post.MigrationResult, error) {
+	from := schemaVersion(metadata)
+	result := &post.MigrationResult{PostID: postID, FromVersion: from, ToVersion: from}
+	if from > post.SchemaVersion {
+		return result, fmt.Errorf("metadata schema version %d is newer than supported version %d", from, post.SchemaVersion)
+	}
+
+	for _, m := range migrations {
+		if m.version <= from {
+			continue
+		}
+		detail, err := m.apply(s, postID, metadata, dryRun)
+		if err != nil {
+			return result, fmt.Errorf("migration to version %d failed: %w", m.version, err)
+		}
+		step := fmt.Sprintf("v%d: %s", m.version, m.description)
+		if detail != "" {
+			step += " (" + detail + ")"
+		}
+		result.Steps = append(result.Steps, step)
+		result.ToVersion = m.version
+	}
+
+	if result.ToVersion == from || dryRun {
+		return result, nil
+	}
+	if err := s.saveMetadata(postID, metadata); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// MigrateAll upgrades every post to the current schema version. With dryRun
+// set it only reports what would change. Trashed posts are migrated on
+// their next write after they are restored.
+func (s *Storage) MigrateAll(dryRun bool) (*post.MigrationReport, error) {
+	dirs, err := s.backend.ListDirs("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root directory: %w", err)
+	}
+
+	report := &post.MigrationReport{DryRun: dryRun, CurrentVersion: post.SchemaVersion}
+	var touched []string
+	for _, postID := range dirs {
+		if !post.ValidatePostID(postID) || !s.PostExists(postID) {
+			continue
+		}
+
+		metadata, err := s.readRawMetadata(postID)
+		var result *post.MigrationResult
+		if err == nil {
+			result, err = s.migratePost(postID, metadata, dryRun)
+		}
+		if err != nil {
+			if result == nil {
+				result = &post.MigrationResult{PostID: postID}
+			}
+			result.Error = err.Error()
+			report.Failed++
+			report.Posts = append(report.Posts, result)
+			continue
+		}
+		if result.ToVersion == result.FromVersion {
+			report.UpToDate++
+			continue
+		}
+		report.Migrated++
+		touched = append(touched, metadataKey(postID), mediaManifestKey(postID))
+		report.Posts = append(report.Posts, result)
+	}
+
+	if !dryRun && report.Migrated > 0 {
+		if err := s.commit(touched, "Migrate %d posts to metadata schema version %d", report.Migrated, post.SchemaVersion); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// readRawMetadata reads metadata.json without applying migrations
+func (s *Storage) readRawMetadata(postID string) (*post.Metadata, error) {
+	data, err := s.backend.Read(metadataKey(postID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+
+	var metadata post.Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+
+	return &metadata, nil
+}
+
+// migrateMediaManifest adds media.json entries for files in media/ that
+// were stored before manifests were recorded
+func migrateMediaManifest(s *Storage, postID string, metadata *post.Metadata, dryRun bool) (string, error) {
+	manifest, err := s.readMediaManifest(postID)
+	if err != nil {
+		return "", err
+	}
+	known := make(map[string]bool, len(manifest.Files))
+	for _, info := range manifest.Files {
+		known[info.Filename] = true
+	}
+
+	objects, err := s.backend.List(path.Join(postID, "media"))
+	if err != nil {
+		return "", fmt.Errorf("failed to list media files: %w", err)
+	}
+
+	added := 0
+	for _, obj := range objects {
+		filename := strings.TrimPrefix(obj.Key, path.Join(postID, "media")+"/")
+		logo := ""
+		if dir, name := path.Split(filename); dir == post.BrandLogoDir+"/" {
+			logo = name
+		} else if dir != "" {
+			continue
+		}
+		if known[filename] {
+			continue
+		}
+		added++
+		if dryRun {
+			continue // Reporting needs no file contents
+		}
+		data, err := s.backend.Read(obj.Key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", obj.Key, err)
+		}
+		info := &post.MediaInfo{
+			Filename:    filename,
+			Path:        path.Join("media", filename),
+			ContentType: media.SniffContentType(data),
+			Size:        int64(len(data)),
+			SHA256:      sha256Hex(data),
+			BrandLogo:   logo,
+			AddedAt:     obj.ModTime,
+		}
+		// Files that no longer pass validation are still indexed with their sniffed type
+		if probed, err := s.mediaPolicy.Inspect(data); err == nil {
+			info.ContentType = probed.ContentType
+			info.Width = probed.Width
+			info.Height = probed.Height
+		}
+		manifest.Files = append(manifest.Files, info)
+	}
+
+	if added == 0 {
+		return "", nil
+	}
+	if !dryRun {
+		if err := s.writeMediaManifest(postID, manifest); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d media file(s)", added), nil
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/post"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Quota limits how much a Storage may hold. Zero values mean unlimited.
+type Quota struct {
+	MaxTotalBytes int64 // Bytes used by all posts and the trash
+	MaxPostBytes  int64 // Bytes used by a single post, including media and versions
+	MaxPosts      int   // Number of live posts
+}
+
+// QuotaError reports a write rejected because it would exceed a quota
+type QuotaError struct {
+	Limit string // "total_bytes", "post_bytes" or "posts"
+	Max   int64
+	Would int64
+}
+
+func (e *QuotaError) Error() string {
+	switch e.Limit {
+	case "posts":
+		return fmt.Sprintf("storage quota exceeded: the limit of %d posts is reached", e.Max)
+	case "post_bytes":
+		return fmt.Sprintf("storage quota exceeded: post would use %d bytes, limit is %d bytes per post", e.Would, e.Max)
+	default:
+		return fmt.Sprintf("storage quota exceeded: storage would use %d bytes, limit is %d bytes in total", e.Would, e.Max)
+	}
+}
+
+// usage caches the bytes used by each post and trash entry so that quota
+// checks don't walk the storage tree on every write. It is loaded on the
+// first check and kept current by the writes made through this Storage;
+// changes made by other processes are picked up only after a reload.
+type usage struct {
+	mu     sync.Mutex
+	loaded bool
+	dirs   map[string]int64 // Bytes under each post and trash entry directory
+	live   map[string]bool  // Post directories containing index.html
+	total  int64
+}
+
+// usageDir returns the directory key is charged to, or "" when key lies
+// outside the posts and the trash
+func usageDir(key string) string {
+	parts := strings.SplitN(key, "/", 3)
+	if parts[0] == trashDir {
+		if len(parts) < 2 {
+			return ""
+		}
+		return trashKey(parts[1])
+	}
+	if !post.ValidatePostID(parts[0]) {
+		return ""
+	}
+	return parts[0]
+}
+
+// checkQuota verifies that growing postID by growth bytes stays within the
+// quota. newPost is set when the write adds a live post. Writes that shrink
+// a post are always allowed so that space can be reclaimed.
+func (s *Storage) checkQuota(postID string, growth int64, newPost bool) error {
+	q := s.quota
+	if q == (Quota{}) {
+		return nil
+	}
+
+	u := &s.usage
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.loaded {
+		if err := s.loadUsage(); err != nil {
+			return fmt.Errorf("failed to measure storage: %w", err)
+		}
+	}
+
+	if newPost && q.MaxPosts > 0 {
+		count := len(u.live)
+		if count >= q.MaxPosts {
+			return &QuotaError{Limit: "posts", Max: int64(q.MaxPosts), Would: int64(count + 1)}
+		}
+	}
+	if growth <= 0 {
+		return nil
+	}
+
+	if size := u.dirs[postID]; q.MaxPostBytes > 0 && size+growth > q.MaxPostBytes {
+		return &QuotaError{Limit: "post_bytes", Max: q.MaxPostBytes, Would: size + growth}
+	}
+	if q.MaxTotalBytes > 0 && u.total+growth > q.MaxTotalBytes {
+		return &QuotaError{Limit: "total_bytes", Max: q.MaxTotalBytes, Would: u.total + growth}
+	}
+	return nil
+}
+
+// loadUsage measures every post and trash entry. Quarantined directories
+// and other workspaces nested in the root are not counted. The caller holds
+// s.usage.mu.
+func (s *Storage) loadUsage() error {
+	u := &s.usage
+	u.dirs = map[string]int64{}
+	u.live = map[string]bool{}
+	u.total = 0
+
+	dirs, err := s.backend.ListDirs("")
+	if err != nil {
+		return err
+	}
+	var counted []string
+	for _, dir := range dirs {
+		if post.ValidatePostID(dir) {
+			counted = append(counted, dir)
+		}
+	}
+	trashed, err := s.backend.ListDirs(trashDir)
+	if err != nil {
+		return err
+	}
+	for _, id := range trashed {
+		counted = append(counted, trashKey(id))
+	}
+
+	for _, dir := range counted {
+		if err := s.measureDir(dir); err != nil {
+			return err
+		}
+	}
+	u.loaded = true
+	return nil
+}
+
+// measureDir replaces the cached size of dir with its size in the backend.
+// The caller holds s.usage.mu.
+func (s *Storage) measureDir(dir string) error {
+	u := &s.usage
+	size, err := s.prefixSize(dir)
+	if err != nil {
+		return err
+	}
+	u.total += size - u.dirs[dir]
+	if size == 0 {
+		delete(u.dirs, dir)
+	} else {
+		u.dirs[dir] = size
+	}
+	if post.ValidatePostID(dir) && s.PostExists(dir) {
+		u.live[dir] = true
+	} else {
+		delete(u.live, dir)
+	}
+	return nil
+}
+
+// tracksUsage reports whether writes need to charge the usage cache
+func (s *Storage) tracksUsage() bool {
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	return s.usage.loaded
+}
+
+// chargeUsage adds delta bytes written to or removed from key
+func (s *Storage) chargeUsage(key string, delta int64) {
+	dir := usageDir(key)
+	if dir == "" {
+		return
+	}
+	u := &s.usage
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.loaded {
+		return
+	}
+	u.dirs[dir] += delta
+	u.total += delta
+	if key == path.Join(dir, "index.html") && post.ValidatePostID(dir) {
+		u.live[dir] = true
+	}
+}
+
+// remeasureUsage refreshes the cached usage of the directories holding keys
+// after moves and prefix deletions whose size isn't known up front. If a
+// directory can't be measured the whole cache is reloaded on the next check.
+func (s *Storage) remeasureUsage(keys ...string) {
+	u := &s.usage
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.loaded {
+		return
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		dir := usageDir(key)
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if err := s.measureDir(dir); err != nil {
+			u.loaded = false
+			return
+		}
+	}
+}
+
+// writeObject writes key and charges the change in its size to the usage cache
+func (s *Storage) writeObject(key string, data []byte) error {
+	var previous int64
+	if s.tracksUsage() {
+		if info, err := s.backend.Stat(key); err == nil {
+			previous = info.Size
+		}
+	}
+	if err := s.backend.Write(key, data); err != nil {
+		return err
+	}
+	s.chargeUsage(key, int64(len(data))-previous)
+	return nil
+}
+
+// deleteObject deletes key and credits its size to the usage cache
+func (s *Storage) deleteObject(key string) error {
+	var size int64
+	if s.tracksUsage() {
+		if info, err := s.backend.Stat(key); err == nil {
+			size = info.Size
+		}
+	}
+	if err := s.backend.Delete(key); err != nil {
+		return err
+	}
+	s.chargeUsage(key, -size)
+	return nil
+}
+
+// prefixSize returns the number of bytes stored under prefix
+func (s *Storage) prefixSize(prefix string) (int64, error) {
+	objects, err := s.backend.List(prefix)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, obj := range objects {
+		size += obj.Size
+	}
+	return size, nil
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"errors"
+	"html_image_creator/pkg/media"
+	"html_image_creator/pkg/post"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listCounter counts the listing calls made to a backend
+type listCounter struct {
+	Backend
+	mu    sync.Mutex
+	lists int
+}
+
+func (c *listCounter) List(prefix string) ([]ObjectInfo, error) {
+	c.mu.Lock()
+	c.lists++
+	c.mu.Unlock()
+	return c.Backend.List(prefix)
+}
+
+func (c *listCounter) ListDirs(prefix string) ([]string, error) {
+	c.mu.Lock()
+	c.lists++
+	c.mu.Unlock()
+	return c.Backend.ListDirs(prefix)
+}
+
+func (c *listCounter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lists
+}
+
+func newQuotaStorage(t *testing.T, quota Quota) (*Storage, *listCounter) {
+	backend := &listCounter{Backend: NewLocalBackend(t.TempDir())}
+	return NewStorage(backend, quota, media.Policy{}), backend
+}
+
+func newTestPost(id, html string) *post.ImagePost {
+	// A fixed time keeps metadata.json the same length across updates
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &post.ImagePost{ID: id, Name: id, HTMLContent: html, Width: 100, Height: 100, CreatedAt: now, UpdatedAt: now}
+}
+
+// checkUsage compares the cached usage with a fresh measurement
+func checkUsage(t *testing.T, s *Storage, step string) {
+	t.Helper()
+	cached := &s.usage
+	fresh := &Storage{backend: s.backend}
+	fresh.usage.mu.Lock()
+	err := fresh.loadUsage()
+	fresh.usage.mu.Unlock()
+	if err != nil {
+		t.Fatalf("%s: measuring storage: %v", step, err)
+	}
+	if cached.total != fresh.usage.total {
+		t.Errorf("%s: cached total is %d bytes, storage holds %d", step, cached.total, fresh.usage.total)
+	}
+	for dir, size := range fresh.usage.dirs {
+		if cached.dirs[dir] != size {
+			t.Errorf("%s: cached size of %s is %d bytes, storage holds %d", step, dir, cached.dirs[dir], size)
+		}
+	}
+	if len(cached.live) != len(fresh.usage.live) {
+		t.Errorf("%s: cached post count is %d, storage holds %d", step, len(cached.live), len(fresh.usage.live))
+	}
+}
+
+func TestUsageFollowsWrites(t *testing.T) {
+	s, _ := newQuotaStorage(t, Quota{MaxTotalBytes: 1 << 20})
+	if err := s.CreatePost(newTestPost("first-1a2b", "<p>first</p>")); err != nil {
+		t.Fatal(err)
+	}
+	checkUsage(t, s, "create")
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"create second", func() error { return s.CreatePost(newTestPost("second-1a2b", "<p>second</p>")) }},
+		{"grow html", func() error { return s.UpdatePost(newTestPost("first-1a2b", strings.Repeat("x", 500))) }},
+		{"shrink html", func() error { return s.UpdatePost(newTestPost("first-1a2b", "<p></p>")) }},
+		{"add media", func() error {
+			return s.WriteMediaFile("first-1a2b", &post.MediaInfo{Filename: "a.png"}, make([]byte, 300))
+		}},
+		{"replace media", func() error {
+			return s.WriteMediaFile("first-1a2b", &post.MediaInfo{Filename: "a.png"}, make([]byte, 100))
+		}},
+		{"delete media", func() error { return s.DeleteMediaFile("first-1a2b", "a.png") }},
+		{"trash", func() error { return s.TrashPost("second-1a2b") }},
+		{"restore", func() error { return s.RestorePost("second-1a2b") }},
+		{"trash again", func() error { return s.TrashPost("second-1a2b") }},
+		{"purge", func() error { _, err := s.PurgeTrash(0); return err }},
+		{"write files", func() error {
+			return s.WritePostFiles("third-1a2b", []*post.PostFile{{Path: "index.html", Data: []byte("<p>third</p>")}})
+		}},
+		{"delete", func() error { return s.DeletePost("third-1a2b") }},
+		{"collect", func() error {
+			_, err := s.GarbageCollect(post.RetentionPolicy{MaxAgeDays: 1}, false)
+			return err
+		}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		checkUsage(t, s, step.name)
+	}
+}
+
+func TestQuotaDoesNotWalkOnWrite(t *testing.T) {
+	s, backend := newQuotaStorage(t, Quota{MaxTotalBytes: 1 << 20, MaxPostBytes: 1 << 16, MaxPosts: 10})
+	if err := s.CreatePost(newTestPost("first-1a2b", "<p>first</p>")); err != nil {
+		t.Fatal(err)
+	}
+
+	before := backend.count()
+	for i := 0; i < 5; i++ {
+		if err := s.UpdatePost(newTestPost("first-1a2b", strings.Repeat("x", 100*(i+1)))); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteMediaFile("first-1a2b", &post.MediaInfo{Filename: "a.png"}, make([]byte, 10*(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreatePost(newTestPost("second-1a2b", "<p>second</p>")); err != nil {
+		t.Fatal(err)
+	}
+	if n := backend.count() - before; n != 0 {
+		t.Errorf("writes listed the backend %d times, want 0", n)
+	}
+}
+
+func TestQuotaLimits(t *testing.T) {
+	s, _ := newQuotaStorage(t, Quota{MaxPosts: 2})
+	for _, id := range []string{"first-1a2b", "second-1a2b"} {
+		if err := s.CreatePost(newTestPost(id, "<p></p>")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var quotaErr *QuotaError
+	if err := s.CreatePost(newTestPost("third-1a2b", "<p></p>")); !errors.As(err, &quotaErr) || quotaErr.Limit != "posts" {
+		t.Errorf("third post: got %v, want a posts quota error", err)
+	}
+	if err := s.TrashPost("second-1a2b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePost(newTestPost("third-1a2b", "<p></p>")); err != nil {
+		t.Errorf("third post after trashing one: %v", err)
+	}
+
+	s, _ = newQuotaStorage(t, Quota{MaxPostBytes: 4096})
+	if err := s.CreatePost(newTestPost("first-1a2b", "<p></p>")); err != nil {
+		t.Fatal(err)
+	}
+	size := s.usage.dirs["first-1a2b"]
+	room := 4096 - size
+
+	tests := []struct {
+		name   string
+		html   int // Length of the new HTML, which replaces 7 bytes
+		reject bool
+	}{
+		{"within limit", int(room) + 7, false},
+		{"shrink", 7, false},
+		{"one byte over", int(room) + 8, true},
+	}
+	for _, tt := range tests {
+		err := s.UpdatePost(newTestPost("first-1a2b", strings.Repeat("x", tt.html)))
+		if tt.reject {
+			if !errors.As(err, &quotaErr) || quotaErr.Limit != "post_bytes" {
+				t.Errorf("%s: got %v, want a post_bytes quota error", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if err := s.UpdatePost(newTestPost("first-1a2b", "<p></p>")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options configures an S3-compatible backend
+type S3Options struct {
+	Endpoint  string // Base URL, e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Region    string
+	Bucket    string
+	Prefix    string // Optional key prefix inside the bucket
+	AccessKey string
+	SecretKey string
+	PathStyle bool // Address the bucket as /bucket/key instead of bucket.host/key
+}
+
+// S3Backend stores objects in an S3-compatible bucket. Requests are signed
+// with AWS Signature Version 4 so it works with AWS S3, MinIO and similar
+// services.
+type S3Backend struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Backend creates a new S3Backend
+func NewS3Backend(opts S3Options) (*S3Backend, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+	}
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+
+	return &S3Backend{
+		opts:     opts,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+// objectKey maps a backend key to the key inside the bucket
+func (b *S3Backend) objectKey(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if b.opts.Prefix == "" {
+		return key
+	}
+	if key == "" {
+		return b.opts.Prefix + "/"
+	}
+	return b.opts.Prefix + "/" + key
+}
+
+// relativeKey maps a key inside the bucket back to a backend key
+func (b *S3Backend) relativeKey(objectKey string) string {
+	if b.opts.Prefix == "" {
+		return objectKey
+	}
+	return strings.TrimPrefix(objectKey, b.opts.Prefix+"/")
+}
+
+// objectURL returns the request URL for an object key and query
+func (b *S3Backend) objectURL(objectKey string, query url.Values) *url.URL {
+	u := *b.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if b.opts.PathStyle {
+		u.Path = basePath + "/" + b.opts.Bucket + "/" + objectKey
+	} else {
+		u.Host = b.opts.Bucket + "." + u.Host
+		u.Path = basePath + "/" + objectKey
+	}
+	u.RawPath = ""
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (b *S3Backend) do(method, objectKey string, query url.Values, body []byte) (*http.Response, error) {
+	u := b.objectURL(objectKey, query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawPath = encodePath(req.URL.Path)
+	req.ContentLength = int64(len(body))
+	if b.opts.AccessKey != "" {
+		signV4(req, body, b.opts.Region, b.opts.AccessKey, b.opts.SecretKey, time.Now())
+	}
+	return b.client.Do(req)
+}
+
+// Read returns the contents of key
+func (b *S3Backend) Read(key string) ([]byte, error) {
+	resp, err := b.do(http.MethodGet, b.objectKey(key), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error("read", key, resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Write creates or replaces key
+func (b *S3Backend) Write(key string, data []byte) error {
+	resp, err := b.do(http.MethodPut, b.objectKey(key), nil, data)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error("write", key, resp)
+	}
+	return nil
+}
+
+// Delete removes key
+func (b *S3Backend) Delete(key string) error {
+	resp, err := b.do(http.MethodDelete, b.objectKey(key), nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error("delete", key, resp)
+	}
+	return nil
+}
+
+// DeletePrefix removes every object under prefix
+func (b *S3Backend) DeletePrefix(prefix string) error {
+	if strings.Trim(prefix, "/") == "" {
+		return fmt.Errorf("refusing to delete the storage root")
+	}
+	objects, err := b.List(prefix)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := b.Delete(obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stat returns object info for key
+func (b *S3Backend) Stat(key string) (*ObjectInfo, error) {
+	resp, err := b.do(http.MethodHead, b.objectKey(key), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error("stat", key, resp)
+	}
+
+	info := &ObjectInfo{Key: key, Size: resp.ContentLength}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modified
+	}
+	return info, nil
+}
+
+// listBucketResult is the ListObjectsV2 response body
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// listObjects pages through ListObjectsV2 for prefix
+func (b *S3Backend) listObjects(prefix, delimiter string, fn func(*listBucketResult)) error {
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := b.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error("list", prefix, resp)
+			resp.Body.Close()
+			return err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse list response: %w", err)
+		}
+		fn(&result)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// dirPrefix returns the bucket prefix for listing the contents of prefix
+func (b *S3Backend) dirPrefix(prefix string) string {
+	p := b.objectKey(strings.Trim(prefix, "/"))
+	if p != "" && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// List returns every object under prefix
+func (b *S3Backend) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := b.listObjects(b.dirPrefix(prefix), "", func(result *listBucketResult) {
+		for _, c := range result.Contents {
+			if strings.HasSuffix(c.Key, "/") {
+				continue
+			}
+			objects = append(objects, ObjectInfo{
+				Key:     b.relativeKey(c.Key),
+				Size:    c.Size,
+				ModTime: c.LastModified,
+			})
+		}
+	})
+	return objects, err
+}
+
+// ListDirs returns the names of the immediate sub-directories of prefix
+func (b *S3Backend) ListDirs(prefix string) ([]string, error) {
+	base := b.dirPrefix(prefix)
+	var dirs []string
+	err := b.listObjects(base, "/", func(result *listBucketResult) {
+		for _, cp := range result.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(cp.Prefix, base), "/")
+			if name != "" {
+				dirs = append(dirs, name)
+			}
+		}
+	})
+	return dirs, err
+}
+
+// Location returns an s3:// URL for key
+func (b *S3Backend) Location(key string) string {
+	return "s3://" + path.Join(b.opts.Bucket, b.objectKey(key))
+}
+
+func s3Error(op, key string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var apiErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+		return fmt.Errorf("failed to %s %s: %s: %s", op, key, apiErr.Code, apiErr.Message)
+	}
+	return fmt.Errorf("failed to %s %s: unexpected status %s", op, key, strconv.Quote(resp.Status))
+}
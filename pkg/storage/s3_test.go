@@ -0,0 +1,377 @@
+package storage
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory stand-in for a path-style S3 bucket. It serves the
+// object and ListObjectsV2 calls S3Backend makes, pages listings pageSize
+// entries at a time and checks the signature of every request.
+type fakeS3 struct {
+	t        *testing.T
+	bucket   string
+	pageSize int
+
+	mu       sync.Mutex
+	objects  map[string][]byte
+	modified map[string]time.Time
+	lists    int // ListObjectsV2 requests served
+}
+
+func newFakeS3(t *testing.T, pageSize int) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{
+		t:        t,
+		bucket:   "posts",
+		pageSize: pageSize,
+		objects:  make(map[string][]byte),
+		modified: make(map[string]time.Time),
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if msg := f.checkSignature(r, body); msg != "" {
+		f.t.Errorf("%s %s: %s", r.Method, r.URL, msg)
+		writeS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", msg)
+		return
+	}
+
+	bucketPath := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, bucketPath) {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, bucketPath)
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		f.modified[key] = time.Now().UTC().Truncate(time.Second)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", f.modified[key].Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		delete(f.modified, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+// checkSignature signs a copy of the request as the server received it and
+// compares the result with the client's Authorization header
+func (f *fakeS3) checkSignature(r *http.Request, body []byte) string {
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("x-amz-date"))
+	if err != nil {
+		return "missing or invalid x-amz-date"
+	}
+	signed, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		return err.Error()
+	}
+	signV4(signed, body, "us-east-1", "access", "secret", now)
+	if got, want := r.Header.Get("Authorization"), signed.Header.Get("Authorization"); got != want {
+		return "Authorization " + got + " does not match " + want
+	}
+	return ""
+}
+
+type fakeListResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Contents []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+	IsTruncated           bool
+	NextContinuationToken string `xml:",omitempty"`
+}
+
+// list serves ListObjectsV2. Continuation tokens are offsets into the
+// sorted entries, where an entry is an object or a common prefix.
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	f.lists++
+	query := r.URL.Query()
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type entry struct {
+		key      string
+		isPrefix bool
+	}
+	var entries []entry
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if !seen[common] {
+					seen[common] = true
+					entries = append(entries, entry{common, true})
+				}
+				continue
+			}
+		}
+		entries = append(entries, entry{key, false})
+	}
+
+	start, _ := strconv.Atoi(query.Get("continuation-token"))
+	end := start + f.pageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	var result fakeListResult
+	for _, e := range entries[start:end] {
+		if e.isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, struct{ Prefix string }{e.key})
+			continue
+		}
+		result.Contents = append(result.Contents, struct {
+			Key          string
+			Size         int64
+			LastModified time.Time
+		}{e.key, int64(len(f.objects[e.key])), f.modified[e.key]})
+	}
+	if end < len(entries) {
+		result.IsTruncated = true
+		result.NextContinuationToken = strconv.Itoa(end)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(&result)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
+
+func newTestS3Backend(t *testing.T, srv *httptest.Server, prefix string) *S3Backend {
+	b, err := NewS3Backend(S3Options{
+		Endpoint:  srv.URL,
+		Bucket:    "posts",
+		Prefix:    prefix,
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestS3BackendReadWrite(t *testing.T) {
+	_, srv := newFakeS3(t, 1000)
+	b := newTestS3Backend(t, srv, "")
+
+	tests := []struct {
+		key  string
+		data string
+	}{
+		{"card-1a2b/index.html", "<p>hello</p>"},
+		{"card-1a2b/media/my photo+1.png", "png"},
+		{"card-1a2b/media/café.svg", "<svg/>"},
+		{"card-1a2b/empty.txt", ""},
+	}
+	for _, tt := range tests {
+		if err := b.Write(tt.key, []byte(tt.data)); err != nil {
+			t.Fatalf("Write(%q): %v", tt.key, err)
+		}
+		got, err := b.Read(tt.key)
+		if err != nil {
+			t.Fatalf("Read(%q): %v", tt.key, err)
+		}
+		if string(got) != tt.data {
+			t.Errorf("Read(%q) = %q, want %q", tt.key, got, tt.data)
+		}
+		info, err := b.Stat(tt.key)
+		if err != nil {
+			t.Fatalf("Stat(%q): %v", tt.key, err)
+		}
+		if info.Key != tt.key || info.Size != int64(len(tt.data)) || info.ModTime.IsZero() {
+			t.Errorf("Stat(%q) = %+v", tt.key, info)
+		}
+	}
+
+	if _, err := b.Read("card-1a2b/missing.html"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Read of a missing key: got %v, want ErrNotFound", err)
+	}
+	if _, err := b.Stat("card-1a2b/missing.html"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat of a missing key: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3BackendListPagination(t *testing.T) {
+	f, srv := newFakeS3(t, 2)
+	b := newTestS3Backend(t, srv, "")
+
+	keys := []string{
+		"card-1a2b/index.html",
+		"card-1a2b/metadata.json",
+		"card-1a2b/media/a.png",
+		"card-1a2b/media/b.png",
+		"card-1a2b/versions/1.html",
+		"card-1a2b0/index.html",
+		"logo-9f8e/index.html",
+		".trash/old-0000/index.html",
+	}
+	for _, key := range keys {
+		if err := b.Write(key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := b.List("card-1a2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, obj := range objects {
+		got = append(got, obj.Key)
+		if obj.Size != int64(len(obj.Key)) {
+			t.Errorf("%s has size %d, want %d", obj.Key, obj.Size, len(obj.Key))
+		}
+	}
+	want := []string{
+		"card-1a2b/index.html",
+		"card-1a2b/media/a.png",
+		"card-1a2b/media/b.png",
+		"card-1a2b/metadata.json",
+		"card-1a2b/versions/1.html",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+	if f.lists < 3 {
+		t.Errorf("List made %d requests, want one per page of 2", f.lists)
+	}
+
+	dirs, err := b.ListDirs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".trash", "card-1a2b", "card-1a2b0", "logo-9f8e"}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("ListDirs(\"\") = %v, want %v", dirs, want)
+	}
+	dirs, err = b.ListDirs("card-1a2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"media", "versions"}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("ListDirs(\"card-1a2b\") = %v, want %v", dirs, want)
+	}
+}
+
+func TestS3BackendDelete(t *testing.T) {
+	f, srv := newFakeS3(t, 2)
+	b := newTestS3Backend(t, srv, "")
+
+	for _, key := range []string{
+		"card-1a2b/index.html",
+		"card-1a2b/media/a.png",
+		"card-1a2b/media/b.png",
+		"card-1a2b0/index.html",
+		"logo-9f8e/index.html",
+	} {
+		if err := b.Write(key, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.Delete("logo-9f8e/index.html"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Delete("logo-9f8e/index.html"); err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+	if err := b.DeletePrefix("card-1a2b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.DeletePrefix("/"); err == nil {
+		t.Error("DeletePrefix of the root succeeded")
+	}
+
+	var remaining []string
+	for key := range f.objects {
+		remaining = append(remaining, key)
+	}
+	if want := []string{"card-1a2b0/index.html"}; !reflect.DeepEqual(remaining, want) {
+		t.Errorf("objects after delete = %v, want %v", remaining, want)
+	}
+}
+
+func TestS3BackendPrefix(t *testing.T) {
+	f, srv := newFakeS3(t, 2)
+	b := newTestS3Backend(t, srv, "/workspaces/a/")
+
+	if err := b.Write("card-1a2b/index.html", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.objects["workspaces/a/card-1a2b/index.html"]; !ok {
+		t.Errorf("object stored under %v, want workspaces/a/card-1a2b/index.html", f.objects)
+	}
+	f.objects["workspaces/b/other-0000/index.html"] = []byte("y")
+
+	dirs, err := b.ListDirs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"card-1a2b"}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("ListDirs = %v, want %v", dirs, want)
+	}
+	objects, err := b.List("card-1a2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "card-1a2b/index.html" {
+		t.Errorf("List = %+v, want card-1a2b/index.html", objects)
+	}
+	if got := b.Location("card-1a2b/index.html"); got != "s3://posts/workspaces/a/card-1a2b/index.html" {
+		t.Errorf("Location = %s", got)
+	}
+}
@@ -2,81 +2,96 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html_image_creator/pkg/media"
 	"html_image_creator/pkg/post"
-	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
-// Storage handles file operations for image posts
+// Storage handles persistence of image posts on top of a Backend
 type Storage struct {
-	rootDir string
+	backend     Backend
+	quota       Quota
+	mediaPolicy media.Policy // Used to probe media indexed by migrations and repairs
+	usage       usage        // Cached byte and post counts for quota checks
 }
 
-// NewStorage creates a new Storage instance
-func NewStorage(rootDir string) *Storage {
+// NewStorage creates a new Storage instance that rejects writes exceeding
+// quota and probes media found without a manifest entry with mediaPolicy
+func NewStorage(backend Backend, quota Quota, mediaPolicy media.Policy) *Storage {
 	return &Storage{
-		rootDir: rootDir,
+		backend:     backend,
+		quota:       quota,
+		mediaPolicy: mediaPolicy,
 	}
 }
 
-// GetPostPath returns the directory path for a post
-func (s *Storage) GetPostPath(postID string) string {
-	return filepath.Join(s.rootDir, postID)
+// postKey returns the backend key for a file inside a post, rejecting IDs
+// that don't match the post ID grammar
+func postKey(postID string, elems ...string) (string, error) {
+	if !post.ValidatePostID(postID) {
+		return "", fmt.Errorf("invalid post ID: %s", postID)
+	}
+	key := path.Join(append([]string{postID}, elems...)...)
+	if key != postID && !strings.HasPrefix(key, postID+"/") {
+		return "", fmt.Errorf("path %s escapes post %s", path.Join(elems...), postID)
+	}
+	return key, nil
 }
 
-// GetHTMLPath returns the path to the index.html file
-func (s *Storage) GetHTMLPath(postID string) string {
-	return filepath.Join(s.GetPostPath(postID), "index.html")
+// htmlKey returns the key of a post's index.html
+func htmlKey(postID string) string {
+	return path.Join(postID, "index.html")
 }
 
-// GetMetadataPath returns the path to the metadata.json file
-func (s *Storage) GetMetadataPath(postID string) string {
-	return filepath.Join(s.GetPostPath(postID), "metadata.json")
+// metadataKey returns the key of a post's metadata.json
+func metadataKey(postID string) string {
+	return path.Join(postID, "metadata.json")
 }
 
-// GetMediaDir returns the path to the media directory
-func (s *Storage) GetMediaDir(postID string) string {
-	return filepath.Join(s.GetPostPath(postID), "media")
+// mediaManifestKey returns the key of a post's media.json manifest
+func mediaManifestKey(postID string) string {
+	return path.Join(postID, "media.json")
+}
+
+// PostLocation returns where the post's HTML is stored, as a file path or URL
+func (s *Storage) PostLocation(postID string) string {
+	return s.backend.Location(htmlKey(postID))
 }
 
 // PostExists checks if a post exists
 func (s *Storage) PostExists(postID string) bool {
-	htmlPath := s.GetHTMLPath(postID)
-	_, err := os.Stat(htmlPath)
+	key, err := postKey(postID, "index.html")
+	if err != nil {
+		return false
+	}
+	_, err = s.backend.Stat(key)
 	return err == nil
 }
 
-// CreatePost creates a new post on disk
+// CreatePost creates a new post in the backend
 func (s *Storage) CreatePost(p *post.ImagePost) error {
-	postPath := s.GetPostPath(p.ID)
-	if err := os.MkdirAll(postPath, 0755); err != nil {
-		return fmt.Errorf("failed to create post directory: %w", err)
+	key, err := postKey(p.ID, "index.html")
+	if err != nil {
+		return err
 	}
-
-	mediaDir := s.GetMediaDir(p.ID)
-	if err := os.MkdirAll(mediaDir, 0755); err != nil {
-		return fmt.Errorf("failed to create media directory: %w", err)
+	if err := s.checkQuota(p.ID, int64(len(p.HTMLContent)), true); err != nil {
+		return err
 	}
 
-	htmlPath := s.GetHTMLPath(p.ID)
-	if err := os.WriteFile(htmlPath, []byte(p.HTMLContent), 0644); err != nil {
+	if err := s.writeObject(key, []byte(p.HTMLContent)); err != nil {
 		return fmt.Errorf("failed to write HTML file: %w", err)
 	}
 
-	metadata := post.Metadata{
-		Name:      p.Name,
-		Width:     p.Width,
-		Height:    p.Height,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
-	}
-	if err := s.writeMetadata(p.ID, &metadata); err != nil {
+	if err := s.writeMetadata(p.ID, metadataFromPost(p)); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	return nil
+	return s.commit([]string{key, metadataKey(p.ID)}, "Create post %s: %s", p.ID, p.Name)
 }
 
 // UpdatePost updates an existing post's HTML content and metadata
@@ -85,33 +100,39 @@ func (s *Storage) UpdatePost(p *post.ImagePost) error {
 		return fmt.Errorf("post %s does not exist", p.ID)
 	}
 
-	htmlPath := s.GetHTMLPath(p.ID)
-	if err := os.WriteFile(htmlPath, []byte(p.HTMLContent), 0644); err != nil {
-		return fmt.Errorf("failed to write HTML file: %w", err)
+	previous, err := s.backend.Read(htmlKey(p.ID))
+	if err != nil {
+		return fmt.Errorf("failed to read HTML file: %w", err)
+	}
+	if growth := int64(len(p.HTMLContent) - len(previous)); growth > 0 {
+		if err := s.checkQuota(p.ID, growth, false); err != nil {
+			return err
+		}
 	}
 
-	metadata := post.Metadata{
-		Name:      p.Name,
-		Width:     p.Width,
-		Height:    p.Height,
-		CreatedAt: p.CreatedAt,
-		UpdatedAt: p.UpdatedAt,
+	if err := s.writeObject(htmlKey(p.ID), []byte(p.HTMLContent)); err != nil {
+		return fmt.Errorf("failed to write HTML file: %w", err)
 	}
-	if err := s.writeMetadata(p.ID, &metadata); err != nil {
+
+	if err := s.writeMetadata(p.ID, metadataFromPost(p)); err != nil {
 		return fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	return nil
+	// Writing metadata may have migrated the media manifest of an old post
+	touched := []string{htmlKey(p.ID), metadataKey(p.ID), mediaManifestKey(p.ID)}
+	if string(previous) == p.HTMLContent {
+		return s.commit(touched, "Update metadata of post %s", p.ID)
+	}
+	return s.commit(touched, "Update post %s", p.ID)
 }
 
-// GetPost retrieves a post from disk
+// GetPost retrieves a post from the backend
 func (s *Storage) GetPost(postID string) (*post.ImagePost, error) {
 	if !s.PostExists(postID) {
 		return nil, fmt.Errorf("post %s does not exist", postID)
 	}
 
-	htmlPath := s.GetHTMLPath(postID)
-	htmlBytes, err := os.ReadFile(htmlPath)
+	htmlBytes, err := s.backend.Read(htmlKey(postID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read HTML file: %w", err)
 	}
@@ -129,24 +150,23 @@ func (s *Storage) GetPost(postID string) (*post.ImagePost, error) {
 		Height:      metadata.Height,
 		CreatedAt:   metadata.CreatedAt,
 		UpdatedAt:   metadata.UpdatedAt,
+		History:     metadata.History,
+		Exports:     metadata.Exports,
+		PostDetails: metadata.PostDetails,
 	}, nil
 }
 
 // ListPosts returns all posts
 func (s *Storage) ListPosts() ([]*post.PostInfo, error) {
-	entries, err := os.ReadDir(s.rootDir)
+	dirs, err := s.backend.ListDirs("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to read root directory: %w", err)
 	}
 
 	var posts []*post.PostInfo
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		postID := entry.Name()
-		if !s.PostExists(postID) {
+	for _, postID := range dirs {
+		// The trash and other dot directories never match the post ID grammar
+		if !post.ValidatePostID(postID) || !s.PostExists(postID) {
 			continue
 		}
 
@@ -156,47 +176,92 @@ func (s *Storage) ListPosts() ([]*post.PostInfo, error) {
 		}
 
 		posts = append(posts, &post.PostInfo{
-			ID:        postID,
-			Name:      metadata.Name,
-			Width:     metadata.Width,
-			Height:    metadata.Height,
-			CreatedAt: metadata.CreatedAt,
-			UpdatedAt: metadata.UpdatedAt,
-			FilePath:  filepath.Join(postID, "index.html"),
+			ID:          postID,
+			Name:        metadata.Name,
+			Width:       metadata.Width,
+			Height:      metadata.Height,
+			CreatedAt:   metadata.CreatedAt,
+			UpdatedAt:   metadata.UpdatedAt,
+			FilePath:    htmlKey(postID),
+			PostDetails: metadata.PostDetails,
 		})
 	}
 
 	return posts, nil
 }
 
-// CopyMediaFile copies a media file to the post's media directory
-func (s *Storage) CopyMediaFile(postID, sourcePath string) (string, error) {
+// WriteMediaFile writes validated media data into the post's media directory
+// and records it in the post's media manifest. info.Path is set to the
+// relative path for use in HTML. Brand kit logos, named by info.BrandLogo,
+// are written to media/brand/ where uploads can't reach them.
+func (s *Storage) WriteMediaFile(postID string, info *post.MediaInfo, data []byte) error {
 	if !s.PostExists(postID) {
-		return "", fmt.Errorf("post %s does not exist", postID)
+		return fmt.Errorf("post %s does not exist", postID)
 	}
 
-	srcFile, err := os.Open(sourcePath)
+	filename := filepath.Base(info.Filename)
+	if info.BrandLogo != "" {
+		// Brand kit logos live apart from uploads so they can't replace one
+		if info.BrandLogo != path.Base(info.BrandLogo) || strings.HasPrefix(info.BrandLogo, ".") {
+			return fmt.Errorf("invalid brand logo name: %s", info.BrandLogo)
+		}
+		filename = path.Join(post.BrandLogoDir, info.BrandLogo)
+	} else if filename == post.BrandLogoDir {
+		return fmt.Errorf("media filename %s is reserved for brand kit logos", filename)
+	}
+	key, err := postKey(postID, "media", filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to open source file: %w", err)
+		return err
+	}
+	growth := int64(len(data))
+	if existing, err := s.backend.Stat(key); err == nil {
+		growth -= existing.Size
 	}
-	defer srcFile.Close()
+	if err := s.checkQuota(postID, growth, false); err != nil {
+		return err
+	}
+	if err := s.backend.Write(key, data); err != nil {
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+	s.chargeUsage(key, growth)
 
-	filename := filepath.Base(sourcePath)
-	mediaDir := s.GetMediaDir(postID)
-	destPath := filepath.Join(mediaDir, filename)
+	info.Filename = filename
+	info.Path = path.Join("media", filename)
+	info.SHA256 = sha256Hex(data)
 
-	destFile, err := os.Create(destPath)
+	manifest, err := s.readMediaManifest(postID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create destination file: %w", err)
+		return fmt.Errorf("failed to read media manifest: %w", err)
+	}
+	replaced := false
+	for i, existing := range manifest.Files {
+		if existing.Filename == filename {
+			manifest.Files[i] = info
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		manifest.Files = append(manifest.Files, info)
 	}
-	defer destFile.Close()
+	if err := s.writeMediaManifest(postID, manifest); err != nil {
+		return fmt.Errorf("failed to write media manifest: %w", err)
+	}
+
+	return s.commit([]string{key, mediaManifestKey(postID)}, "Add media %s to post %s", filename, postID)
+}
 
-	if _, err := io.Copy(destFile, srcFile); err != nil {
-		return "", fmt.Errorf("failed to copy file: %w", err)
+// ListMedia returns the media manifest entries for a post
+func (s *Storage) ListMedia(postID string) ([]*post.MediaInfo, error) {
+	if !s.PostExists(postID) {
+		return nil, fmt.Errorf("post %s does not exist", postID)
 	}
 
-	relativePath := filepath.Join("media", filename)
-	return relativePath, nil
+	manifest, err := s.readMediaManifest(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media manifest: %w", err)
+	}
+	return manifest.Files, nil
 }
 
 // DeletePost deletes a post and all its files
@@ -205,39 +270,147 @@ func (s *Storage) DeletePost(postID string) error {
 		return fmt.Errorf("post %s does not exist", postID)
 	}
 
-	postPath := s.GetPostPath(postID)
-	if err := os.RemoveAll(postPath); err != nil {
+	err := s.backend.DeletePrefix(postID)
+	s.remeasureUsage(postID)
+	if err != nil {
 		return fmt.Errorf("failed to delete post directory: %w", err)
 	}
 
-	return nil
+	return s.commit([]string{postID}, "Delete post %s", postID)
 }
 
+// MaterializePost returns a local directory containing the post's files for
+// rendering. Local backends return the post directory itself; other
+// backends download the post into a scratch directory that is removed by
+// the returned cleanup function.
+func (s *Storage) MaterializePost(postID string) (string, func(), error) {
+	if !s.PostExists(postID) {
+		return "", nil, fmt.Errorf("post %s does not exist", postID)
+	}
+
+	if local, ok := s.backend.(LocalPather); ok {
+		dir, err := local.LocalPath(postID)
+		if err != nil {
+			return "", nil, err
+		}
+		return dir, func() {}, nil
+	}
+
+	scratchDir, err := os.MkdirTemp("", "html-image-"+postID+"-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(scratchDir) }
+
+	objects, err := s.backend.List(postID)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to list post files: %w", err)
+	}
+	for _, obj := range objects {
+		rel := obj.Key[len(postID)+1:]
+		data, err := s.backend.Read(obj.Key)
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to download %s: %w", obj.Key, err)
+		}
+		dest := filepath.Join(scratchDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to create scratch directory: %w", err)
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("failed to write %s: %w", dest, err)
+		}
+	}
+
+	return scratchDir, cleanup, nil
+}
+
+// metadataFromPost returns the metadata.json contents for a post
+func metadataFromPost(p *post.ImagePost) *post.Metadata {
+	return &post.Metadata{
+		Name:        p.Name,
+		Width:       p.Width,
+		Height:      p.Height,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		History:     p.History,
+		Exports:     p.Exports,
+		PostDetails: p.PostDetails,
+	}
+}
+
+// writeMetadata saves a post's metadata. A post still stored with an older
+// schema version is migrated first, so the upgrade is saved together with the
+// change that triggered it.
 func (s *Storage) writeMetadata(postID string, metadata *post.Metadata) error {
-	metadataPath := s.GetMetadataPath(postID)
+	if stored, err := s.readRawMetadata(postID); err == nil && schemaVersion(stored) < post.SchemaVersion {
+		metadata.SchemaVersion = stored.SchemaVersion
+		_, err := s.migratePost(postID, metadata, false)
+		return err
+	}
+	return s.saveMetadata(postID, metadata)
+}
+
+// saveMetadata writes metadata.json at the current schema version
+func (s *Storage) saveMetadata(postID string, metadata *post.Metadata) error {
+	metadata.SchemaVersion = post.SchemaVersion
 	data, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+	if err := s.writeObject(metadataKey(postID), data); err != nil {
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
 
 	return nil
 }
 
+// readMetadata reads a post's metadata. Only the schema version of posts
+// written by an older version is checked; their files are upgraded on the
+// next write or by MigrateAll, so reads never touch media.
 func (s *Storage) readMetadata(postID string) (*post.Metadata, error) {
-	metadataPath := s.GetMetadataPath(postID)
-	data, err := os.ReadFile(metadataPath)
+	metadata, err := s.readRawMetadata(postID)
+	if err != nil {
+		return nil, err
+	}
+	if version := schemaVersion(metadata); version > post.SchemaVersion {
+		return nil, fmt.Errorf("metadata schema version %d is newer than supported version %d", version, post.SchemaVersion)
+	}
+	return metadata, nil
+}
+
+func (s *Storage) writeMediaManifest(postID string, manifest *post.MediaManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal media manifest: %w", err)
+	}
+
+	if err := s.writeObject(mediaManifestKey(postID), data); err != nil {
+		return fmt.Errorf("failed to write media manifest file: %w", err)
+	}
+
+	return nil
+}
+
+// readMediaManifest reads a post's media manifest, returning an empty
+// manifest for posts created before manifests were recorded
+func (s *Storage) readMediaManifest(postID string) (*post.MediaManifest, error) {
+	data, err := s.backend.Read(mediaManifestKey(postID))
+	if errors.Is(err, ErrNotFound) {
+		return &post.MediaManifest{}, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read metadata file: %w", err)
+		return nil, fmt.Errorf("failed to read media manifest file: %w", err)
 	}
 
-	var metadata post.Metadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	var manifest post.MediaManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal media manifest: %w", err)
 	}
 
-	return &metadata, nil
+	return &manifest, nil
 }
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"html_image_creator/pkg/post"
+	"path"
+	"strings"
+	"time"
+)
+
+// trashDir is the root-level directory holding soft-deleted posts. Its name
+// can never collide with a post ID.
+const trashDir = ".trash"
+
+// trashRecord is stored as trash.json inside a trashed post
+type trashRecord struct {
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// trashKey returns the key of a trashed post's directory or a file inside it
+func trashKey(postID string, elems ...string) string {
+	return path.Join(append([]string{trashDir, postID}, elems...)...)
+}
+
+// TrashPost moves a post into the trash. A previously trashed post with the
+// same ID is replaced.
+func (s *Storage) TrashPost(postID string) error {
+	if !s.PostExists(postID) {
+		return fmt.Errorf("post %s does not exist", postID)
+	}
+
+	if err := s.backend.DeletePrefix(trashKey(postID)); err != nil {
+		return fmt.Errorf("failed to clear previous trash entry: %w", err)
+	}
+	err := s.movePrefix(postID, trashKey(postID))
+	s.remeasureUsage(postID, trashKey(postID))
+	if err != nil {
+		return fmt.Errorf("failed to move post to trash: %w", err)
+	}
+
+	data, err := json.MarshalIndent(trashRecord{DeletedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash record: %w", err)
+	}
+	if err := s.writeObject(trashKey(postID, "trash.json"), data); err != nil {
+		return fmt.Errorf("failed to write trash record: %w", err)
+	}
+
+	return s.commit([]string{postID, trashKey(postID)}, "Move post %s to trash", postID)
+}
+
+// ListTrash returns all trashed posts
+func (s *Storage) ListTrash() ([]*post.TrashedPost, error) {
+	dirs, err := s.backend.ListDirs(trashDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+
+	var trashed []*post.TrashedPost
+	for _, postID := range dirs {
+		if !post.ValidatePostID(postID) {
+			continue
+		}
+		entry, err := s.readTrashEntry(postID)
+		if err != nil {
+			continue // Skip entries with missing or invalid records
+		}
+		trashed = append(trashed, entry)
+	}
+
+	return trashed, nil
+}
+
+// RestorePost moves a trashed post back into place. It fails if a live post
+// with the same ID exists.
+func (s *Storage) RestorePost(postID string) error {
+	if !post.ValidatePostID(postID) {
+		return fmt.Errorf("invalid post ID: %s", postID)
+	}
+	if _, err := s.backend.Stat(trashKey(postID, "index.html")); err != nil {
+		return fmt.Errorf("post %s is not in the trash", postID)
+	}
+	if s.PostExists(postID) {
+		return fmt.Errorf("post %s already exists", postID)
+	}
+	// Restoring moves bytes out of the trash, so only the post count can grow
+	if err := s.checkQuota(postID, 0, true); err != nil {
+		return err
+	}
+
+	if err := s.deleteObject(trashKey(postID, "trash.json")); err != nil {
+		return fmt.Errorf("failed to remove trash record: %w", err)
+	}
+	err := s.movePrefix(trashKey(postID), postID)
+	s.remeasureUsage(postID, trashKey(postID))
+	if err != nil {
+		return fmt.Errorf("failed to restore post: %w", err)
+	}
+
+	return s.commit([]string{postID, trashKey(postID)}, "Restore post %s from trash", postID)
+}
+
+// PurgeTrash permanently deletes trashed posts deleted more than olderThan
+// ago and returns their IDs. A zero olderThan purges everything.
+func (s *Storage) PurgeTrash(olderThan time.Duration) ([]string, error) {
+	trashed, err := s.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	var purged []string
+	for _, entry := range trashed {
+		if olderThan > 0 && entry.DeletedAt.After(cutoff) {
+			continue
+		}
+		err := s.backend.DeletePrefix(trashKey(entry.ID))
+		s.remeasureUsage(trashKey(entry.ID))
+		if err != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", entry.ID, err)
+		}
+		purged = append(purged, entry.ID)
+	}
+
+	if len(purged) > 0 {
+		touched := make([]string, len(purged))
+		for i, id := range purged {
+			touched[i] = trashKey(id)
+		}
+		if err := s.commit(touched, "Purge %d posts from trash", len(purged)); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+func (s *Storage) readTrashEntry(postID string) (*post.TrashedPost, error) {
+	data, err := s.backend.Read(trashKey(postID, "trash.json"))
+	if err != nil {
+		return nil, err
+	}
+	var record trashRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trash record: %w", err)
+	}
+
+	data, err = s.backend.Read(trashKey(postID, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	var metadata post.Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+
+	return &post.TrashedPost{
+		ID:        postID,
+		Name:      metadata.Name,
+		Width:     metadata.Width,
+		Height:    metadata.Height,
+		CreatedAt: metadata.CreatedAt,
+		UpdatedAt: metadata.UpdatedAt,
+		DeletedAt: record.DeletedAt,
+	}, nil
+}
+
+// movePrefix moves every object under src to dst, using the backend's
+// native move when available
+func (s *Storage) movePrefix(src, dst string) error {
+	if mover, ok := s.backend.(Mover); ok {
+		return mover.Move(src, dst)
+	}
+
+	objects, err := s.backend.List(src)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		data, err := s.backend.Read(obj.Key)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.Write(dst+strings.TrimPrefix(obj.Key, src), data); err != nil {
+			return err
+		}
+	}
+	return s.backend.DeletePrefix(src)
+}
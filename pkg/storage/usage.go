@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/post"
+	"sort"
+	"strings"
+)
+
+// Usage reports the bytes used by each post, split by kind, along with the
+// largest media files. Sizes come from object listings and metadata.json;
+// HTML content is never read. An empty postID reports every post.
+func (s *Storage) Usage(postID string, largestMedia int) (*post.StorageUsage, error) {
+	var postIDs []string
+	if postID != "" {
+		if !s.PostExists(postID) {
+			return nil, fmt.Errorf("post %s does not exist", postID)
+		}
+		postIDs = []string{postID}
+	} else {
+		dirs, err := s.backend.ListDirs("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root directory: %w", err)
+		}
+		for _, dir := range dirs {
+			if post.ValidatePostID(dir) && s.PostExists(dir) {
+				postIDs = append(postIDs, dir)
+			}
+		}
+	}
+
+	usage := &post.StorageUsage{
+		PostCount:    len(postIDs),
+		Posts:        []*post.PostUsage{},
+		LargestMedia: []*post.MediaUsage{},
+	}
+	for _, id := range postIDs {
+		pu, media, err := s.postUsage(id)
+		if err != nil {
+			return nil, err
+		}
+		usage.Posts = append(usage.Posts, pu)
+		usage.LargestMedia = append(usage.LargestMedia, media...)
+
+		usage.Totals.HTMLBytes += pu.HTMLBytes
+		usage.Totals.MediaBytes += pu.MediaBytes
+		usage.Totals.VersionBytes += pu.VersionBytes
+		usage.Totals.MetadataBytes += pu.MetadataBytes
+		usage.Totals.ExportBytes += pu.ExportBytes
+		usage.Totals.TotalBytes += pu.TotalBytes
+	}
+
+	if postID == "" {
+		trashBytes, err := s.prefixSize(trashDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure trash: %w", err)
+		}
+		usage.TrashBytes = trashBytes
+	}
+
+	sort.SliceStable(usage.LargestMedia, func(i, j int) bool {
+		return usage.LargestMedia[i].Bytes > usage.LargestMedia[j].Bytes
+	})
+	if len(usage.LargestMedia) > largestMedia {
+		usage.LargestMedia = usage.LargestMedia[:largestMedia]
+	}
+
+	return usage, nil
+}
+
+// postUsage measures one post and returns its media files
+func (s *Storage) postUsage(postID string) (*post.PostUsage, []*post.MediaUsage, error) {
+	objects, err := s.backend.List(postID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s: %w", postID, err)
+	}
+
+	pu := &post.PostUsage{PostID: postID}
+	var media []*post.MediaUsage
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Key, postID+"/")
+		switch {
+		case rel == "index.html":
+			pu.HTMLBytes += obj.Size
+		case strings.HasPrefix(rel, "media/"):
+			pu.MediaBytes += obj.Size
+			media = append(media, &post.MediaUsage{PostID: postID, Path: rel, Bytes: obj.Size})
+		case isVersionPath(rel):
+			pu.VersionBytes += obj.Size
+		default:
+			pu.MetadataBytes += obj.Size
+		}
+		pu.TotalBytes += obj.Size
+	}
+
+	// Read without migrating; a usage report must not modify storage
+	if metadata, err := s.readRawMetadata(postID); err == nil {
+		pu.Name = metadata.Name
+		// Repeated exports to the same path overwrite each other, so only the latest counts
+		latest := make(map[string]int64)
+		for _, record := range metadata.Exports {
+			latest[record.OutputPath] = record.Size
+		}
+		for _, size := range latest {
+			pu.ExportBytes += size
+		}
+		pu.ExportCount = len(latest)
+	}
+
+	return pu, media, nil
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"fmt"
+	"html_image_creator/pkg/post"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// versionTimeFormat names version snapshots so they sort chronologically
+const versionTimeFormat = "20060102T150405.000000000Z"
+
+// versionsKey returns the key of a post's versions directory or a version inside it
+func versionsKey(postID string, elems ...string) string {
+	return path.Join(append([]string{postID, "versions"}, elems...)...)
+}
+
+// ListVersions returns the saved HTML versions of a post, oldest first
+func (s *Storage) ListVersions(postID string) ([]*post.VersionInfo, error) {
+	if !s.PostExists(postID) {
+		return nil, fmt.Errorf("post %s does not exist", postID)
+	}
+
+	objects, err := s.backend.List(versionsKey(postID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	var versions []*post.VersionInfo
+	for _, obj := range objects {
+		name := path.Base(obj.Key)
+		versionID := strings.TrimSuffix(name, ".html")
+		savedAt, err := time.Parse(versionTimeFormat, versionID)
+		if err != nil || versionID == name {
+			continue
+		}
+		versions = append(versions, &post.VersionInfo{
+			ID:      versionID,
+			SavedAt: savedAt,
+			Size:    obj.Size,
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID < versions[j].ID })
+	return versions, nil
+}
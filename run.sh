@@ -37,7 +37,7 @@ case "$command" in
         ;;
 
     list)
-        bin/html_image_creator -list
+        bin/html_image_creator -list "$@"
         ;;
 
     get)
@@ -56,6 +56,14 @@ case "$command" in
         bin/html_image_creator -update "$1" -html "$2"
         ;;
 
+    edit)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh edit <post_id> <operations_json>"
+            exit 1
+        fi
+        bin/html_image_creator -edit "$1" -ops "$2"
+        ;;
+
     export)
         if [ -z "$1" ] || [ -z "$2" ]; then
             echo "Usage: ./run.sh export <post_id> <output_path>"
@@ -72,6 +80,226 @@ case "$command" in
         bin/html_image_creator -add-media "$1" -media-path "$2"
         ;;
 
+    localize)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh localize <post_id>"
+            exit 1
+        fi
+        bin/html_image_creator -localize "$1"
+        ;;
+
+    delete)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh delete <post_id>"
+            exit 1
+        fi
+        bin/html_image_creator -delete "$1"
+        ;;
+
+    list-trash)
+        bin/html_image_creator -list-trash
+        ;;
+
+    restore)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh restore <post_id>"
+            exit 1
+        fi
+        bin/html_image_creator -restore "$1"
+        ;;
+
+    empty-trash)
+        bin/html_image_creator -empty-trash -older-than-days "${1:-0}"
+        ;;
+
+    tag)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh tag <post_id> <tag>"
+            exit 1
+        fi
+        bin/html_image_creator -tag-post "$1" -add-tag "$2"
+        ;;
+
+    collections)
+        bin/html_image_creator -list-collections
+        ;;
+
+    create-collection)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh create-collection <name>"
+            exit 1
+        fi
+        bin/html_image_creator -create-collection "$1"
+        ;;
+
+    move)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh move <post_id[,post_id...]> <collection_id>"
+            exit 1
+        fi
+        bin/html_image_creator -move-to-collection "$1" -collection "$2"
+        ;;
+
+    rename)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh rename <post_id> <new_name>"
+            exit 1
+        fi
+        bin/html_image_creator -set-metadata "$1" -name "$2"
+        ;;
+
+    resize)
+        if [ -z "$1" ] || [ -z "$2" ] || [ -z "$3" ]; then
+            echo "Usage: ./run.sh resize <post_id> <width> <height> [keep|scale|rewrite]"
+            exit 1
+        fi
+        bin/html_image_creator -resize "$1" -width "$2" -height "$3" -mode "${4:-keep}"
+        ;;
+
+    clone)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh clone <post_id> <name> [width] [height]"
+            exit 1
+        fi
+        if [ -n "$3" ] && [ -n "$4" ]; then
+            bin/html_image_creator -clone "$1" -name "$2" -width "$3" -height "$4" -rescale
+        else
+            bin/html_image_creator -clone "$1" -name "$2"
+        fi
+        ;;
+
+    export-bundle)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh export-bundle <post_id> <output_path>"
+            exit 1
+        fi
+        bin/html_image_creator -export-bundle "$1" -output "$2"
+        ;;
+
+    import-bundle)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh import-bundle <bundle_path> [error|rename|overwrite]"
+            exit 1
+        fi
+        bin/html_image_creator -import-bundle "$1" -on-conflict "${2:-error}"
+        ;;
+
+    migrate)
+        if [ "$1" = "--dry-run" ]; then
+            bin/html_image_creator -migrate -dry-run
+        else
+            bin/html_image_creator -migrate
+        fi
+        ;;
+
+    fsck)
+        bin/html_image_creator -fsck -repair "${1:-}"
+        ;;
+
+    gc)
+        if [ "$1" = "--dry-run" ]; then
+            bin/html_image_creator -gc -dry-run
+        else
+            bin/html_image_creator -gc
+        fi
+        ;;
+
+    log)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh log <post_id>"
+            exit 1
+        fi
+        bin/html_image_creator -log "$1"
+        ;;
+
+    checkout)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh checkout <post_id> <revision>"
+            exit 1
+        fi
+        bin/html_image_creator -checkout "$1" -revision "$2"
+        ;;
+
+    usage)
+        bin/html_image_creator -usage -limit "${1:-0}"
+        ;;
+
+    render-template)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh render-template <template_id> <values_json> [name] [output_path]"
+            exit 1
+        fi
+        bin/html_image_creator -render-template "$1" -values "$2" -name "${3:-}" -output "${4:-}"
+        ;;
+
+    batch)
+        if [ -z "$1" ] || [ -z "$2" ] || [ -z "$3" ]; then
+            echo "Usage: ./run.sh batch <template_id> <data_file> <output_dir> [pattern]"
+            exit 1
+        fi
+        bin/html_image_creator -batch "$1" -data "$2" -output "$3" -pattern "${4:-}"
+        ;;
+
+    brand-kits)
+        bin/html_image_creator -list-brand-kits
+        ;;
+
+    create-brand-kit)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh create-brand-kit <name> <kit_json>"
+            exit 1
+        fi
+        bin/html_image_creator -create-brand-kit "$1" -kit "$2"
+        ;;
+
+    update-brand-kit)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh update-brand-kit <kit_id> <kit_json>"
+            exit 1
+        fi
+        bin/html_image_creator -update-brand-kit "$1" -kit "$2"
+        ;;
+
+    apply-brand-kit)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh apply-brand-kit <post_id> [kit_id]"
+            exit 1
+        fi
+        bin/html_image_creator -apply-brand-kit "$1" -brand-kit "${2:-}"
+        ;;
+
+    components)
+        bin/html_image_creator -list-components
+        ;;
+
+    register-component)
+        if [ -z "$1" ] || [ -z "$2" ]; then
+            echo "Usage: ./run.sh register-component <name> <html> [css] [params_json]"
+            exit 1
+        fi
+        bin/html_image_creator -register-component "$1" -html "$2" -css "${3:-}" -params "${4:-}"
+        ;;
+
+    preview-component)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh preview-component <name> [params_json] [output_path] [width] [height]"
+            exit 1
+        fi
+        bin/html_image_creator -preview-component "$1" -values "${2:-}" -output "${3:-}" -width "${4:-0}" -height "${5:-0}"
+        ;;
+
+    workspaces)
+        bin/html_image_creator -list-workspaces
+        ;;
+
+    create-workspace)
+        if [ -z "$1" ]; then
+            echo "Usage: ./run.sh create-workspace <name> [root_dir]"
+            exit 1
+        fi
+        bin/html_image_creator -create-workspace "$1" -root-dir "${2:-}"
+        ;;
+
     clean)
         echo "Cleaning build artifacts..."
         rm -rf bin
@@ -88,11 +316,43 @@ case "$command" in
         echo "  test                                   Run tests"
         echo "  install                                Install dependencies"
         echo "  create <name> <html> <width> <height>  Create a new image post"
-        echo "  list                                   List all image posts"
+        echo "  list [--query q] [--sort f] [...]      List image posts (search, filter, sort, page)"
         echo "  get <id>                               Get image post by ID"
         echo "  update <id> <html>                     Update image post content"
+        echo "  edit <id> <json>                       Edit image post HTML by CSS selector"
         echo "  export <id> <output_path>              Export as PNG image"
         echo "  add-media <id> <path>                  Add media file to post"
+        echo "  localize <id>                          Download remote images into post media"
+        echo "  delete <id>                            Move image post to the trash"
+        echo "  list-trash                             List trashed image posts"
+        echo "  restore <id>                           Restore image post from the trash"
+        echo "  empty-trash [days]                     Purge trashed posts older than days"
+        echo "  tag <id> <tag>                         Add a tag to a post"
+        echo "  collections                            List collections"
+        echo "  create-collection <name>               Create a collection"
+        echo "  move <ids> <collection_id>             Move posts into a collection"
+        echo "  rename <id> <new_name>                 Rename a post (ID is unchanged)"
+        echo "  resize <id> <w> <h> [mode]             Resize a post's canvas (keep, scale, rewrite)"
+        echo "  clone <id> <name> [width] [height]     Clone a post, rescaling to new dimensions"
+        echo "  export-bundle <id> <output_path>       Export post as a zip or tar.gz bundle"
+        echo "  import-bundle <path> [on_conflict]     Import a post bundle"
+        echo "  migrate [--dry-run]                    Upgrade all posts to the current metadata schema"
+        echo "  fsck [regenerate|quarantine|delete]    Check storage and optionally repair it"
+        echo "  gc [--dry-run]                         Apply retention policies (old posts, surplus versions)"
+        echo "  log <id>                               Show a post's git history"
+        echo "  checkout <id> <revision>               Restore a post to a past git revision"
+        echo "  usage [limit]                          Report disk usage per post"
+        echo "  render-template <id> <json> [name]     Render a template (4th arg: output path)"
+        echo "  batch <id> <data> <dir> [pattern]      Render one image per CSV/JSON row"
+        echo "  brand-kits                             List brand kits"
+        echo "  create-brand-kit <name> <json>         Create a brand kit (palette, typography, spacing, logos)"
+        echo "  update-brand-kit <id> <json>           Edit a brand kit and update its posts"
+        echo "  apply-brand-kit <post_id> [kit_id]     Apply a brand kit to a post (omit kit_id to remove)"
+        echo "  components                             List registered components"
+        echo "  register-component <name> <html>       Register a component (3rd arg: css, 4th: params JSON)"
+        echo "  preview-component <name> [json]        Render a component (3rd arg: output path)"
+        echo "  workspaces                             List workspaces"
+        echo "  create-workspace <name> [root_dir]     Create a workspace with its own storage"
         echo "  clean                                  Remove build artifacts"
         echo ""
         echo "Examples:"